@@ -0,0 +1,135 @@
+// Command audit exports the audit log of a passKeeper server and verifies
+// exports offline.
+//
+//	audit export [-d connection string] [-o file]
+//	audit verify -key <public key> [file]
+//	audit public-key [-k key file]
+package main
+
+import (
+	"bufio"
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	audit "passKeeper/internal/models/audit"
+	db "passKeeper/internal/models/database"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+	var err error
+	switch os.Args[1] {
+	case "export":
+		err = export(os.Args[2:])
+	case "verify":
+		err = verify(os.Args[2:])
+	case "public-key":
+		err = publicKey(os.Args[2:])
+	default:
+		usage()
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: audit export [-d connection string] [-o file]")
+	fmt.Fprintln(os.Stderr, "       audit verify -key <public key> [file]")
+	fmt.Fprintln(os.Stderr, "       audit public-key [-k key file]")
+	os.Exit(2)
+}
+
+// export writes the audit log from the database as JSON lines.
+func export(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	conn := fs.String("d", os.Getenv("DATABASE_URI"), "Postgres connection string (default $DATABASE_URI)")
+	out := fs.String("o", "", "Write the export to this file instead of stdout")
+	fs.Parse(args)
+	if *conn == "" {
+		return fmt.Errorf("no database given, use -d or DATABASE_URI")
+	}
+
+	w := io.Writer(os.Stdout)
+	if *out != "" {
+		f, err := os.OpenFile(*out, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	repo := db.GetAuditRepo(db.ConnectDB(*conn))
+	if err := repo.ExportAudit(func(rec audit.ExportRecord) error { return enc.Encode(rec) }); err != nil {
+		return fmt.Errorf("cannot export audit log: %s", err)
+	}
+	return bw.Flush()
+}
+
+// verify checks an export against the public key of the server and reports
+// the first broken link.
+func verify(args []string) error {
+	fs := flag.NewFlagSet("verify", flag.ExitOnError)
+	key := fs.String("key", "", "Base64 public key the server signs checkpoints with, see audit public-key")
+	fs.Parse(args)
+	pub, err := audit.ParsePublicKey(*key)
+	if err != nil {
+		return err
+	}
+
+	r := io.Reader(os.Stdin)
+	if fs.NArg() > 0 {
+		f, err := os.Open(fs.Arg(0))
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+	report, err := audit.Verify(r, pub)
+	var broken *audit.BrokenLink
+	if errors.As(err, &broken) {
+		fmt.Printf("BROKEN after %d valid events: %s\n", report.Events, broken)
+		os.Exit(1)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Printf("OK: %d events chained, %d checkpoints verified\n", report.Events, report.Checkpoints)
+	if c := report.LastCheckpoint; c != nil {
+		fmt.Printf("Last checkpoint covers event %d, signed at %s\n", c.EventID, c.CreatedAt.Format("2006-01-02 15:04:05 MST"))
+	}
+	if report.Unsigned > 0 {
+		fmt.Printf("%d events after the last checkpoint are not signed yet\n", report.Unsigned)
+	}
+	return nil
+}
+
+// publicKey prints the public key of the key file signing the checkpoints.
+func publicKey(args []string) error {
+	fs := flag.NewFlagSet("public-key", flag.ExitOnError)
+	path := fs.String("k", os.Getenv("AUDIT_KEY_FILE"), "Key file of the server (default $AUDIT_KEY_FILE)")
+	fs.Parse(args)
+	if *path == "" {
+		return fmt.Errorf("no key file given, use -k or AUDIT_KEY_FILE")
+	}
+	if _, err := os.Stat(*path); err != nil {
+		return err
+	}
+	key, err := audit.LoadSigningKey(*path)
+	if err != nil {
+		return err
+	}
+	fmt.Println(audit.EncodePublicKey(key.Public().(ed25519.PublicKey)))
+	return nil
+}
@@ -12,8 +12,17 @@ func main() {
 	accountRepo := db.GetAccountRepo(conn)
 	secretRepo := db.GetSecretRepo(conn)
 	migrationRepo := db.GetMigrationRepo(conn)
-	app := app.NewApp(*sc, accountRepo, secretRepo, migrationRepo)
+	orgRepo := db.GetOrgRepo(conn)
+	emergencyRepo := db.GetEmergencyRepo(conn)
+	auditRepo := db.GetAuditRepo(conn)
+	webhookRepo := db.GetWebhookRepo(conn)
+	app := app.NewApp(*sc, accountRepo, secretRepo, migrationRepo, orgRepo, emergencyRepo, auditRepo, webhookRepo)
 	app.CreateTables()
+	app.StartTrashRetention()
+	app.StartExpiryArchiving()
+	app.StartEmergencyGrants()
+	app.StartAuditCheckpoints()
+	app.StartWebhooks()
 	app.StartWebServer()
 
 }
@@ -16,6 +16,9 @@ type Config struct {
 	ServerAuth
 	ServerLog
 	Certificates
+	Retention
+	Audit
+	Webhooks
 }
 type HTTPServer struct {
 	ServerPort string `env:"RUN_ADDRESS" envDefault:"127.0.0.1:8080"`
@@ -30,6 +33,27 @@ type ServerAuth struct {
 type ServerLog struct {
 	Log string `env:"SERVER_LOG"`
 }
+
+// Retention configures how long deleted secrets stay in the trash before
+// they are purged permanently.
+type Retention struct {
+	TrashRetentionDays int `env:"TRASH_RETENTION_DAYS" envDefault:"30"`
+}
+
+// Audit configures the signed checkpoints and the export of the audit log.
+// Without a key file no checkpoints are signed, without an export token the
+// export endpoint is disabled.
+type Audit struct {
+	AuditKeyFile           string `env:"AUDIT_KEY_FILE"`
+	AuditCheckpointMinutes int    `env:"AUDIT_CHECKPOINT_MINUTES" envDefault:"60"`
+	AuditExportToken       string `env:"AUDIT_EXPORT_TOKEN"`
+}
+
+// Webhooks configures the delivery of webhooks. Receivers on loopback,
+// private and other non-public addresses are refused unless allowed.
+type Webhooks struct {
+	WebhookAllowPrivate bool `env:"WEBHOOK_ALLOW_PRIVATE" envDefault:"false"`
+}
 type Certificates struct {
 	TLSCertFile string `env:"TLSCERTFILE"`
 	TLSKeyFile  string `env:"TLSKEYFILE"`
@@ -41,6 +65,9 @@ func NewServerConfig() *Config {
 	err := env.Parse(&sc.ExternalDependency)
 	env.Parse(&sc.HTTPServer)
 	env.Parse(&sc.ServerAuth)
+	env.Parse(&sc.Retention)
+	env.Parse(&sc.Audit)
+	env.Parse(&sc.Webhooks)
 
 	_, envAdddressExists := os.LookupEnv("RUN_ADDRESS")
 	_, envDBExists := os.LookupEnv("DATABASE_URI")
@@ -48,6 +75,8 @@ func NewServerConfig() *Config {
 	_, envExpirationTimeExists := os.LookupEnv("EXPIRATION_TIME")
 	_, envTLSCertFileExists := os.LookupEnv("TLSCERTFILE")
 	_, envTLSKeyFileExists := os.LookupEnv("TLSKEYFILE")
+	_, envTrashRetentionExists := os.LookupEnv("TRASH_RETENTION_DAYS")
+	_, envAuditKeyFileExists := os.LookupEnv("AUDIT_KEY_FILE")
 
 	if err != nil {
 		log.Fatalf("unable to parse ennvironment variables: %e", err)
@@ -99,6 +128,24 @@ func NewServerConfig() *Config {
 		sc.TLSKeyFile = flagValue
 		return nil
 	})
+	flag.Func("tr", "Days deleted secrets are kept in the trash (default 30)", func(flagValue string) error {
+		if envTrashRetentionExists {
+			return nil
+		}
+		intVar, err := strconv.Atoi(flagValue)
+		if err != nil {
+			return err
+		}
+		sc.TrashRetentionDays = intVar
+		return nil
+	})
+	flag.Func("ak", "Key file signing audit log checkpoints, created if missing", func(flagValue string) error {
+		if envAuditKeyFileExists {
+			return nil
+		}
+		sc.AuditKeyFile = flagValue
+		return nil
+	})
 	flag.Parse()
 
 	return &sc
@@ -1,13 +1,15 @@
 package cmd
 
 import (
+	"bufio"
 	"crypto/tls"
 	"errors"
 	"fmt"
+	"io"
 	"net"
 	"net/http"
 	"os"
-	"regexp"
+	"sort"
 	"strings"
 
 	"path/filepath"
@@ -42,8 +44,38 @@ type Application struct {
 	client *http.Client
 }
 
+// NewSecret holds the options of the new command for the secret it creates.
+type NewSecret struct {
+	// Path stores the secret under a unique path, empty for none.
+	Path string
+	// ExpiresAt is the date after which the server stops serving the
+	// secret, nil for never.
+	ExpiresAt *time.Time
+	// Vault is the vault to store the secret in, zero for the personal
+	// secrets.
+	Vault uint
+}
+
+// secretOptions returns the request options of the new secret.
+func secretOptions(target NewSecret) []clientRequest.SecretOption {
+	var opts []clientRequest.SecretOption
+	if target.Path != "" {
+		opts = append(opts, clientRequest.WithPath(target.Path))
+	}
+	if target.ExpiresAt != nil {
+		opts = append(opts, clientRequest.WithExpiry(*target.ExpiresAt))
+	}
+	if target.Vault != 0 {
+		opts = append(opts, clientRequest.WithVault(target.Vault))
+	}
+	return opts
+}
+
+// Username is the configuration stored in config.yaml.
 type Username struct {
 	Username string `yaml:"username,omitempty"`
+	// Vault is the vault selected with vault use, see VaultRef.
+	Vault string `yaml:"vault,omitempty"`
 }
 
 func (app *Application) initialize() error {
@@ -68,12 +100,16 @@ func (app *Application) initialize() error {
 	app.Config.Server.Username = usernameStruct.Username
 	app.Config.Server.Password = password
 
+	app.client = newHTTPClient()
+
+	return nil
+}
+
+func newHTTPClient() *http.Client {
 	customTransport := &http.Transport{
 		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
 	}
-	app.client = &http.Client{Timeout: time.Second * 10, Transport: customTransport}
-
-	return nil
+	return &http.Client{Timeout: time.Second * 10, Transport: customTransport}
 }
 
 func GetApplication() *Application {
@@ -133,36 +169,144 @@ func (app *Application) ListSecrets() *[]secret.Secret {
 	return &secrets
 }
 
-func List(app Application, ds []secret.DecodedSecret) error {
-	columns := []table.Column{
-		{Title: "SecretID", Width: 10},
-		{Title: "Metadata", Width: 25},
-		{Title: "Data", Width: 80},
+func (app *Application) ListSecretsByTags(tags []string, matchAll bool) *[]secret.Secret {
+	app.initializeAndLogin()
+	secrets, err := clientRequest.SendGetSecretListByTags(app.client, app.Config.Server.Host, app.Config.Server.Token, tags, matchAll)
+	if err != nil {
+		log.Printf(err.Error())
+		return nil
 	}
 
-	var rows []table.Row
-	for _, v := range ds {
-		meta, err := GetFileInfo(v.Metadata)
+	return &secrets
+}
+
+// SecretPager fetches a secret listing page by page as it is consumed.
+type SecretPager struct {
+	app    Application
+	opts   clientRequest.ListOptions
+	cursor string
+	done   bool
+}
+
+// NewSecretPager logs in and returns a pager over the secrets selected by opts.
+func (app Application) NewSecretPager(opts clientRequest.ListOptions) *SecretPager {
+	app = *app.login()
+	return &SecretPager{app: app, opts: opts}
+}
+
+// Next returns the next page of secrets. It returns no secrets once Done.
+func (p *SecretPager) Next() ([]secret.Secret, error) {
+	if p.done {
+		return nil, nil
+	}
+	page, err := clientRequest.GetSecretPage(p.app.client, p.app.Config.Server.Host, p.app.Config.Server.Token, p.opts, p.cursor)
+	if err != nil {
+		return nil, err
+	}
+	p.cursor = page.NextCursor
+	p.done = page.NextCursor == ""
+	return page.Items, nil
+}
+
+// Done reports whether all pages have been fetched.
+func (p *SecretPager) Done() bool {
+	return p.done
+}
+
+// UpdateTags applies tag changes to the secret: "+name" or "name" adds the
+// tag, "-name" removes it.
+func (app Application) UpdateTags(id string, changes []string) error {
+
+	app = *app.login()
+	for _, change := range changes {
+		var err error
+		switch {
+		case strings.HasPrefix(change, "-"):
+			err = clientRequest.RemoveTag(app.client, app.Config.Server.Host, app.Config.Server.Token, id, change[1:])
+		default:
+			err = clientRequest.AddTag(app.client, app.Config.Server.Host, app.Config.Server.Token, id, strings.TrimPrefix(change, "+"))
+		}
 		if err != nil {
-			rows = append(rows, []string{strconv.Itoa(int(v.ID)), v.Metadata, v.ValueToString()})
-		} else {
-			filename := []string{meta[0], meta[1]}
-			rows = append(rows, []string{strconv.Itoa(int(v.ID)), strings.Join(filename, "."), v.ValueToString()})
+			return fmt.Errorf("cannot apply %s: %w", change, err)
 		}
+	}
+	return nil
+
+}
+
+var listColumns = []table.Column{
+	{Title: "SecretID", Width: 10},
+	{Title: "Path", Width: 25},
+	{Title: "Name", Width: 25},
+	{Title: "Tags", Width: 20},
+	{Title: "Updated", Width: 16},
+	{Title: "Accessed", Width: 16},
+	{Title: "Data", Width: 80},
+}
 
+// FormatTime formats a timestamp for tables in local time, nil as "never".
+func FormatTime(t *time.Time) string {
+	if t == nil || t.IsZero() {
+		return "never"
 	}
+	return t.Local().Format("2006-01-02 15:04")
+}
 
-	m := list.NewModel(columns, rows)
+func List(app Application, ds []secret.DecodedSecret) error {
+	m := list.NewModel(listColumns, secretRows(ds))
 	if _, err := tea.NewProgram(m).Run(); err != nil {
 		return fmt.Errorf("could not start passKeeper: %s\n", err)
 	}
 	return nil
 }
 
-func (app Application) CreateTextSecret(meta, data string) error {
+// ListPaged shows the secrets of the pager, loading further pages as the
+// user scrolls towards the end of the table.
+func ListPaged(pager *SecretPager) error {
+	loadMore := func() ([]table.Row, bool, error) {
+		secrets, err := pager.Next()
+		if err != nil {
+			return nil, false, err
+		}
+		ds, err := secret.GetDecodedSecrets(secrets)
+		if err != nil {
+			return nil, false, err
+		}
+		return secretRows(ds), !pager.Done(), nil
+	}
+	rows, more, err := loadMore()
+	if err != nil {
+		return err
+	}
+
+	m := list.NewPagedModel(listColumns, rows, more, loadMore)
+	if _, err := tea.NewProgram(m).Run(); err != nil {
+		return fmt.Errorf("could not start passKeeper: %s\n", err)
+	}
+	return nil
+}
+
+func secretRows(ds []secret.DecodedSecret) []table.Row {
+	var rows []table.Row
+	for _, v := range ds {
+		data := v.ValueToString()
+		warning := v.ExpiryWarning(time.Now())
+		if identity, ok := v.Value.(*secret.Identity); ok && warning == "" {
+			warning = identity.ExpiryWarning(time.Now())
+		}
+		if warning != "" {
+			data = "[" + warning + "] " + data
+		}
+		rows = append(rows, []string{strconv.Itoa(int(v.ID)), v.Path, v.TypedMeta().Label(), strings.Join(v.Tags, ","),
+			FormatTime(&v.UpdatedAt), FormatTime(v.LastAccessedAt), data})
+	}
+	return rows
+}
+
+func (app Application) CreateTextSecret(target NewSecret, meta, data string) error {
 
 	app = *app.login()
-	err := clientRequest.PostTextSecret(app.client, app.Config.Server.Host, app.Config.Server.Token, meta, data, 0)
+	err := clientRequest.PostTextSecret(app.client, app.Config.Server.Host, app.Config.Server.Token, meta, data, 0, secretOptions(target)...)
 	if err != nil {
 		return err
 	}
@@ -179,10 +323,10 @@ func (app Application) EditTextSecret(id uint, meta, data string) error {
 	return nil
 
 }
-func (app Application) CreateKVSecret(meta, key, value string) error {
+func (app Application) CreateKVSecret(target NewSecret, meta, key, value string) error {
 
 	app = *app.login()
-	err := clientRequest.PostKVSecret(app.client, app.Config.Server.Host, app.Config.Server.Token, meta, key, value, 0)
+	err := clientRequest.PostKVSecret(app.client, app.Config.Server.Host, app.Config.Server.Token, meta, key, value, 0, secretOptions(target)...)
 	if err != nil {
 		return err
 	}
@@ -200,20 +344,129 @@ func (app Application) EditKVSecret(id uint, meta, key, value string) error {
 
 }
 
-func (app Application) CreateCCSecret(meta, cnn, exp, cvv, cholder string) error {
+func (app Application) CreateCCSecret(target NewSecret, meta, cnn, exp, cvv, cholder string) error {
+
+	app = *app.login()
+	err := clientRequest.PostCCSecret(app.client, app.Config.Server.Host, app.Config.Server.Token, meta, cnn, exp, cvv, cholder, 0, secretOptions(target)...)
+	if err != nil {
+		return err
+	}
+	return nil
+
+}
+
+// CreateFileSecret uploads the file with its name, MIME type, size, checksum
+// and mode. An empty name defaults to the filename without extension.
+func (app Application) CreateFileSecret(target NewSecret, name, description, path string) error {
 
 	app = *app.login()
-	err := clientRequest.PostCCSecret(app.client, app.Config.Server.Host, app.Config.Server.Token, meta, cnn, exp, cvv, cholder, 0)
+	opts := secretOptions(target)
+	if name != "" {
+		opts = append(opts, clientRequest.WithName(name))
+	}
+	err := clientRequest.PostFileSecret(app.client, app.Config.Server.Host, app.Config.Server.Token, description, path, 0, opts...)
+	if err != nil {
+		return err
+	}
+	return nil
+
+}
+
+func (app Application) CreateBankSecret(target NewSecret, meta string, account secret.BankAccount) error {
+
+	app = *app.login()
+	err := clientRequest.PostBankSecret(app.client, app.Config.Server.Host, app.Config.Server.Token, meta, account, 0, secretOptions(target)...)
 	if err != nil {
 		return err
 	}
 	return nil
 
 }
-func (app Application) CreateFileSecret(meta, path string) error {
+
+func (app Application) EditBankSecret(id uint, meta string, account secret.BankAccount) error {
+
+	app = *app.login()
+	err := clientRequest.PostBankSecret(app.client, app.Config.Server.Host, app.Config.Server.Token, meta, account, id)
+	if err != nil {
+		return err
+	}
+	return nil
+
+}
+
+func (app Application) CreateEnvSecret(target NewSecret, meta string, bundle secret.EnvBundle) error {
+
+	app = *app.login()
+	err := clientRequest.PostEnvSecret(app.client, app.Config.Server.Host, app.Config.Server.Token, meta, bundle, 0, secretOptions(target)...)
+	if err != nil {
+		return err
+	}
+	return nil
+
+}
+
+func (app Application) EditEnvSecret(id uint, meta string, bundle secret.EnvBundle) error {
+
+	app = *app.login()
+	err := clientRequest.PostEnvSecret(app.client, app.Config.Server.Host, app.Config.Server.Token, meta, bundle, id)
+	if err != nil {
+		return err
+	}
+	return nil
+
+}
+
+func (app Application) CreateIdentitySecret(target NewSecret, meta string, identity secret.Identity, scans []string) error {
+
+	app = *app.login()
+	saved, err := clientRequest.PostIdentitySecret(app.client, app.Config.Server.Host, app.Config.Server.Token, meta, identity, 0, secretOptions(target)...)
+	if err != nil {
+		return err
+	}
+	return app.attachScans(saved.ID, scans)
+
+}
+
+func (app Application) EditIdentitySecret(id uint, meta string, identity secret.Identity, scans []string) error {
 
 	app = *app.login()
-	err := clientRequest.PostFileSecret(app.client, app.Config.Server.Host, app.Config.Server.Token, meta, path, 0)
+	if _, err := clientRequest.PostIdentitySecret(app.client, app.Config.Server.Host, app.Config.Server.Token, meta, identity, id); err != nil {
+		return err
+	}
+	return app.attachScans(id, scans)
+
+}
+
+// attachScans attaches every scan to the identity document with the ID.
+func (app Application) attachScans(id uint, scans []string) error {
+	for _, path := range scans {
+		_, err := clientRequest.PostAttachment(app.client, app.Config.Server.Host, app.Config.Server.Token, strconv.FormatUint(uint64(id), 10), path)
+		if err != nil {
+			return fmt.Errorf("cannot attach %s to secret %d: %w", path, id, err)
+		}
+	}
+	return nil
+}
+
+func (app Application) CreateCertificateSecret(target NewSecret, meta, certPath, keyPath string) error {
+	chain, err := os.ReadFile(certPath)
+	if err != nil {
+		return fmt.Errorf("cannot read certificate: %w", err)
+	}
+	var key []byte
+	if keyPath != "" {
+		key, err = os.ReadFile(keyPath)
+		if err != nil {
+			return fmt.Errorf("cannot read private key: %w", err)
+		}
+	}
+	cert := secret.Certificate{Chain: string(chain), PrivateKey: string(key)}
+	if err := cert.Validate(); err != nil {
+		return err
+	}
+
+	app = *app.login()
+	err = clientRequest.PostCertificateSecret(app.client, app.Config.Server.Host, app.Config.Server.Token, meta, cert.Chain, cert.PrivateKey, 0, secretOptions(target)...)
 	if err != nil {
 		return err
 	}
@@ -221,6 +474,51 @@ func (app Application) CreateFileSecret(meta, path string) error {
 
 }
 
+func (app Application) ExpiringCertificates(within time.Duration) ([]secret.CertificateInfo, error) {
+
+	app = *app.login()
+	certs, err := clientRequest.GetExpiringCertificates(app.client, app.Config.Server.Host, app.Config.Server.Token, within)
+	if err != nil {
+		return nil, err
+	}
+	return certs, nil
+
+}
+
+// SetExpiry sets the expiry date of the secret, nil removes it.
+func (app Application) SetExpiry(id string, expiresAt *time.Time) error {
+
+	app = *app.login()
+	return clientRequest.SetSecretExpiry(app.client, app.Config.Server.Host, app.Config.Server.Token, id, expiresAt)
+
+}
+
+// ExpiringSecrets returns the secrets of the vault expiring within the
+// duration, including the ones which already expired and were archived.
+func (app Application) ExpiringSecrets(vault uint, within time.Duration) ([]secret.Secret, error) {
+
+	app = *app.login()
+	secrets, err := clientRequest.GetVaultExpiringSecrets(app.client, app.Config.Server.Host, app.Config.Server.Token, vault, within)
+	if err != nil {
+		return nil, err
+	}
+	return secrets, nil
+
+}
+
+// StaleSecrets returns the secrets which have not been read for the given
+// duration.
+func (app Application) StaleSecrets(unusedFor time.Duration) ([]secret.Secret, error) {
+
+	app = *app.login()
+	secrets, err := clientRequest.GetStaleSecrets(app.client, app.Config.Server.Host, app.Config.Server.Token, unusedFor)
+	if err != nil {
+		return nil, err
+	}
+	return secrets, nil
+
+}
+
 func (app Application) EditCCSecret(id uint, meta, cnn, exp, cvv, cholder string) error {
 
 	app = *app.login()
@@ -243,6 +541,102 @@ func (app Application) GetSecret(id string) (*secret.Secret, error) {
 
 }
 
+// ResolveID turns a secret reference into its ID. Numeric references are IDs
+// and are returned as is, anything else is looked up as a path in the vault,
+// or among the personal secrets for a vault of zero.
+func (app Application) ResolveID(ref string, vault uint) (string, error) {
+	if _, err := strconv.ParseUint(ref, 10, 64); err == nil {
+		return ref, nil
+	}
+
+	app = *app.login()
+	return app.resolvePath(ref, vault)
+
+}
+
+// resolvePath returns the ID of the secret stored at the path ref in the
+// vault. The lookup leaves out the value, so resolving a path does not count
+// as an access to the secret.
+func (app Application) resolvePath(ref string, vault uint) (string, error) {
+	path, err := secret.NormalizePath(ref)
+	if err != nil {
+		return "", err
+	}
+	s, err := clientRequest.ResolveVaultPath(app.client, app.Config.Server.Host, app.Config.Server.Token, vault, path)
+	if err != nil {
+		return "", fmt.Errorf("cannot find secret %s: %w", ref, err)
+	}
+	return strconv.Itoa(int(s.ID)), nil
+}
+
+func (app Application) MoveSecret(id, path string) error {
+
+	app = *app.login()
+	return clientRequest.MoveSecret(app.client, app.Config.Server.Host, app.Config.Server.Token, id, path)
+
+}
+
+// CopySecret duplicates the secret under path and returns the copy.
+func (app Application) CopySecret(id, path string) (*secret.Secret, error) {
+
+	app = *app.login()
+	return clientRequest.CopySecret(app.client, app.Config.Server.Host, app.Config.Server.Token, id, path)
+
+}
+
+// ListDirectory returns the secrets of the vault stored below prefix without
+// their values.
+func (app Application) ListDirectory(prefix string, vault uint) ([]secret.Secret, error) {
+
+	app = *app.login()
+	secrets, err := clientRequest.ListVaultPath(app.client, app.Config.Server.Host, app.Config.Server.Token, vault, prefix)
+	if err != nil {
+		return nil, err
+	}
+	return secrets, nil
+
+}
+
+// SearchSecrets returns the secrets matching the query without their values,
+// searching the vault or, for a vault of zero, the personal secrets.
+func (app Application) SearchSecrets(query string, vault uint, limit int) ([]secret.Secret, error) {
+
+	app = *app.login()
+	secrets, err := clientRequest.SearchVaultSecrets(app.client, app.Config.Server.Host, app.Config.Server.Token, query, vault, limit)
+	if err != nil {
+		return nil, err
+	}
+	return secrets, nil
+
+}
+
+// DirEntries returns the direct children of prefix among the given paths, like
+// ls does for a directory. Subdirectories end with a slash and are listed once.
+func DirEntries(prefix string, paths []string) []string {
+	prefix = strings.Trim(prefix, "/")
+	if prefix != "" {
+		prefix += "/"
+	}
+	var entries []string
+	seen := make(map[string]bool)
+	for _, p := range paths {
+		if !strings.HasPrefix(p, prefix) {
+			continue
+		}
+		entry := strings.TrimPrefix(p, prefix)
+		if i := strings.Index(entry, "/"); i >= 0 {
+			entry = entry[:i+1]
+		}
+		if entry == "" || seen[entry] {
+			continue
+		}
+		seen[entry] = true
+		entries = append(entries, entry)
+	}
+	sort.Strings(entries)
+	return entries
+}
+
 func (app Application) DeleteSecret(id string) error {
 
 	app = *app.login()
@@ -254,30 +648,152 @@ func (app Application) DeleteSecret(id string) error {
 
 }
 
-func (app Application) DumpSecret(id string) (string, error) {
+// GetTrash lists the trashed secrets of the vault, zero is the default vault.
+func (app Application) GetTrash(vault uint) ([]secret.Secret, error) {
+
+	app = *app.login()
+	secrets, err := clientRequest.GetVaultTrash(app.client, app.Config.Server.Host, app.Config.Server.Token, vault)
+	if err != nil {
+		return nil, err
+	}
+	return secrets, nil
+
+}
+
+func (app Application) RestoreSecret(id string) error {
+
+	app = *app.login()
+	return clientRequest.RestoreSecret(app.client, app.Config.Server.Host, app.Config.Server.Token, id)
+
+}
+
+func (app Application) PurgeSecret(id string) error {
+
+	app = *app.login()
+	return clientRequest.PurgeSecret(app.client, app.Config.Server.Host, app.Config.Server.Token, id)
+
+}
+
+// EmptyTrash purges the trashed secrets of the vault, zero is the default
+// vault.
+func (app Application) EmptyTrash(vault uint) (int, error) {
+
+	app = *app.login()
+	return clientRequest.EmptyVaultTrash(app.client, app.Config.Server.Host, app.Config.Server.Token, vault)
+
+}
+
+// Confirm asks a yes/no question on out and reads the answer from in. Only
+// "y" and "yes" confirm.
+func Confirm(in io.Reader, out io.Writer, question string) bool {
+	fmt.Fprintf(out, "%s [y/N] ", question)
+	answer, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && answer == "" {
+		return false
+	}
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true
+	}
+	return false
+}
+
+// DumpSecret saves binary secret data on disk and returns the paths of the
+// written files. For identity documents all attached scans are saved,
+// environment bundles are written as dotenv files.
+func (app Application) DumpSecret(id string) ([]string, error) {
 
 	app = *app.login()
 	sec, err := clientRequest.GetSecret(app.client, app.Config.Server.Host, app.Config.Server.Token, id)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	if sec.SecretType != "ByteSlice" {
-		return "", fmt.Errorf("only bynary data could be saved on disk")
+	if sec.SecretType != "ByteSlice" && sec.SecretType != "Identity" && sec.SecretType != "EnvBundle" {
+		return nil, fmt.Errorf("only bynary data could be saved on disk")
 	}
 	var secrets []secret.Secret
 	secrets = append(secrets, *sec)
 	decoded, err := secret.GetDecodedSecrets(secrets)
 	if err != nil {
-		return "", fmt.Errorf("cannot decode secret. %s", err.Error())
+		return nil, fmt.Errorf("cannot decode secret. %s", err.Error())
+	}
+
+	if _, ok := decoded[0].Value.(*secret.Identity); ok {
+		attachments, err := clientRequest.GetAttachments(app.client, app.Config.Server.Host, app.Config.Server.Token, id)
+		if err != nil {
+			return nil, fmt.Errorf("cannot list scans. %s", err.Error())
+		}
+		var paths []string
+		for _, a := range attachments {
+			path, err := app.DumpAttachment(id, a.Filename)
+			if err != nil {
+				return paths, fmt.Errorf("cannot dump scan %s. %s", a.Filename, err.Error())
+			}
+			paths = append(paths, path)
+		}
+		return paths, nil
+	}
+	if bundle, ok := decoded[0].Value.(*secret.EnvBundle); ok {
+		path, err := saveOnDisk([]byte(bundle.Dotenv()), fmt.Sprintf("secret-%d.env", sec.ID), 0600)
+		if err != nil {
+			return nil, fmt.Errorf("cannot save data on disk. %s", err.Error())
+		}
+		return []string{path}, nil
 	}
 
 	data := decoded[0].Value.(*secret.ByteSlice)
-	path, err := SaveBinarySecretOnDisk(*data, sec.Metadata)
+	path, err := SaveBinarySecretOnDisk(*data, sec.TypedMeta(), sec.ID)
 	if err != nil {
-		return "", fmt.Errorf("cannot save data on disk. %s", err.Error())
+		return nil, fmt.Errorf("cannot save data on disk. %s", err.Error())
 	}
 
+	return []string{path}, nil
+
+}
+
+func (app Application) AttachFile(id, path string) (*secret.Attachment, error) {
+
+	app = *app.login()
+	attachment, err := clientRequest.PostAttachment(app.client, app.Config.Server.Host, app.Config.Server.Token, id, path)
+	if err != nil {
+		return nil, err
+	}
+	return attachment, nil
+
+}
+
+func (app Application) ListAttachments(id string) ([]secret.Attachment, error) {
+
+	app = *app.login()
+	attachments, err := clientRequest.GetAttachments(app.client, app.Config.Server.Host, app.Config.Server.Token, id)
+	if err != nil {
+		return nil, err
+	}
+	return attachments, nil
+
+}
+
+func (app Application) DetachFile(id, name string) error {
+
+	app = *app.login()
+	return clientRequest.DeleteAttachment(app.client, app.Config.Server.Host, app.Config.Server.Token, id, name)
+
+}
+
+// DumpAttachment saves the named attachment of the secret on disk and returns
+// the path of the written file.
+func (app Application) DumpAttachment(id, name string) (string, error) {
+
+	app = *app.login()
+	attachment, err := clientRequest.GetAttachment(app.client, app.Config.Server.Host, app.Config.Server.Token, id, name)
+	if err != nil {
+		return "", err
+	}
+	path, err := saveOnDisk(attachment.Data, attachment.Filename, 0600)
+	if err != nil {
+		return "", fmt.Errorf("cannot save data on disk. %s", err.Error())
+	}
 	return path, nil
 
 }
@@ -372,7 +888,21 @@ func DeleteFolderStructure() error {
 	return nil
 }
 
-func SaveBinarySecretOnDisk(data []byte, meta string) (string, error) {
+// SaveBinarySecretOnDisk writes file secret data under its original filename
+// and mode after checking it against the stored checksum.
+func SaveBinarySecretOnDisk(data []byte, meta secret.SecretMeta, id uint) (string, error) {
+	if meta.File.SHA256 != "" && secret.Checksum(data) != meta.File.SHA256 {
+		return "", fmt.Errorf("checksum mismatch for %s", meta.File.Filename)
+	}
+	filename := meta.File.Filename
+	if filename == "" {
+		filename = fmt.Sprintf("secret-%d.bin", id)
+	}
+
+	return saveOnDisk(data, filename, meta.File.FileMode())
+}
+
+func saveOnDisk(data []byte, filename string, mode os.FileMode) (string, error) {
 	home, err := os.UserHomeDir()
 	if err != nil {
 		return "", err
@@ -384,13 +914,9 @@ func SaveBinarySecretOnDisk(data []byte, meta string) (string, error) {
 		log.Print("cannot create a file")
 		return "", err
 	}
-	matches, err := GetFileInfo(meta)
-	if err != nil {
-		return "", err
-	}
 
-	fullFilename := matches[0] + "." + matches[1]
-	f, err := os.Create(filepath.Join(fullPath, fullFilename))
+	fullFilename := filepath.Base(filename)
+	f, err := os.OpenFile(filepath.Join(fullPath, fullFilename), os.O_RDWR|os.O_CREATE|os.O_TRUNC, mode)
 	if err != nil {
 		return "", err
 	}
@@ -404,13 +930,14 @@ func SaveBinarySecretOnDisk(data []byte, meta string) (string, error) {
 
 }
 
+// GetFileInfo splits legacy name|ext|description metadata. The extension may
+// be empty and the description may contain "|".
 func GetFileInfo(meta string) ([]string, error) {
-	re := regexp.MustCompile(`^([^|]+)\|([^|]+)\|(.+)$`)
-	matches := re.FindStringSubmatch(meta)
-	if len(matches) != 4 {
+	parts := strings.SplitN(meta, "|", 3)
+	if len(parts) != 3 || parts[0] == "" || parts[2] == "" {
 		return nil, fmt.Errorf("invalid meta string: %s", meta)
 	}
-	return matches[1:], nil
+	return parts, nil
 }
 func SetUsername(username string) error {
 	creds, err := GetUsername()
@@ -420,7 +947,11 @@ func SetUsername(username string) error {
 	if username != "" {
 		creds.Username = username
 	}
+	return saveConfig(creds)
+}
 
+// saveConfig writes the configuration to config.yaml.
+func saveConfig(creds *Username) error {
 	home, err := os.UserHomeDir()
 	if err != nil {
 		return err
@@ -454,6 +985,47 @@ func SetUsername(username string) error {
 	return nil
 }
 
+// ParseDuration extends time.ParseDuration with day (d) and week (w) units,
+// so reports can be asked for "30d" instead of "720h".
+func ParseDuration(s string) (time.Duration, error) {
+	unit := time.Duration(0)
+	switch {
+	case strings.HasSuffix(s, "d"):
+		unit = 24 * time.Hour
+	case strings.HasSuffix(s, "w"):
+		unit = 7 * 24 * time.Hour
+	default:
+		return time.ParseDuration(s)
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(s[:len(s)-1]))
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid duration: %s", s)
+	}
+	return time.Duration(n) * unit, nil
+}
+
+// ParseExpiry reads an expiry date given as a date (2006-01-02, local time),
+// an RFC 3339 timestamp or a duration from now such as 90d. "never" returns
+// nil for no expiry date.
+func ParseExpiry(s string, now time.Time) (*time.Time, error) {
+	s = strings.TrimSpace(s)
+	if s == "never" {
+		return nil, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", s, now.Location()); err == nil {
+		return &t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return &t, nil
+	}
+	d, err := ParseDuration(s)
+	if err != nil || d <= 0 {
+		return nil, fmt.Errorf("invalid expiry: %s, expected a date, a duration such as 90d or never", s)
+	}
+	t := now.Add(d)
+	return &t, nil
+}
+
 func PingServer(address string) bool {
 	timeout := time.Second * 5
 	s := strings.Split(address, ":")
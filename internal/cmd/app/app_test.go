@@ -1,10 +1,15 @@
 package cmd
 
 import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"gopkg.in/yaml.v2"
 )
@@ -86,3 +91,136 @@ func TestGetFileInfo(t *testing.T) {
 		}
 	}
 }
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		in        string
+		want      time.Duration
+		expectErr bool
+	}{
+		{in: "30d", want: 30 * 24 * time.Hour},
+		{in: "2w", want: 14 * 24 * time.Hour},
+		{in: "12h", want: 12 * time.Hour},
+		{in: "xd", expectErr: true},
+		{in: "-1d", expectErr: true},
+		{in: "soon", expectErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseDuration(tt.in)
+		if (err != nil) != tt.expectErr {
+			t.Errorf("ParseDuration(%q) error = %v, expectErr %v", tt.in, err, tt.expectErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseDuration(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestDirEntries(t *testing.T) {
+	paths := []string{"prod/db", "prod/payments/api-key", "prod/payments/db-password", "staging/db", "readme"}
+	tests := []struct {
+		prefix string
+		want   []string
+	}{
+		{prefix: "", want: []string{"prod/", "readme", "staging/"}},
+		{prefix: "prod/", want: []string{"db", "payments/"}},
+		{prefix: "/prod/payments", want: []string{"api-key", "db-password"}},
+		{prefix: "dev", want: nil},
+	}
+
+	for _, tt := range tests {
+		got := DirEntries(tt.prefix, paths)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("DirEntries(%q) = %v, want %v", tt.prefix, got, tt.want)
+		}
+	}
+}
+
+func TestConfirm(t *testing.T) {
+	tests := []struct {
+		answer string
+		want   bool
+	}{
+		{answer: "y\n", want: true},
+		{answer: " YES \n", want: true},
+		{answer: "yes", want: true},
+		{answer: "n\n", want: false},
+		{answer: "\n", want: false},
+		{answer: "", want: false},
+		{answer: "yep\n", want: false},
+	}
+
+	for _, tt := range tests {
+		var out strings.Builder
+		got := Confirm(strings.NewReader(tt.answer), &out, "Delete?")
+		if got != tt.want {
+			t.Errorf("Confirm(%q) = %v, want %v", tt.answer, got, tt.want)
+		}
+		if out.String() != "Delete? [y/N] " {
+			t.Errorf("unexpected prompt %q", out.String())
+		}
+	}
+}
+
+func TestParseExpiry(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		in        string
+		want      *time.Time
+		expectErr bool
+	}{
+		{in: "90d", want: ptrTime(now.Add(90 * 24 * time.Hour))},
+		{in: "12h", want: ptrTime(now.Add(12 * time.Hour))},
+		{in: "2024-06-30", want: ptrTime(time.Date(2024, 6, 30, 0, 0, 0, 0, time.UTC))},
+		{in: "2024-06-30T08:00:00Z", want: ptrTime(time.Date(2024, 6, 30, 8, 0, 0, 0, time.UTC))},
+		{in: "never", want: nil},
+		{in: "0d", expectErr: true},
+		{in: "soon", expectErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseExpiry(tt.in, now)
+		if (err != nil) != tt.expectErr {
+			t.Errorf("ParseExpiry(%q) error = %v, expectErr %v", tt.in, err, tt.expectErr)
+			continue
+		}
+		if (got == nil) != (tt.want == nil) || got != nil && !got.Equal(*tt.want) {
+			t.Errorf("ParseExpiry(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func ptrTime(t time.Time) *time.Time {
+	return &t
+}
+
+func TestResolveID(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The secret is stored at exactly prod/db, nothing is stored below it.
+		if r.URL.Path != "/api/secret/resolve" || r.URL.Query().Get("path") != "prod/db" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fmt.Fprintln(w, `{"ID": 7, "Path": "prod/db", "SecretType": "Text"}`)
+	}))
+	defer ts.Close()
+	appl := Application{client: ts.Client()}
+	appl.Config.Server.Host = strings.TrimPrefix(ts.URL, "https://")
+
+	id, err := appl.resolvePath("/prod/db/", 0)
+	if err != nil {
+		t.Fatalf("didn't expect error, got %v", err)
+	}
+	if id != "7" {
+		t.Errorf("resolvePath() = %s, want 7", id)
+	}
+	if _, err := appl.resolvePath("prod", 0); err == nil {
+		t.Errorf("expected error for a path without a secret")
+	}
+	// IDs are returned without asking the server.
+	if id, err := appl.ResolveID("42", 5); err != nil || id != "42" {
+		t.Errorf("ResolveID(42) = %s, %v, want 42", id, err)
+	}
+}
@@ -0,0 +1,194 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	secret "passKeeper/internal/models/secret"
+	clientRequest "passKeeper/pkg"
+
+	"gopkg.in/yaml.v2"
+)
+
+// operationsFile is the format read by passKeeper apply.
+type operationsFile struct {
+	Operations []fileOperation `yaml:"operations"`
+}
+
+// fileOperation is one operation of an operations file. Creates use path as
+// the path of the new secret, updates and deletes address the secret by id
+// or, without an id, by path. An update with an id moves the secret to path.
+type fileOperation struct {
+	Op          string      `yaml:"op"`
+	ID          uint        `yaml:"id"`
+	Path        string      `yaml:"path"`
+	Type        string      `yaml:"type"`
+	Name        string      `yaml:"name"`
+	Description string      `yaml:"description"`
+	Expires     string      `yaml:"expires"`
+	File        string      `yaml:"file"`
+	Data        interface{} `yaml:"data"`
+}
+
+// secretTypes maps the type names used by passKeeper new to the secret types.
+var secretTypes = map[string]string{
+	"kv":       "KeyValue",
+	"txt":      "Text",
+	"text":     "Text",
+	"cc":       "CreditCard",
+	"file":     "ByteSlice",
+	"cert":     "Certificate",
+	"bank":     "BankAccount",
+	"identity": "Identity",
+	"env":      "EnvBundle",
+}
+
+// LoadOperations reads an operations file. Files of file secrets are read
+// relative to the directory of the operations file.
+func LoadOperations(path string, now time.Time) ([]secret.BatchOperation, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseOperations(data, filepath.Dir(path), now)
+}
+
+// ParseOperations parses the YAML operations into batch operations.
+func ParseOperations(data []byte, baseDir string, now time.Time) ([]secret.BatchOperation, error) {
+	var file operationsFile
+	if err := yaml.UnmarshalStrict(data, &file); err != nil {
+		return nil, err
+	}
+	if len(file.Operations) == 0 {
+		return nil, fmt.Errorf("no operations found")
+	}
+	ops := make([]secret.BatchOperation, len(file.Operations))
+	for i, fo := range file.Operations {
+		op, err := fo.batchOperation(baseDir, now)
+		if err != nil {
+			return nil, fmt.Errorf("operation %d: %s", i+1, err)
+		}
+		ops[i] = op
+	}
+	return ops, nil
+}
+
+func (fo fileOperation) batchOperation(baseDir string, now time.Time) (secret.BatchOperation, error) {
+	op := secret.BatchOperation{Op: fo.Op, ID: fo.ID}
+	switch fo.Op {
+	case secret.OpDelete:
+		if fo.ID == 0 && fo.Path == "" {
+			return op, fmt.Errorf("delete needs an id or a path")
+		}
+		op.Path = fo.Path
+		return op, nil
+	case secret.OpCreate, secret.OpUpdate:
+	default:
+		return op, fmt.Errorf("unknown op %q, expected create, update or delete", fo.Op)
+	}
+
+	req, err := fo.secretRequest(baseDir, now)
+	if err != nil {
+		return op, err
+	}
+	if fo.Op == secret.OpUpdate && fo.ID == 0 {
+		if fo.Path == "" {
+			return op, fmt.Errorf("update needs an id or a path")
+		}
+		op.Path = fo.Path
+	} else {
+		req.Path = fo.Path
+	}
+	op.Secret = &req
+	return op, nil
+}
+
+func (fo fileOperation) secretRequest(baseDir string, now time.Time) (secret.SecretRequest, error) {
+	secretType := fo.Type
+	if t, ok := secretTypes[secretType]; ok {
+		secretType = t
+	}
+
+	var req secret.SecretRequest
+	if secretType == "ByteSlice" {
+		if fo.File == "" {
+			return req, fmt.Errorf("file secrets need a file")
+		}
+		path := fo.File
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(baseDir, path)
+		}
+		var err error
+		if req, err = clientRequest.FileSecretRequest(path, fo.Description); err != nil {
+			return req, err
+		}
+		if fo.Name != "" {
+			req.Info.Name = fo.Name
+		}
+	} else {
+		if fo.Data == nil {
+			return req, fmt.Errorf("data is missing")
+		}
+		data, err := json.Marshal(jsonValue(fo.Data))
+		if err != nil {
+			return req, err
+		}
+		req = secret.SecretRequest{
+			Type: secretType,
+			Data: data,
+			Meta: fo.Description,
+			Info: &secret.SecretMeta{Name: fo.Name, Description: fo.Description},
+		}
+	}
+
+	switch fo.Expires {
+	case "":
+	case "never":
+		req.NoExpiry = true
+	default:
+		expiresAt, err := ParseExpiry(fo.Expires, now)
+		if err != nil {
+			return req, err
+		}
+		req.ExpiresAt = expiresAt
+	}
+	return req, nil
+}
+
+// jsonValue converts the maps decoded by yaml, which have interface{} keys,
+// into maps which can be encoded as JSON.
+func jsonValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, value := range v {
+			m[fmt.Sprint(k)] = jsonValue(value)
+		}
+		return m
+	case []interface{}:
+		for i, value := range v {
+			v[i] = jsonValue(value)
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// ApplyOperations applies the operations on the server in one transaction,
+// creating secrets in and resolving paths against the vault.
+func (app Application) ApplyOperations(ops []secret.BatchOperation, vault uint) (*secret.BatchResponse, error) {
+	for i := range ops {
+		ops[i].VaultID = vault
+		if ops[i].Secret != nil {
+			ops[i].Secret.VaultID = vault
+		}
+	}
+
+	app = *app.login()
+	return clientRequest.ApplyBatch(app.client, app.Config.Server.Host, app.Config.Server.Token, ops)
+
+}
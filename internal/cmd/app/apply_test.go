@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	secret "passKeeper/internal/models/secret"
+)
+
+func TestParseOperations(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "report.txt"), []byte("report"), 0600); err != nil {
+		t.Fatalf("couldn't create a test file: %v", err)
+	}
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	data := []byte(`
+operations:
+  - op: create
+    type: kv
+    path: prod/db-password
+    name: Database
+    expires: 30d
+    data:
+      key: admin
+      value: s3cret
+  - op: create
+    type: file
+    file: report.txt
+    description: Q3
+  - op: update
+    path: prod/api-token
+    type: Text
+    expires: never
+    data: {value: new-token}
+  - op: delete
+    id: 7
+`)
+
+	ops, err := ParseOperations(data, dir, now)
+	if err != nil {
+		t.Fatalf("didn't expect error, got %v", err)
+	}
+	if len(ops) != 4 {
+		t.Fatalf("expected 4 operations, got %d", len(ops))
+	}
+
+	create := ops[0]
+	if create.Op != secret.OpCreate || create.Secret.Type != "KeyValue" || create.Secret.Path != "prod/db-password" ||
+		create.Secret.Info.Name != "Database" || !create.Secret.ExpiresAt.Equal(now.Add(30*24*time.Hour)) {
+		t.Errorf("unexpected create operation %+v %+v", create, create.Secret)
+	}
+	var kv secret.KeyValue
+	if err := json.Unmarshal(create.Secret.Data, &kv); err != nil || kv.Key != "admin" || kv.Value != "s3cret" {
+		t.Errorf("unexpected data %s", create.Secret.Data)
+	}
+
+	file := ops[1].Secret
+	if file.Type != "ByteSlice" || file.Info.File.Filename != "report.txt" || file.Info.Name != "report" || file.Info.Description != "Q3" {
+		t.Errorf("unexpected file secret %+v", file.Info)
+	}
+
+	update := ops[2]
+	if update.Path != "prod/api-token" || update.Secret.Path != "" || !update.Secret.NoExpiry {
+		t.Errorf("unexpected update operation %+v %+v", update, update.Secret)
+	}
+
+	if del := ops[3]; del.Op != secret.OpDelete || del.ID != 7 || del.Secret != nil {
+		t.Errorf("unexpected delete operation %+v", del)
+	}
+}
+
+func TestParseOperationsErrors(t *testing.T) {
+	tests := []string{
+		``,
+		"operations:\n  - op: rename\n    id: 1\n",
+		"operations:\n  - op: delete\n",
+		"operations:\n  - op: update\n    type: Text\n    data: {value: x}\n",
+		"operations:\n  - op: create\n    type: Text\n",
+		"operations:\n  - op: create\n    type: file\n",
+		"operations:\n  - op: create\n    type: Text\n    data: {value: x}\n    expires: soon\n",
+		"operations:\n  - op: create\n    colour: red\n",
+	}
+
+	for _, data := range tests {
+		if _, err := ParseOperations([]byte(data), ".", time.Now()); err == nil {
+			t.Errorf("expected error for %q", data)
+		}
+	}
+}
@@ -0,0 +1,23 @@
+package cmd
+
+import (
+	"time"
+
+	audit "passKeeper/internal/models/audit"
+	clientRequest "passKeeper/pkg"
+)
+
+// AuditEvents returns the audit events of the user, newest first: its own
+// actions and the actions of others on its secrets. A period of zero returns
+// events of any age, an empty id events of all secrets.
+func (app Application) AuditEvents(within time.Duration, id string, limit int) ([]audit.AuditEvent, error) {
+	var since *time.Time
+	if within > 0 {
+		t := time.Now().Add(-within)
+		since = &t
+	}
+
+	app = *app.login()
+	return clientRequest.GetAuditEvents(app.client, app.Config.Server.Host, app.Config.Server.Token, since, id, limit)
+
+}
@@ -0,0 +1,28 @@
+package cmd
+
+import (
+	sec "passKeeper/internal/models/secret"
+	clientRequest "passKeeper/pkg"
+)
+
+// Changes returns all changes of the secrets of the user after the revision
+// since, zero for all of them, and the revision to ask for changes after
+// next time.
+func (app Application) Changes(since uint64) ([]sec.Change, uint64, error) {
+
+	app = *app.login()
+	var changes []sec.Change
+	for {
+		feed, err := clientRequest.GetChanges(app.client, app.Config.Server.Host, app.Config.Server.Token, since, 0)
+		if err != nil {
+			return nil, since, err
+		}
+		changes = append(changes, feed.Changes...)
+		// A page which does not advance would be asked for again forever.
+		if !feed.More || feed.Revision <= since {
+			return changes, feed.Revision, nil
+		}
+		since = feed.Revision
+	}
+
+}
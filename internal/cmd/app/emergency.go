@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	em "passKeeper/internal/models/emergency"
+	secret "passKeeper/internal/models/secret"
+	clientRequest "passKeeper/pkg"
+)
+
+// WaitHours converts the waiting period of an emergency contact to the whole
+// hours the server stores.
+func WaitHours(wait time.Duration) (int, error) {
+	if wait%time.Hour != 0 {
+		return 0, fmt.Errorf("waiting period must be whole hours")
+	}
+	hours := int(wait / time.Hour)
+	if hours < 1 || hours > em.MaxWaitHours {
+		return 0, fmt.Errorf("waiting period must be between 1 hour and %d days", em.MaxWaitHours/24)
+	}
+	return hours, nil
+}
+
+// EmergencyContacts returns the emergency contacts of the user.
+func (app Application) EmergencyContacts() ([]em.EmergencyContact, error) {
+
+	app = *app.login()
+	return clientRequest.GetEmergencyContacts(app.client, app.Config.Server.Host, app.Config.Server.Token)
+
+}
+
+// AddEmergencyContact designates the account login as an emergency contact
+// of the user, whose requests are granted after wait unless rejected.
+func (app Application) AddEmergencyContact(login string, wait time.Duration) (*em.EmergencyContact, error) {
+	hours, err := WaitHours(wait)
+	if err != nil {
+		return nil, err
+	}
+
+	app = *app.login()
+	return clientRequest.SetEmergencyContact(app.client, app.Config.Server.Host, app.Config.Server.Token, login, hours)
+
+}
+
+func (app Application) RemoveEmergencyContact(login string) error {
+
+	app = *app.login()
+	return clientRequest.RemoveEmergencyContact(app.client, app.Config.Server.Host, app.Config.Server.Token, login)
+
+}
+
+// ChangeEmergencyAccess applies one of the em.Action* actions to the
+// emergency access between the user and the account login.
+func (app Application) ChangeEmergencyAccess(login, action string) (*em.EmergencyContact, error) {
+
+	app = *app.login()
+	return clientRequest.ChangeEmergencyAccess(app.client, app.Config.Server.Host, app.Config.Server.Token, login, action)
+
+}
+
+// EmergencyOwners returns the accounts which designated the user as an
+// emergency contact.
+func (app Application) EmergencyOwners() ([]em.EmergencyContact, error) {
+
+	app = *app.login()
+	return clientRequest.GetEmergencyOwners(app.client, app.Config.Server.Host, app.Config.Server.Token)
+
+}
+
+// EmergencyEvents returns the history of the emergency access between the
+// user and the account login, with the user as the owner or the contact.
+func (app Application) EmergencyEvents(login string, asOwner bool) ([]em.EmergencyEvent, error) {
+
+	app = *app.login()
+	return clientRequest.GetEmergencyEvents(app.client, app.Config.Server.Host, app.Config.Server.Token, login, asOwner)
+
+}
+
+// EmergencySecrets lists the secrets of the account login once the user was
+// granted emergency access to them.
+func (app Application) EmergencySecrets(login string) ([]secret.Secret, error) {
+
+	app = *app.login()
+	return clientRequest.GetEmergencySecrets(app.client, app.Config.Server.Host, app.Config.Server.Token, login)
+
+}
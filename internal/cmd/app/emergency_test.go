@@ -0,0 +1,31 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWaitHours(t *testing.T) {
+	tests := []struct {
+		in        time.Duration
+		want      int
+		expectErr bool
+	}{
+		{7 * 24 * time.Hour, 168, false},
+		{time.Hour, 1, false},
+		{90 * time.Minute, 0, true},
+		{0, 0, true},
+		{400 * 24 * time.Hour, 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := WaitHours(tt.in)
+		if (err != nil) != tt.expectErr {
+			t.Errorf("WaitHours(%v) error = %v, expectErr %v", tt.in, err, tt.expectErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("WaitHours(%v) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package cmd
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	secret "passKeeper/internal/models/secret"
+	clientRequest "passKeeper/pkg"
+)
+
+// oneTimeKeySize is the size of the AES-256 keys of one-time shares.
+const oneTimeKeySize = 32
+
+// oneTimePath is the path of one-time share links, followed by the token.
+const oneTimePath = "/api/once/"
+
+// SealOneTime encrypts the plaintext with a new random AES-GCM key. The
+// ciphertext starts with the nonce, the key is returned base64url encoded
+// for the fragment of the link.
+func SealOneTime(plaintext []byte) ([]byte, string, error) {
+	key := make([]byte, oneTimeKeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, "", err
+	}
+	gcm, err := oneTimeCipher(key)
+	if err != nil {
+		return nil, "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, "", err
+	}
+	return gcm.Seal(nonce, nonce, plaintext, nil), base64.RawURLEncoding.EncodeToString(key), nil
+}
+
+// OpenOneTime decrypts a ciphertext sealed by SealOneTime.
+func OpenOneTime(ciphertext []byte, encodedKey string) ([]byte, error) {
+	key, err := base64.RawURLEncoding.DecodeString(encodedKey)
+	if err != nil || len(key) != oneTimeKeySize {
+		return nil, errors.New("invalid key in link")
+	}
+	gcm, err := oneTimeCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(ciphertext) < gcm.NonceSize() {
+		return nil, errors.New("ciphertext is too short")
+	}
+	nonce, sealed := ciphertext[:gcm.NonceSize()], ciphertext[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, sealed, nil)
+	if err != nil {
+		return nil, errors.New("cannot decrypt share, the link is damaged")
+	}
+	return plaintext, nil
+}
+
+func oneTimeCipher(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// OneTimeLink builds the link of a one-time share. The key is in the
+// fragment, which clients do not send to the server.
+func OneTimeLink(host, token, key string) string {
+	return "https://" + host + oneTimePath + token + "#" + key
+}
+
+// ParseOneTimeLink splits a link built by OneTimeLink into the server, the
+// token and the key.
+func ParseOneTimeLink(link string) (host, token, key string, err error) {
+	u, err := url.Parse(strings.TrimSpace(link))
+	if err != nil || u.Host == "" || !strings.HasPrefix(u.Path, oneTimePath) {
+		return "", "", "", fmt.Errorf("invalid one-time share link")
+	}
+	token = path.Base(u.Path)
+	if token == "" || token == "." || u.Fragment == "" {
+		return "", "", "", fmt.Errorf("incomplete one-time share link, the part after # is missing")
+	}
+	return u.Host, token, u.Fragment, nil
+}
+
+// ShareOnce encrypts the secret on the client and stores it as a one-time
+// share which can be opened views times until expiresAt. It returns the
+// link to hand out.
+func (app Application) ShareOnce(id string, views int, expiresAt time.Time) (string, error) {
+	s, err := app.GetSecret(id)
+	if err != nil {
+		return "", err
+	}
+	plaintext, err := json.Marshal(secret.Secret{SecretType: s.SecretType, Metadata: s.Metadata, Meta: s.Meta, Value: s.Value})
+	if err != nil {
+		return "", err
+	}
+	ciphertext, key, err := SealOneTime(plaintext)
+	if err != nil {
+		return "", err
+	}
+
+	app = *app.login()
+	created, err := clientRequest.CreateOneTimeShare(app.client, app.Config.Server.Host, app.Config.Server.Token,
+		secret.OneTimeShareRequest{Ciphertext: ciphertext, MaxViews: views, ExpiresAt: expiresAt})
+	if err != nil {
+		return "", err
+	}
+	return OneTimeLink(app.Config.Server.Host, created.Token, key), nil
+
+}
+
+// OpenShareLink uses up one view of the one-time share behind the link and
+// returns the decrypted secret and the views left. It needs no account.
+func OpenShareLink(link string) (*secret.Secret, int, error) {
+	host, token, key, err := ParseOneTimeLink(link)
+	if err != nil {
+		return nil, 0, err
+	}
+	opened, err := clientRequest.OpenOneTimeShare(newHTTPClient(), host, token)
+	if err != nil {
+		return nil, 0, fmt.Errorf("cannot open share, it was used up, has expired or never existed: %w", err)
+	}
+	plaintext, err := OpenOneTime(opened.Ciphertext, key)
+	if err != nil {
+		return nil, 0, err
+	}
+	var s secret.Secret
+	if err := json.Unmarshal(plaintext, &s); err != nil {
+		return nil, 0, err
+	}
+	return &s, opened.ViewsLeft, nil
+}
@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSealOneTime(t *testing.T) {
+	ciphertext, key, err := SealOneTime([]byte("hunter2"))
+	if err != nil {
+		t.Fatalf("didn't expect error, got %v", err)
+	}
+	if strings.Contains(string(ciphertext), "hunter2") {
+		t.Errorf("ciphertext contains the plaintext")
+	}
+
+	plaintext, err := OpenOneTime(ciphertext, key)
+	if err != nil {
+		t.Fatalf("didn't expect error, got %v", err)
+	}
+	if string(plaintext) != "hunter2" {
+		t.Errorf("OpenOneTime() = %q, want hunter2", plaintext)
+	}
+
+	_, otherKey, _ := SealOneTime([]byte("other"))
+	if _, err := OpenOneTime(ciphertext, otherKey); err == nil {
+		t.Errorf("expected error with the wrong key")
+	}
+}
+
+func TestParseOneTimeLink(t *testing.T) {
+	link := OneTimeLink("vault.example.com:8443", "abc_-123", "c2VjcmV0")
+	host, token, key, err := ParseOneTimeLink(link)
+	if err != nil {
+		t.Fatalf("didn't expect error, got %v", err)
+	}
+	if host != "vault.example.com:8443" || token != "abc_-123" || key != "c2VjcmV0" {
+		t.Errorf("ParseOneTimeLink() = %s, %s, %s", host, token, key)
+	}
+
+	for _, link := range []string{
+		"https://vault.example.com/api/once/abc",
+		"https://vault.example.com/api/secret/1#key",
+		"not a link",
+	} {
+		if _, _, _, err := ParseOneTimeLink(link); err == nil {
+			t.Errorf("expected error for %q", link)
+		}
+	}
+}
@@ -0,0 +1,197 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	org "passKeeper/internal/models/org"
+	clientRequest "passKeeper/pkg"
+)
+
+// VaultRef returns the reference of the current vault: override, e.g. from
+// the --vault flag, the one selected with vault use, or the default vault.
+func VaultRef(override string) (string, error) {
+	if override != "" {
+		return override, nil
+	}
+	creds, err := GetUsername()
+	if err != nil {
+		return "", err
+	}
+	if creds.Vault == "" {
+		return org.DefaultVault, nil
+	}
+	return creds.Vault, nil
+}
+
+// CurrentVault returns the ID of the current vault, see VaultRef, which list,
+// search, ls and new work on and in which paths are resolved. The default
+// vault has the ID zero and is resolved without asking the server.
+func (app Application) CurrentVault(override string) (uint, error) {
+	ref, err := VaultRef(override)
+	if err != nil {
+		return 0, err
+	}
+	return app.ResolveVault(ref)
+}
+
+// UseVault selects the vault for the following commands and remembers it in
+// config.yaml.
+func (app Application) UseVault(ref string) error {
+	ref = strings.ToLower(strings.TrimSpace(ref))
+	if _, err := app.ResolveVault(ref); err != nil {
+		return err
+	}
+	creds, err := GetUsername()
+	if err != nil {
+		return err
+	}
+	creds.Vault = ref
+	if ref == org.DefaultVault {
+		creds.Vault = ""
+	}
+	return saveConfig(creds)
+}
+
+// SplitVaultRef splits a vault reference of the form org/vault.
+func SplitVaultRef(ref string) (string, string, error) {
+	orgName, vaultName, ok := strings.Cut(ref, "/")
+	if !ok || orgName == "" || vaultName == "" || strings.Contains(vaultName, "/") {
+		return "", "", fmt.Errorf("invalid vault %q, expected org/vault", ref)
+	}
+	return strings.ToLower(orgName), strings.ToLower(vaultName), nil
+}
+
+// ResolveVault returns the ID of the vault referenced as org/vault for the
+// vault of an organization, or by name for a personal vault.
+func (app Application) ResolveVault(ref string) (uint, error) {
+	ref = strings.ToLower(strings.TrimSpace(ref))
+	if ref == "" || ref == org.DefaultVault {
+		return 0, nil
+	}
+	var vaults []org.Vault
+	vaultName := ref
+	if strings.Contains(ref, "/") {
+		orgName, name, err := SplitVaultRef(ref)
+		if err != nil {
+			return 0, err
+		}
+		if vaults, err = app.Vaults(orgName); err != nil {
+			return 0, err
+		}
+		vaultName = name
+	} else {
+		var err error
+		if vaults, err = app.PersonalVaults(); err != nil {
+			return 0, err
+		}
+	}
+	for _, v := range vaults {
+		if v.Name == vaultName {
+			return v.ID, nil
+		}
+	}
+	return 0, fmt.Errorf("vault %s not found", ref)
+}
+
+// MoveToVault moves the secret to the vault referenced as org/vault or by
+// name.
+func (app Application) MoveToVault(id, ref string) error {
+	vault, err := app.ResolveVault(ref)
+	if err != nil {
+		return err
+	}
+
+	app = *app.login()
+	return clientRequest.MoveSecretToVault(app.client, app.Config.Server.Host, app.Config.Server.Token, id, vault)
+
+}
+
+func (app Application) CreateOrganization(name string) (*org.Organization, error) {
+
+	app = *app.login()
+	return clientRequest.CreateOrganization(app.client, app.Config.Server.Host, app.Config.Server.Token, name)
+
+}
+
+// Organizations returns the organizations of the user with its role.
+func (app Application) Organizations() ([]org.Organization, error) {
+
+	app = *app.login()
+	return clientRequest.GetOrganizations(app.client, app.Config.Server.Host, app.Config.Server.Token)
+
+}
+
+func (app Application) Members(orgName string) ([]org.Member, error) {
+
+	app = *app.login()
+	return clientRequest.GetMembers(app.client, app.Config.Server.Host, app.Config.Server.Token, orgName)
+
+}
+
+// SetMember adds the account login to the organization or changes its role.
+func (app Application) SetMember(orgName, login, role string) (*org.Member, error) {
+	if !org.ValidRole(role) {
+		return nil, fmt.Errorf("invalid role %q, expected owner, admin, editor or viewer", role)
+	}
+
+	app = *app.login()
+	return clientRequest.SetMember(app.client, app.Config.Server.Host, app.Config.Server.Token, orgName, login, role)
+
+}
+
+func (app Application) RemoveMember(orgName, login string) error {
+
+	app = *app.login()
+	return clientRequest.RemoveMember(app.client, app.Config.Server.Host, app.Config.Server.Token, orgName, login)
+
+}
+
+func (app Application) Vaults(orgName string) ([]org.Vault, error) {
+
+	app = *app.login()
+	return clientRequest.GetVaults(app.client, app.Config.Server.Host, app.Config.Server.Token, orgName)
+
+}
+
+func (app Application) PersonalVaults() ([]org.Vault, error) {
+
+	app = *app.login()
+	return clientRequest.GetPersonalVaults(app.client, app.Config.Server.Host, app.Config.Server.Token)
+
+}
+
+// CreateVault creates the vault referenced as org/vault, or a personal vault
+// for a plain name.
+func (app Application) CreateVault(ref string) (*org.Vault, error) {
+	if !strings.Contains(ref, "/") {
+		app = *app.login()
+		return clientRequest.CreatePersonalVault(app.client, app.Config.Server.Host, app.Config.Server.Token, ref)
+	}
+	orgName, vaultName, err := SplitVaultRef(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	app = *app.login()
+	return clientRequest.CreateVault(app.client, app.Config.Server.Host, app.Config.Server.Token, orgName, vaultName)
+
+}
+
+// DeleteVault deletes the vault referenced as org/vault, which must be empty,
+// or the personal vault with the name together with its secrets. It returns
+// the number of secrets deleted.
+func (app Application) DeleteVault(ref string) (int, error) {
+	if !strings.Contains(ref, "/") {
+		app = *app.login()
+		return clientRequest.DeletePersonalVault(app.client, app.Config.Server.Host, app.Config.Server.Token, ref)
+	}
+	orgName, vaultName, err := SplitVaultRef(ref)
+	if err != nil {
+		return 0, err
+	}
+
+	app = *app.login()
+	return 0, clientRequest.DeleteVault(app.client, app.Config.Server.Host, app.Config.Server.Token, orgName, vaultName)
+
+}
@@ -0,0 +1,36 @@
+package cmd
+
+import "testing"
+
+func TestSplitVaultRef(t *testing.T) {
+	orgName, vaultName, err := SplitVaultRef("Acme/Prod")
+	if err != nil {
+		t.Fatalf("didn't expect error, got %v", err)
+	}
+	if orgName != "acme" || vaultName != "prod" {
+		t.Errorf("SplitVaultRef() = %s, %s, want acme, prod", orgName, vaultName)
+	}
+	for _, ref := range []string{"acme", "acme/", "/prod", "acme/prod/db"} {
+		if _, _, err := SplitVaultRef(ref); err == nil {
+			t.Errorf("expected error for %q", ref)
+		}
+	}
+}
+
+func TestCurrentVaultOverride(t *testing.T) {
+	ref, err := VaultRef("Default")
+	if err != nil {
+		t.Fatalf("didn't expect error, got %v", err)
+	}
+	if ref != "Default" {
+		t.Errorf("VaultRef() = %s, want Default", ref)
+	}
+	// The default vault is resolved without asking the server.
+	id, err := Application{}.CurrentVault("Default")
+	if err != nil {
+		t.Fatalf("didn't expect error, got %v", err)
+	}
+	if id != 0 {
+		t.Errorf("CurrentVault() = %d, want 0", id)
+	}
+}
@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	secret "passKeeper/internal/models/secret"
+	clientRequest "passKeeper/pkg"
+)
+
+// ShareSecret grants the account login read-only or read-write access to the
+// secret.
+func (app Application) ShareSecret(id, login string, readOnly bool) (*secret.Share, error) {
+
+	app = *app.login()
+	return clientRequest.ShareSecret(app.client, app.Config.Server.Host, app.Config.Server.Token, id,
+		secret.ShareRequest{Login: login, ReadOnly: readOnly})
+
+}
+
+func (app Application) Shares(id string) ([]secret.Share, error) {
+
+	app = *app.login()
+	return clientRequest.GetShares(app.client, app.Config.Server.Host, app.Config.Server.Token, id)
+
+}
+
+func (app Application) RevokeShare(id, login string) error {
+
+	app = *app.login()
+	return clientRequest.RevokeShare(app.client, app.Config.Server.Host, app.Config.Server.Token, id, login)
+
+}
+
+// SharedWithMe returns the secrets other accounts shared with the user.
+func (app Application) SharedWithMe() ([]secret.SharedSecret, error) {
+
+	app = *app.login()
+	return clientRequest.GetSharedSecrets(app.client, app.Config.Server.Host, app.Config.Server.Token)
+
+}
@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"errors"
+	"time"
+
+	"passKeeper/internal/events"
+	clientRequest "passKeeper/pkg"
+
+	"github.com/charmbracelet/log"
+)
+
+// Delays between attempts to reconnect a lost stream of changes. The delay
+// doubles while the server cannot be reached.
+const (
+	watchRetryMin = time.Second
+	watchRetryMax = time.Minute
+)
+
+// errWatchHandler wraps the errors of the function passed to Watch.
+type errWatchHandler struct{ err error }
+
+func (e errWatchHandler) Error() string { return e.err.Error() }
+
+// Watch calls fn with every change of the secrets the user can read, see
+// events.Subscribed for the first event of each stream. Lost streams are
+// reconnected with a new login, so fn gets another events.Subscribed event
+// after changes may have been missed. Watch returns when the first stream
+// cannot be opened or fn fails.
+func (app Application) Watch(fn func(events.Event) error) error {
+	app.initialize()
+	stream := *app.client
+	stream.Timeout = 0
+	connected := false
+	delay := watchRetryMin
+	for {
+		app.login()
+		err := clientRequest.WatchSecrets(&stream, app.Config.Server.Host, app.Config.Server.Token, func(e events.Event) error {
+			if e.Type == events.Subscribed {
+				connected = true
+				delay = watchRetryMin
+			}
+			if err := fn(e); err != nil {
+				return errWatchHandler{err}
+			}
+			return nil
+		})
+		var handlerErr errWatchHandler
+		if errors.As(err, &handlerErr) {
+			return handlerErr.err
+		}
+		if !connected {
+			if err == nil {
+				err = errors.New("the server closed the stream")
+			}
+			return err
+		}
+		if err != nil {
+			log.Warnf("lost the stream of changes: %s, reconnecting in %s", err, delay)
+		} else {
+			log.Warnf("the server closed the stream of changes, reconnecting in %s", delay)
+		}
+		time.Sleep(delay)
+		if delay *= 2; delay > watchRetryMax {
+			delay = watchRetryMax
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	wh "passKeeper/internal/models/webhook"
+	clientRequest "passKeeper/pkg"
+)
+
+// AddWebhook registers a webhook posting the events to the URL, for the
+// organization if it is not empty. Without events all events are posted,
+// without a secret the server generates one.
+func (app Application) AddWebhook(url string, events []string, organization, secret string) (*wh.WebhookCreated, error) {
+	req := wh.WebhookRequest{URL: url, Events: events, Org: organization, Secret: secret}
+
+	app = *app.login()
+	return clientRequest.CreateWebhook(app.client, app.Config.Server.Host, app.Config.Server.Token, req)
+
+}
+
+// Webhooks lists the personal webhooks of the user, or those of the
+// organization if it is not empty.
+func (app Application) Webhooks(organization string) ([]wh.Webhook, error) {
+
+	app = *app.login()
+	return clientRequest.GetWebhooks(app.client, app.Config.Server.Host, app.Config.Server.Token, organization)
+
+}
+
+func (app Application) RemoveWebhook(id string) error {
+
+	app = *app.login()
+	return clientRequest.DeleteWebhook(app.client, app.Config.Server.Host, app.Config.Server.Token, id)
+
+}
+
+// WebhookDeliveries returns the last deliveries of the webhook, the most
+// recent first.
+func (app Application) WebhookDeliveries(id string) ([]wh.Delivery, error) {
+
+	app = *app.login()
+	return clientRequest.GetWebhookDeliveries(app.client, app.Config.Server.Host, app.Config.Server.Token, id)
+
+}
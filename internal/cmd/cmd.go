@@ -3,20 +3,72 @@ package cmd
 import (
 	"fmt"
 	"log"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
 
 	app "passKeeper/internal/cmd/app"
+	bank "passKeeper/internal/cmd/tui/new/bank"
+	cert "passKeeper/internal/cmd/tui/new/cert"
 	cc "passKeeper/internal/cmd/tui/new/creditcard"
+	env "passKeeper/internal/cmd/tui/new/env"
 	f "passKeeper/internal/cmd/tui/new/file"
+	identity "passKeeper/internal/cmd/tui/new/identity"
 	kv "passKeeper/internal/cmd/tui/new/kv"
 	txt "passKeeper/internal/cmd/tui/new/txt"
 	conf "passKeeper/internal/cmd/tui/setup"
+	"passKeeper/internal/events"
+	audit "passKeeper/internal/models/audit"
+	em "passKeeper/internal/models/emergency"
+	org "passKeeper/internal/models/org"
 	sec "passKeeper/internal/models/secret"
+	wh "passKeeper/internal/models/webhook"
+	client "passKeeper/pkg"
 
 	"github.com/spf13/cobra"
 )
 
 var (
 	username, password string
+	certsWithin        string
+	reveal             bool
+	dumpAttachment     string
+	envFrom            string
+	listTags           []string
+	listAllTags        bool
+	newPath            string
+	searchLimit        int
+	listTypes          []string
+	listSort           string
+	listDesc           bool
+	listPageSize       int
+	deleteYes          bool
+	purgeAll           bool
+	expires            string
+	expiringWithin     string
+	applyFile          string
+	unusedFor          string
+	copyEdit           bool
+	shareWith          string
+	shareReadOnly      bool
+	vaultRef           string
+	memberRole         string
+	moveVault          string
+	onceViews          int
+	onceExpires        string
+	emergencyWait      string
+	emergencyOwner     bool
+	auditSince         string
+	auditSecret        string
+	auditLimit         int
+	webhookEvents      []string
+	webhookOrg         string
+	webhookSecret      string
+	watchExec          string
+	changesSince       uint64
 )
 var (
 	rootCmd = &cobra.Command{
@@ -27,7 +79,32 @@ var (
 	newCmd = &cobra.Command{
 		Use:   "new",
 		Short: "Generate a new secret.",
-		Long:  "Generate a new secret of a specific type, options include key-value pair (kv), credit card details (cc), text (txt), or file.",
+		Long:  "Generate a new secret of a specific type, options include key-value pair (kv), credit card details (cc), text (txt), file, certificate (cert), bank account (bank), identity document (identity) or environment bundle (env). With --path the secret is stored under a unique path such as prod/payments/db-password, with --expires the server stops serving it after the given date, with --vault it is stored in another vault than the current one, e.g. work or acme/prod.",
+	}
+	trashCmd = &cobra.Command{
+		Use:   "trash",
+		Short: "Work with deleted secrets.",
+		Long:  "Deleted secrets are kept in the trash until they are purged, either with passKeeper trash purge or by the server after its retention period. Every vault has its own trash, list and purge --all work on the current vault or the one given with --vault. Secrets of an organization vault are restored and purged by its editors.",
+	}
+	certsCmd = &cobra.Command{
+		Use:   "certs",
+		Short: "Work with stored certificates.",
+		Long:  "Reports computed from the X.509 certificates stored in passKeeper.",
+	}
+	orgCmd = &cobra.Command{
+		Use:   "org",
+		Short: "Work with organizations.",
+		Long:  "Organizations own vaults of secrets shared by their members. Members are owners, admins, editors or viewers: owners manage everything, admins manage vaults, editors and viewers, editors change secrets and viewers read them.",
+	}
+	vaultCmd = &cobra.Command{
+		Use:   "vault",
+		Short: "Work with the vaults of organizations.",
+		Long:  "Vaults group secrets. Personal vaults are referenced by name, the vaults of an organization as org/vault. Your secrets outside of other vaults are in the vault default. list, search, ls and new work on the vault selected with passKeeper vault use, or on the one given with --vault.",
+	}
+	emergencyCmd = &cobra.Command{
+		Use:   "emergency",
+		Short: "Work with emergency access to your secrets.",
+		Long:  "Emergency contacts may request read access to your default and personal vaults. A request is granted when you approve it, or automatically after the waiting period of the contact unless you reject it first. Secrets of organizations are never included.",
 	}
 )
 
@@ -51,10 +128,113 @@ func NewRootCommand() *cobra.Command {
 	rootCmd.AddCommand(editCmd)
 	rootCmd.AddCommand(describeCmd)
 	rootCmd.AddCommand(dumpCmd)
+	rootCmd.AddCommand(attachCmd)
+	rootCmd.AddCommand(detachCmd)
+	rootCmd.AddCommand(tagCmd)
+	rootCmd.AddCommand(mvCmd)
+	rootCmd.AddCommand(cpCmd)
+	rootCmd.AddCommand(shareCmd)
+	rootCmd.AddCommand(unshareCmd)
+	rootCmd.AddCommand(sharedCmd)
+	rootCmd.AddCommand(shareOnceCmd)
+	rootCmd.AddCommand(openShareCmd)
+	shareOnceCmd.Flags().IntVar(&onceViews, "views", 1, fmt.Sprintf("Number of times the link can be opened (at most %d)", sec.MaxOneTimeViews))
+	shareOnceCmd.Flags().StringVar(&onceExpires, "expires", "24h", "Delete the share after this period even if it was not opened (e.g. 1h, 7d)")
+	shareCmd.Flags().StringVar(&shareWith, "with", "", "Login of the user to share the secret with")
+	shareCmd.Flags().BoolVar(&shareReadOnly, "read-only", false, "Only allow reading the secret")
+	cpCmd.Flags().BoolVarP(&copyEdit, "edit", "e", false, "Open the copy in the edit form")
+	rootCmd.AddCommand(lsCmd)
+	rootCmd.AddCommand(searchCmd)
+	rootCmd.AddCommand(trashCmd)
+	trashCmd.AddCommand(trashListCmd)
+	trashCmd.AddCommand(trashRestoreCmd)
+	trashCmd.AddCommand(trashPurgeCmd)
+	deleteCmd.Flags().BoolVarP(&deleteYes, "yes", "y", false, "Do not ask for confirmation")
+	trashPurgeCmd.Flags().BoolVarP(&deleteYes, "yes", "y", false, "Do not ask for confirmation")
+	trashPurgeCmd.Flags().BoolVar(&purgeAll, "all", false, "Purge the whole trash")
+	trashListCmd.Flags().StringVar(&vaultRef, "vault", "", "List the trash of this vault instead of the current one (e.g. work or acme/prod)")
+	trashPurgeCmd.Flags().StringVar(&vaultRef, "vault", "", "With --all, purge the trash of this vault instead of the current one (e.g. work or acme/prod)")
+	searchCmd.Flags().IntVar(&searchLimit, "limit", 50, "Maximum number of matches to show")
+	listCmd.Flags().StringSliceVar(&listTags, "tag", nil, "Only list secrets with this tag (repeatable)")
+	listCmd.Flags().BoolVar(&listAllTags, "all-tags", false, "Require all given tags instead of any of them")
+	listCmd.Flags().StringSliceVar(&listTypes, "type", nil, "Only list secrets of this type, e.g. KeyValue (repeatable)")
+	listCmd.Flags().StringVar(&listSort, "sort", sec.SortName, "Sort by name, created, updated, type, accessed or accesses")
+	listCmd.Flags().BoolVar(&listDesc, "desc", false, "Sort in descending order")
+	listCmd.Flags().IntVar(&listPageSize, "page-size", 50, "Number of secrets loaded at a time")
+	newCmd.PersistentFlags().StringVar(&newPath, "path", "", "Store the secret under this path (e.g. prod/payments/db-password)")
+	newCmd.PersistentFlags().StringVar(&expires, "expires", "", "Expire the secret at this date or after this period (e.g. 2025-12-31, 90d)")
+	editCmd.Flags().StringVar(&expires, "expires", "", "Only change the expiry date (e.g. 2025-12-31, 90d or never)")
+	rootCmd.AddCommand(expiringCmd)
+	rootCmd.AddCommand(applyCmd)
+	rootCmd.AddCommand(staleCmd)
+	staleCmd.Flags().StringVar(&unusedFor, "unused-for", "90d", "Report secrets not read for this period (e.g. 90d, 12w)")
+	applyCmd.Flags().StringVarP(&applyFile, "file", "f", "", "YAML file with the operations to apply")
+	applyCmd.MarkFlagRequired("file")
+	expiringCmd.Flags().StringVar(&expiringWithin, "within", "7d", "Report secrets expiring within this period (e.g. 7d, 12h)")
+	expiringCmd.Flags().StringVar(&vaultRef, "vault", "", "Report the secrets of this vault instead of the current one (e.g. work or acme/prod)")
 	newCmd.AddCommand(newTextCmd)
 	newCmd.AddCommand(newKVCmd)
 	newCmd.AddCommand(newCCCmd)
 	newCmd.AddCommand(newFileCmd)
+	newCmd.AddCommand(newCertCmd)
+	newCmd.AddCommand(newBankCmd)
+	newCmd.AddCommand(newIdentityCmd)
+	newCmd.AddCommand(newEnvCmd)
+	newEnvCmd.Flags().StringVar(&envFrom, "from", "", "Import variables from a dotenv file")
+	dumpCmd.Flags().StringVar(&dumpAttachment, "attachment", "", "Export the named attachment of the secret instead of its value")
+	describeCmd.Flags().BoolVar(&reveal, "reveal", false, "Show masked fields such as account numbers in full")
+	rootCmd.AddCommand(certsCmd)
+	certsCmd.AddCommand(certsExpiringCmd)
+	certsExpiringCmd.Flags().StringVar(&certsWithin, "within", "30d", "Report certificates expiring within this period (e.g. 30d, 12h)")
+	rootCmd.AddCommand(orgCmd)
+	orgCmd.AddCommand(orgCreateCmd)
+	orgCmd.AddCommand(orgListCmd)
+	orgCmd.AddCommand(orgMembersCmd)
+	orgCmd.AddCommand(orgAddCmd)
+	orgCmd.AddCommand(orgRemoveCmd)
+	orgAddCmd.Flags().StringVar(&memberRole, "role", org.RoleViewer, "Role of the member: owner, admin, editor or viewer")
+	rootCmd.AddCommand(vaultCmd)
+	vaultCmd.AddCommand(vaultCreateCmd)
+	vaultCmd.AddCommand(vaultListCmd)
+	vaultCmd.AddCommand(vaultDeleteCmd)
+	vaultCmd.AddCommand(vaultUseCmd)
+	vaultDeleteCmd.Flags().BoolVarP(&deleteYes, "yes", "y", false, "Do not ask for confirmation")
+	newCmd.PersistentFlags().StringVar(&vaultRef, "vault", "", "Store the secret in this vault instead of the current one (e.g. work or acme/prod)")
+	listCmd.Flags().StringVar(&vaultRef, "vault", "", "List the secrets of this vault instead of the current one (e.g. work or acme/prod)")
+	searchCmd.Flags().StringVar(&vaultRef, "vault", "", "Search the secrets of this vault instead of the current one (e.g. work or acme/prod)")
+	lsCmd.Flags().StringVar(&vaultRef, "vault", "", "Browse this vault instead of the current one (e.g. work or acme/prod)")
+	mvCmd.Flags().StringVar(&moveVault, "vault", "", "Move the secret to this vault (e.g. work, acme/prod or default)")
+	rootCmd.AddCommand(auditCmd)
+	auditCmd.Flags().StringVar(&auditSince, "since", "", "Only show events of this period (e.g. 24h, 7d)")
+	auditCmd.Flags().StringVar(&auditSecret, "secret", "", "Only show events of this secret (ID or path)")
+	auditCmd.Flags().IntVar(&auditLimit, "limit", 100, fmt.Sprintf("Maximum number of events to show (at most %d)", audit.MaxQueryLimit))
+	rootCmd.AddCommand(emergencyCmd)
+	emergencyCmd.AddCommand(emergencyAddCmd)
+	emergencyCmd.AddCommand(emergencyContactsCmd)
+	emergencyCmd.AddCommand(emergencyRemoveCmd)
+	emergencyCmd.AddCommand(emergencyApproveCmd)
+	emergencyCmd.AddCommand(emergencyRejectCmd)
+	emergencyCmd.AddCommand(emergencyRevokeCmd)
+	emergencyCmd.AddCommand(emergencyOwnersCmd)
+	emergencyCmd.AddCommand(emergencyRequestCmd)
+	emergencyCmd.AddCommand(emergencyCancelCmd)
+	emergencyCmd.AddCommand(emergencySecretsCmd)
+	emergencyCmd.AddCommand(emergencyEventsCmd)
+	emergencyAddCmd.Flags().StringVar(&emergencyWait, "wait", "7d", "Grant requests of the contact after this period unless rejected (e.g. 48h, 7d)")
+	emergencyEventsCmd.Flags().BoolVar(&emergencyOwner, "owner", false, "The user designated you as an emergency contact, instead of being your contact")
+	rootCmd.AddCommand(webhookCmd)
+	webhookCmd.AddCommand(webhookAddCmd)
+	webhookCmd.AddCommand(webhookListCmd)
+	webhookCmd.AddCommand(webhookRemoveCmd)
+	webhookCmd.AddCommand(webhookDeliveriesCmd)
+	webhookAddCmd.Flags().StringSliceVar(&webhookEvents, "events", nil, fmt.Sprintf("Post only these events (%s), all by default", strings.Join(wh.AllEvents, ", ")))
+	webhookAddCmd.Flags().StringVar(&webhookOrg, "org", "", "Post the events of the vaults of this organization instead of your own")
+	webhookAddCmd.Flags().StringVar(&webhookSecret, "secret", "", fmt.Sprintf("Sign deliveries with this secret of at least %d characters instead of a generated one", wh.MinSecretLength))
+	webhookListCmd.Flags().StringVar(&webhookOrg, "org", "", "List the webhooks of this organization instead of your own")
+	rootCmd.AddCommand(changesCmd)
+	changesCmd.Flags().Uint64Var(&changesSince, "since", 0, "Only show changes after this revision, as printed by the last run")
+	rootCmd.AddCommand(watchCmd)
+	watchCmd.Flags().StringVar(&watchExec, "exec", "", "Run this shell command on each change (e.g. \"make config\")")
 
 	return rootCmd
 }
@@ -108,28 +288,166 @@ var setupCmd = &cobra.Command{
 
 var deleteCmd = &cobra.Command{
 	Use:   "delete",
-	Short: "Delete a secret.",
-	Long:  "Remove a secret stored in passKeeper by its unique identifier. The secret will be permanently deleted from the system.",
-	Run: func(cmd *cobra.Command, args []string) {
+	Short: "Move secrets to the trash.",
+	Long:  "Move secrets stored in passKeeper to the trash by their unique identifiers or paths. You are asked for confirmation unless --yes is given. Trashed secrets can be restored with passKeeper trash restore until they are purged.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 {
+			return fmt.Errorf("wrong number of arguments. expected at least one id")
+		}
 		app := app.GetApplication()
 
+		ids := make([]string, 0, len(args))
 		for _, v := range args {
-			app.DeleteSecret(v)
+			id, err := resolveID(app, v)
+			if err != nil {
+				return err
+			}
+			ids = append(ids, id)
+		}
+		if !deleteYes && !confirm(fmt.Sprintf("Move %d secret(s) (%s) to the trash?", len(ids), strings.Join(args, ", "))) {
+			fmt.Println("Nothing deleted.")
+			return nil
+		}
+
+		for _, id := range ids {
+			if err := app.DeleteSecret(id); err != nil {
+				return fmt.Errorf("cannot delete secret %s: %s", id, err)
+			}
+		}
+		return nil
+
+	},
+}
+
+var trashListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the secrets in the trash.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		appl := app.GetApplication()
+		vault, err := currentVault(appl)
+		if err != nil {
+			return err
+		}
+		secrets, err := appl.GetTrash(vault)
+		if err != nil {
+			return fmt.Errorf("cannot get trash: %s", err)
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "SecretID\tPath\tType\tName\tDeleted")
+		for _, s := range secrets {
+			deleted := ""
+			if s.DeletedAt != nil {
+				deleted = s.DeletedAt.Local().Format("2006-01-02 15:04")
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n", s.ID, s.Path, s.SecretType, s.TypedMeta().Label(), deleted)
+		}
+		return w.Flush()
+	},
+}
+
+var trashRestoreCmd = &cobra.Command{
+	Use:   "restore",
+	Short: "Restore secrets from the trash.",
+	Long:  "Restore secrets from the trash by their unique identifiers. A secret whose path has been taken by another secret in the meantime has to be moved first.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 {
+			return fmt.Errorf("wrong number of arguments. expected at least one id")
+		}
+		app := app.GetApplication()
+		for _, id := range args {
+			if err := app.RestoreSecret(id); err != nil {
+				return fmt.Errorf("cannot restore secret %s: %s", id, err)
+			}
+		}
+		return nil
+	},
+}
+
+var trashPurgeCmd = &cobra.Command{
+	Use:   "purge",
+	Short: "Permanently delete secrets from the trash.",
+	Long:  "Permanently delete secrets in the trash by their unique identifiers, or the whole trash with --all. You are asked for confirmation unless --yes is given.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if purgeAll == (len(args) > 0) {
+			return fmt.Errorf("expected either secret ids or --all")
+		}
+		app := app.GetApplication()
 
+		if purgeAll {
+			if !deleteYes && !confirm("Permanently delete all secrets in the trash?") {
+				fmt.Println("Nothing purged.")
+				return nil
+			}
+			vault, err := currentVault(app)
+			if err != nil {
+				return err
+			}
+			n, err := app.EmptyTrash(vault)
+			if err != nil {
+				return fmt.Errorf("cannot empty trash: %s", err)
+			}
+			fmt.Printf("Purged %d secret(s).\n", n)
+			return nil
 		}
 
+		if !deleteYes && !confirm(fmt.Sprintf("Permanently delete %d secret(s) (%s)?", len(args), strings.Join(args, ", "))) {
+			fmt.Println("Nothing purged.")
+			return nil
+		}
+		for _, id := range args {
+			if err := app.PurgeSecret(id); err != nil {
+				return fmt.Errorf("cannot purge secret %s: %s", id, err)
+			}
+		}
+		return nil
 	},
 }
+
+// confirm asks the question on the terminal.
+func confirm(question string) bool {
+	return app.Confirm(os.Stdin, os.Stdout, question)
+}
+
 var dumpCmd = &cobra.Command{
 	Use:   "dump",
 	Short: "Export binary secret data.",
-	Long:  "Extract and export the binary data of a secret by its unique identifier on disk. This is useful for backing up or transferring secret information.",
+	Long:  "Extract and export the binary data of a secret by its unique identifier or path on disk. For identity documents all attached scans are exported, environment bundles are written as dotenv files. This is useful for backing up or transferring secret information.",
 	Run: func(cmd *cobra.Command, args []string) {
 		app := app.GetApplication()
 
-		for _, v := range args {
-			app.DumpSecret(v)
+		if dumpAttachment != "" {
+			if len(args) != 1 {
+				log.Printf("%s", "Wrong number of arguments. Expected only one id.")
+				return
+			}
+			id, err := resolveID(app, args[0])
+			if err != nil {
+				log.Printf("%s", err)
+				return
+			}
+			path, err := app.DumpAttachment(id, dumpAttachment)
+			if err != nil {
+				log.Printf("cannot dump attachment %s: %s", dumpAttachment, err)
+				return
+			}
+			fmt.Println(path)
+			return
+		}
 
+		for _, v := range args {
+			id, err := resolveID(app, v)
+			if err != nil {
+				log.Printf("%s", err)
+				continue
+			}
+			paths, err := app.DumpSecret(id)
+			if err != nil {
+				log.Printf("cannot dump secret %s: %s", v, err)
+			}
+			for _, path := range paths {
+				fmt.Println(path)
+			}
 		}
 
 	},
@@ -138,58 +456,92 @@ var dumpCmd = &cobra.Command{
 var editCmd = &cobra.Command{
 	Use:   "edit",
 	Short: "Modify a secret.",
-	Long:  "Edit the contents of a secret stored in passKeeper by its unique identifier. Depending on the type of the secret (key-value pair, text, or credit card), the corresponding user interface will be invoked for modification.",
+	Long:  "Edit the contents of a secret stored in passKeeper by its unique identifier or path. Depending on the type of the secret (key-value pair, text, or credit card), the corresponding user interface will be invoked for modification. With --expires only the expiry date is changed, which also brings an expired secret back from the archive.",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		app := app.GetApplication()
+		appl := app.GetApplication()
 
 		if len(args) > 1 || len(args) == 0 {
 			return fmt.Errorf("wrong number of arguments. expected only one id")
 		}
 
-		secret, err := app.GetSecret(args[0])
+		id, err := resolveID(appl, args[0])
 		if err != nil {
-			return fmt.Errorf("cannot get secret")
+			return err
 		}
-
-		decodedSecret, err := sec.GetDecodedSecrets([]sec.Secret{*secret})
-		if err != nil {
-			return fmt.Errorf("cannot decode secret")
+		if cmd.Flags().Changed("expires") {
+			expiresAt, err := app.ParseExpiry(expires, time.Now())
+			if err != nil {
+				return err
+			}
+			return appl.SetExpiry(id, expiresAt)
 		}
+		return editSecret(appl, id)
+	},
+}
 
-		switch v := decodedSecret[0].Value.(type) {
-		case *sec.KeyValue:
-			if err := kv.EditKVTui(*v, secret.Metadata, secret.ID); err != nil {
-				return fmt.Errorf("could not start passKeeper: %s", err)
-			}
-		case *sec.Text:
-			if err := txt.EditTextTui(*v, secret.Metadata, secret.ID); err != nil {
-				return fmt.Errorf("could not start passKeeper: %s", err)
-			}
-		case *sec.CreditCard:
-			if err := cc.EditCCTui(*v, secret.Metadata, secret.ID); err != nil {
-				return fmt.Errorf("could not start passKeeper: %s", err)
-			}
-		case *sec.ByteSlice:
-		default:
-			return nil
+// editSecret opens the edit form matching the type of the secret. File
+// secrets and certificates have no edit form and are left unchanged.
+func editSecret(appl *app.Application, id string) error {
+	secret, err := appl.GetSecret(id)
+	if err != nil {
+		return fmt.Errorf("cannot get secret")
+	}
+
+	decodedSecret, err := sec.GetDecodedSecrets([]sec.Secret{*secret})
+	if err != nil {
+		return fmt.Errorf("cannot decode secret")
+	}
+
+	switch v := decodedSecret[0].Value.(type) {
+	case *sec.KeyValue:
+		if err := kv.EditKVTui(*v, secret.Metadata, secret.ID); err != nil {
+			return fmt.Errorf("could not start passKeeper: %s", err)
+		}
+	case *sec.Text:
+		if err := txt.EditTextTui(*v, secret.Metadata, secret.ID); err != nil {
+			return fmt.Errorf("could not start passKeeper: %s", err)
+		}
+	case *sec.CreditCard:
+		if err := cc.EditCCTui(*v, secret.Metadata, secret.ID); err != nil {
+			return fmt.Errorf("could not start passKeeper: %s", err)
+		}
+	case *sec.BankAccount:
+		if err := bank.EditBankTui(*v, secret.Metadata, secret.ID); err != nil {
+			return fmt.Errorf("could not start passKeeper: %s", err)
+		}
+	case *sec.Identity:
+		if err := identity.EditIdentityTui(*v, secret.Metadata, secret.ID); err != nil {
+			return fmt.Errorf("could not start passKeeper: %s", err)
+		}
+	case *sec.EnvBundle:
+		if err := env.EditEnvTui(*v, secret.Metadata, secret.ID); err != nil {
+			return fmt.Errorf("could not start passKeeper: %s", err)
 		}
+	case *sec.ByteSlice:
+	default:
 		return nil
-	},
+	}
+	return nil
 }
 
 var describeCmd = &cobra.Command{
 	Use:   "describe",
 	Short: "Display a secret's details.",
-	Long:  "Provide comprehensive details of a secret stored in passKeeper by its unique identifier. This includes the metadata, value, and other associated information.",
+	Long:  "Provide comprehensive details of a secret stored in passKeeper by its unique identifier or path. This includes the metadata, value, and other associated information.",
 	Run: func(cmd *cobra.Command, args []string) {
-		app := app.GetApplication()
+		appl := app.GetApplication()
 
 		if len(args) > 1 || len(args) == 0 {
 			log.Printf("%s", "Wrong number of arguments. Expected only one id.")
 			return
 		} else {
 
-			secret, err := app.GetSecret(args[0])
+			id, err := resolveID(appl, args[0])
+			if err != nil {
+				log.Printf("%s", err)
+				return
+			}
+			secret, err := appl.GetSecret(id)
 			if err != nil {
 				log.Printf("%s", "Cannot get secret")
 				return
@@ -200,76 +552,1313 @@ var describeCmd = &cobra.Command{
 				log.Printf("%s", "Cannot decode secret")
 				return
 			}
-			fmt.Printf("Secret Id: %d \nSecret metadata: %s\n", secret.ID, secret.Metadata)
-			fmt.Printf("Secret value:\n%s", decodedSecret[0].ValueToString())
+			meta := secret.TypedMeta()
+			fmt.Printf("Secret Id: %d \nSecret name: %s\n", secret.ID, meta.Label())
+			if meta.Description != "" {
+				fmt.Printf("Secret description: %s\n", meta.Description)
+			}
+			if f := meta.File; f.Filename != "" {
+				fmt.Printf("File: %s (%s, %d bytes, mode %s, sha256 %s)\n", f.Filename, f.MimeType, f.Size, f.FileMode(), f.SHA256)
+			}
+			if secret.Path != "" {
+				fmt.Printf("Secret path: %s\n", secret.Path)
+			}
+			if secret.ExpiresAt != nil {
+				fmt.Printf("Secret expires: %s\n", secret.ExpiresAt.Local().Format("2006-01-02 15:04"))
+			}
+			fmt.Printf("Created: %s\n", app.FormatTime(&secret.CreatedAt))
+			fmt.Printf("Updated: %s\n", app.FormatTime(&secret.UpdatedAt))
+			fmt.Printf("Last accessed: %s (%d accesses)\n", app.FormatTime(secret.LastAccessedAt), secret.AccessCount)
+			if len(secret.Tags) > 0 {
+				fmt.Printf("Secret tags: %s\n", strings.Join(sec.TagNames(secret.Tags), ", "))
+			}
+			if reveal {
+				fmt.Printf("Secret value:\n%s", decodedSecret[0].RevealedValueToString())
+			} else {
+				fmt.Printf("Secret value:\n%s", decodedSecret[0].ValueToString())
+			}
+
+			attachments, err := appl.ListAttachments(id)
+			if err != nil {
+				log.Printf("%s", "Cannot get attachments")
+				return
+			}
+			if len(attachments) > 0 {
+				fmt.Printf("\nAttachments:\n")
+				for _, a := range attachments {
+					fmt.Printf(" %s (%s, %d bytes, sha256 %s)\n", a.Filename, a.MimeType, a.Size, a.Checksum)
+				}
+			}
 
 		}
 
 	},
 }
 
-var listCmd = &cobra.Command{
-	Use:   "list",
-	Short: "List available secrets.",
-	Long:  "Display a list of all secrets currently stored in passKeeper. This includes the secret's identifier, value, and any associated metadata.",
-	Run: func(cmd *cobra.Command, args []string) {
+var attachCmd = &cobra.Command{
+	Use:   "attach",
+	Short: "Attach files to a secret.",
+	Long:  "Attach one or more files to a secret of any type by its unique identifier or path. The filename, MIME type, size and SHA-256 checksum of every file are stored with it.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) < 2 {
+			return fmt.Errorf("wrong number of arguments. expected secret id and at least one file")
+		}
+		app := app.GetApplication()
+		id, err := resolveID(app, args[0])
+		if err != nil {
+			return err
+		}
 
-		appl := app.GetApplication()
-		list := appl.ListSecrets()
-		decodedSecrets, err := sec.GetDecodedSecrets(*list)
+		for _, path := range args[1:] {
+			attachment, err := app.AttachFile(id, path)
+			if err != nil {
+				return fmt.Errorf("cannot attach %s: %s", path, err)
+			}
+			fmt.Printf("Attached %s (%d bytes)\n", attachment.Filename, attachment.Size)
+		}
+		return nil
+	},
+}
+
+var tagCmd = &cobra.Command{
+	Use:   "tag",
+	Short: "Add or remove tags of a secret.",
+	Long:  "Change the tags of a secret by its unique identifier or path. Prefix a tag with + (or nothing) to add it and with - to remove it, e.g. passKeeper tag 12 +prod -staging.",
+	// Tags to remove start with a dash and must not be parsed as flags.
+	DisableFlagParsing: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) < 2 {
+			return fmt.Errorf("wrong number of arguments. expected secret id and at least one tag")
+		}
+		app := app.GetApplication()
+		id, err := resolveID(app, args[0])
 		if err != nil {
-			log.Printf("%s", err.Error())
+			return err
 		}
+		return app.UpdateTags(id, args[1:])
+	},
+}
 
-		app.List(*appl, decodedSecrets)
+var detachCmd = &cobra.Command{
+	Use:   "detach",
+	Short: "Remove an attachment from a secret.",
+	Long:  "Remove the named attachments from a secret by its unique identifier or path.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) < 2 {
+			return fmt.Errorf("wrong number of arguments. expected secret id and at least one attachment name")
+		}
+		app := app.GetApplication()
+		id, err := resolveID(app, args[0])
+		if err != nil {
+			return err
+		}
 
+		for _, name := range args[1:] {
+			if err := app.DetachFile(id, name); err != nil {
+				return fmt.Errorf("cannot remove attachment %s: %s", name, err)
+			}
+		}
+		return nil
 	},
 }
 
-var newTextCmd = &cobra.Command{
-	Use:   "txt",
-	Short: "Create a new text secret.",
-	Long:  "Generate a new secret of the 'text' type. The secret contain plain text data.",
+var mvCmd = &cobra.Command{
+	Use:   "mv",
+	Short: "Rename a secret.",
+	Long:  "Move a secret, given by its unique identifier or current path, to a new path, e.g. passKeeper mv prod/db staging/db. With --vault the secret is moved to another vault, keeping its path unless a new one is given, e.g. passKeeper mv prod/db --vault work. Only admins may move a secret out of its organization.",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		if err := txt.NewTextTui(); err != nil {
-			return fmt.Errorf("could not start passKeeper: %s", err)
+		if len(args) != 2 && (moveVault == "" || len(args) != 1) {
+			return fmt.Errorf("wrong number of arguments. expected secret id or path and the new path")
+		}
+		app := app.GetApplication()
+		id, err := resolveID(app, args[0])
+		if err != nil {
+			return err
+		}
+		if len(args) == 2 {
+			if err := app.MoveSecret(id, args[1]); err != nil {
+				return err
+			}
+		}
+		if moveVault != "" {
+			return app.MoveToVault(id, moveVault)
 		}
 		return nil
 	},
 }
 
-var newFileCmd = &cobra.Command{
-	Use:   "file",
-	Short: "Create a new file secret.",
-	Long:  "Generate a new secret of the 'file' type. The secret can contain binary data.",
+var cpCmd = &cobra.Command{
+	Use:   "cp",
+	Short: "Copy a secret.",
+	Long:  "Copy a secret, given by its unique identifier or path, with its value, metadata, tags and attachments to a new path, e.g. passKeeper cp prod/db staging/db. With --edit the copy is opened in the edit form for adjustment.",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		if err := f.FileTui(); err != nil {
-			return fmt.Errorf("could not start passKeeper: %s", err)
+		if len(args) != 2 {
+			return fmt.Errorf("wrong number of arguments. expected secret id or path and the new path")
+		}
+		appl := app.GetApplication()
+		id, err := resolveID(appl, args[0])
+		if err != nil {
+			return err
+		}
+		copied, err := appl.CopySecret(id, args[1])
+		if err != nil {
+			return fmt.Errorf("cannot copy secret: %s", err)
+		}
+		fmt.Printf("Copied secret %s to %s (secret %d)\n", args[0], copied.Path, copied.ID)
+		if !copyEdit {
+			return nil
+		}
+		return editSecret(appl, strconv.Itoa(int(copied.ID)))
+	},
+}
+
+var shareCmd = &cobra.Command{
+	Use:   "share",
+	Short: "Share a secret with another user.",
+	Long:  "Grant another passKeeper user access to a secret, given by its unique identifier or path, e.g. passKeeper share prod/db --with alice --read-only. Read-only users can read the secret and its attachments, otherwise they can also edit it. Sharing again changes the access. Without --with the users the secret is shared with are listed.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 1 {
+			return fmt.Errorf("wrong number of arguments. expected secret id or path")
+		}
+		appl := app.GetApplication()
+		id, err := resolveID(appl, args[0])
+		if err != nil {
+			return err
+		}
+		if shareWith != "" {
+			share, err := appl.ShareSecret(id, shareWith, shareReadOnly)
+			if err != nil {
+				return fmt.Errorf("cannot share secret: %s", err)
+			}
+			fmt.Printf("Shared secret %s with %s (%s)\n", args[0], share.Login, shareAccess(share.ReadOnly))
+			return nil
+		}
+
+		shares, err := appl.Shares(id)
+		if err != nil {
+			return fmt.Errorf("cannot get shares: %s", err)
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "User\tAccess\tShared")
+		for _, s := range shares {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", s.Login, shareAccess(s.ReadOnly), app.FormatTime(&s.CreatedAt))
+		}
+		return w.Flush()
+	},
+}
+
+var unshareCmd = &cobra.Command{
+	Use:   "unshare",
+	Short: "Stop sharing a secret with a user.",
+	Long:  "Revoke the access of a user to a secret given by its unique identifier or path, e.g. passKeeper unshare prod/db alice.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 2 {
+			return fmt.Errorf("wrong number of arguments. expected secret id or path and the user")
+		}
+		appl := app.GetApplication()
+		id, err := resolveID(appl, args[0])
+		if err != nil {
+			return err
+		}
+		return appl.RevokeShare(id, args[1])
+	},
+}
+
+var sharedCmd = &cobra.Command{
+	Use:   "shared",
+	Short: "List secrets shared with me.",
+	Long:  "List the secrets other users shared with you. Use their ID with describe, dump or edit.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		appl := app.GetApplication()
+		shared, err := appl.SharedWithMe()
+		if err != nil {
+			return fmt.Errorf("cannot get shared secrets: %s", err)
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "SecretID\tOwner\tPath\tType\tName\tAccess")
+		for _, s := range shared {
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\n", s.Secret.ID, s.Owner, s.Secret.Path, s.Secret.SecretType,
+				s.Secret.TypedMeta().Label(), shareAccess(s.ReadOnly))
+		}
+		return w.Flush()
+	},
+}
+
+var shareOnceCmd = &cobra.Command{
+	Use:   "share-once",
+	Short: "Create a one-time link to a secret.",
+	Long:  "Encrypt a secret, given by its unique identifier or path, and print a link for someone without a passKeeper account, e.g. passKeeper share-once prod/vendor-api --views 1 --expires 24h. The key is part of the link after # and never sent to the server. The share is deleted once it was opened --views times or when it expires. Attachments are not included.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 1 {
+			return fmt.Errorf("wrong number of arguments. expected secret id or path")
+		}
+		within, err := app.ParseDuration(onceExpires)
+		if err != nil || within <= 0 {
+			return fmt.Errorf("invalid expiry period %q", onceExpires)
 		}
+		appl := app.GetApplication()
+		id, err := resolveID(appl, args[0])
+		if err != nil {
+			return err
+		}
+		link, err := appl.ShareOnce(id, onceViews, time.Now().Add(within))
+		if err != nil {
+			return fmt.Errorf("cannot create one-time share: %s", err)
+		}
+		fmt.Println(link)
 		return nil
 	},
 }
 
-var newKVCmd = &cobra.Command{
-	Use:   "kv",
-	Short: "Create a new key-value secret.",
-	Long:  "Generate a new secret of the 'key-value' type. The secret can contain a key-value pair.",
+var openShareCmd = &cobra.Command{
+	Use:   "open-share",
+	Short: "Open a one-time link.",
+	Long:  "Open a link created with passKeeper share-once and print the secret, or save it on disk for files. Opening uses up one view of the share; no account is needed.",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		if err := kv.NewKVTui(); err != nil {
-			return fmt.Errorf("could not start passKeeper: %s", err)
+		if len(args) != 1 {
+			return fmt.Errorf("wrong number of arguments. expected the link")
+		}
+		secret, viewsLeft, err := app.OpenShareLink(args[0])
+		if err != nil {
+			return err
+		}
+		meta := secret.TypedMeta()
+		fmt.Printf("Secret name: %s\n", meta.Label())
+		if meta.Description != "" {
+			fmt.Printf("Secret description: %s\n", meta.Description)
+		}
+		decoded, err := sec.GetDecodedSecrets([]sec.Secret{*secret})
+		if err != nil {
+			return fmt.Errorf("cannot decode secret: %s", err)
+		}
+		if data, ok := decoded[0].Value.(*sec.ByteSlice); ok {
+			path, err := app.SaveBinarySecretOnDisk(*data, meta, 0)
+			if err != nil {
+				return fmt.Errorf("cannot save data on disk: %s", err)
+			}
+			fmt.Printf("Saved file: %s\n", path)
+		} else {
+			fmt.Printf("Secret value:\n%s\n", decoded[0].RevealedValueToString())
+		}
+		if viewsLeft == 0 {
+			fmt.Println("The share has been deleted.")
+		} else {
+			fmt.Printf("The share can be opened %d more time(s).\n", viewsLeft)
 		}
 		return nil
 	},
 }
 
-var newCCCmd = &cobra.Command{
-	Use:   "cc",
-	Short: "Create a new credit card secret.",
-	Long:  "Generate a new secret of the 'credit card' type. The secret can contain credit card information.",
+func shareAccess(readOnly bool) string {
+	if readOnly {
+		return "read-only"
+	}
+	return "read-write"
+}
+
+var lsCmd = &cobra.Command{
+	Use:   "ls",
+	Short: "Browse secrets by path.",
+	Long:  "List the secrets and directories directly below a path prefix, e.g. passKeeper ls prod/. Without a prefix the top level is listed.",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		if err := cc.NewCCTui(); err != nil {
-			return fmt.Errorf("could not start passKeeper: %s", err)
+		if len(args) > 1 {
+			return fmt.Errorf("wrong number of arguments. expected at most one path prefix")
+		}
+		prefix := ""
+		if len(args) == 1 {
+			prefix = args[0]
+		}
+		appl := app.GetApplication()
+		vault, err := currentVault(appl)
+		if err != nil {
+			return err
+		}
+		secrets, err := appl.ListDirectory(prefix, vault)
+		if err != nil {
+			return err
+		}
+		paths := make([]string, len(secrets))
+		for i, s := range secrets {
+			paths[i] = s.Path
+		}
+		for _, entry := range app.DirEntries(prefix, paths) {
+			fmt.Println(entry)
+		}
+		return nil
+	},
+}
+
+var searchCmd = &cobra.Command{
+	Use:   "search",
+	Short: "Find secrets by metadata, path, tag or type.",
+	Long:  "Search secrets on the server without downloading their values. Every word must match the metadata, path, a tag or the type of a secret, case-insensitively. A word ending with * only matches at the start, type:<type> restricts the secret type, e.g. passKeeper search db* type:KeyValue.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 {
+			return fmt.Errorf("wrong number of arguments. expected a search query")
+		}
+		appl := app.GetApplication()
+		vault, err := currentVault(appl)
+		if err != nil {
+			return err
+		}
+		secrets, err := appl.SearchSecrets(strings.Join(args, " "), vault, searchLimit)
+		if err != nil {
+			return fmt.Errorf("cannot search secrets: %s", err)
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "SecretID\tPath\tType\tName\tTags")
+		for _, s := range secrets {
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n", s.ID, s.Path, s.SecretType, s.TypedMeta().Label(), strings.Join(sec.TagNames(s.Tags), ","))
+		}
+		return w.Flush()
+	},
+}
+
+var listCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List available secrets.",
+	Long:  "Display a list of all secrets currently stored in passKeeper. This includes the secret's identifier, value, tags and any associated metadata. With --tag only secrets carrying any of the given tags (or all of them with --all-tags) are listed, --type restricts the secret types. Secrets are loaded page by page as you scroll.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if !sec.ValidSort(listSort) {
+			return fmt.Errorf("sort must be one of name, created, updated or type")
+		}
+		if listPageSize < 1 || listPageSize > 200 {
+			return fmt.Errorf("page size must be between 1 and 200")
+		}
+
+		appl := app.GetApplication()
+		vault, err := currentVault(appl)
+		if err != nil {
+			return err
+		}
+		pager := appl.NewSecretPager(client.ListOptions{
+			Vault:        vault,
+			Tags:         listTags,
+			MatchAllTags: listAllTags,
+			Types:        listTypes,
+			Sort:         listSort,
+			Desc:         listDesc,
+			Limit:        listPageSize,
+		})
+
+		return app.ListPaged(pager)
+
+	},
+}
+
+var newTextCmd = &cobra.Command{
+	Use:   "txt",
+	Short: "Create a new text secret.",
+	Long:  "Generate a new secret of the 'text' type. The secret contain plain text data.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		target, err := newSecret()
+		if err != nil {
+			return err
+		}
+		if err := txt.NewTextTui(target); err != nil {
+			return fmt.Errorf("could not start passKeeper: %s", err)
+		}
+		return nil
+	},
+}
+
+var newFileCmd = &cobra.Command{
+	Use:   "file",
+	Short: "Create a new file secret.",
+	Long:  "Generate a new secret of the 'file' type. The secret can contain binary data.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		target, err := newSecret()
+		if err != nil {
+			return err
+		}
+		if err := f.FileTui(target); err != nil {
+			return fmt.Errorf("could not start passKeeper: %s", err)
+		}
+		return nil
+	},
+}
+
+var newKVCmd = &cobra.Command{
+	Use:   "kv",
+	Short: "Create a new key-value secret.",
+	Long:  "Generate a new secret of the 'key-value' type. The secret can contain a key-value pair.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		target, err := newSecret()
+		if err != nil {
+			return err
+		}
+		if err := kv.NewKVTui(target); err != nil {
+			return fmt.Errorf("could not start passKeeper: %s", err)
+		}
+		return nil
+	},
+}
+
+var newCCCmd = &cobra.Command{
+	Use:   "cc",
+	Short: "Create a new credit card secret.",
+	Long:  "Generate a new secret of the 'credit card' type. The secret can contain credit card information.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		target, err := newSecret()
+		if err != nil {
+			return err
+		}
+		if err := cc.NewCCTui(target); err != nil {
+			return fmt.Errorf("could not start passKeeper: %s", err)
+		}
+		return nil
+	},
+}
+
+var newCertCmd = &cobra.Command{
+	Use:   "cert",
+	Short: "Create a new certificate secret.",
+	Long:  "Generate a new secret of the 'certificate' type. The secret contains a PEM certificate chain and, optionally, the private key of the leaf certificate.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		target, err := newSecret()
+		if err != nil {
+			return err
+		}
+		if err := cert.CertTui(target); err != nil {
+			return fmt.Errorf("could not start passKeeper: %s", err)
+		}
+		return nil
+	},
+}
+
+var newBankCmd = &cobra.Command{
+	Use:   "bank",
+	Short: "Create a new bank account secret.",
+	Long:  "Generate a new secret of the 'bank account' type. The secret contains the account holder, bank name, IBAN, BIC/SWIFT, account and routing numbers.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		target, err := newSecret()
+		if err != nil {
+			return err
+		}
+		if err := bank.NewBankTui(target); err != nil {
+			return fmt.Errorf("could not start passKeeper: %s", err)
+		}
+		return nil
+	},
+}
+
+var newIdentityCmd = &cobra.Command{
+	Use:   "identity",
+	Short: "Create a new identity document secret.",
+	Long:  "Generate a new secret of the 'identity' type. The secret contains passport or ID card data, the scanned images of the document are attached to it.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		target, err := newSecret()
+		if err != nil {
+			return err
+		}
+		if err := identity.NewIdentityTui(target); err != nil {
+			return fmt.Errorf("could not start passKeeper: %s", err)
+		}
+		return nil
+	},
+}
+
+var newEnvCmd = &cobra.Command{
+	Use:   "env",
+	Short: "Create a new environment bundle secret.",
+	Long:  "Generate a new secret of the 'environment bundle' type. The secret holds an ordered set of environment variables, each of which can be marked as hidden. Variables can be imported from a dotenv file.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		bundle := &sec.EnvBundle{}
+		if envFrom != "" {
+			data, err := os.ReadFile(envFrom)
+			if err != nil {
+				return fmt.Errorf("cannot read %s: %s", envFrom, err)
+			}
+			bundle, err = sec.ParseDotenv(string(data))
+			if err != nil {
+				return fmt.Errorf("cannot parse %s: %s", envFrom, err)
+			}
+		}
+		target, err := newSecret()
+		if err != nil {
+			return err
+		}
+		if err := env.NewEnvTui(target, *bundle); err != nil {
+			return fmt.Errorf("could not start passKeeper: %s", err)
+		}
+		return nil
+	},
+}
+
+var applyCmd = &cobra.Command{
+	Use:   "apply",
+	Short: "Apply a file of secret operations.",
+	Long:  "Create, update and delete secrets as listed in a YAML file. All operations are applied in one transaction on the server: if one of them fails, none of them takes effect.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ops, err := app.LoadOperations(applyFile, time.Now())
+		if err != nil {
+			return fmt.Errorf("cannot read %s: %s", applyFile, err)
+		}
+		appl := app.GetApplication()
+		vault, err := currentVault(appl)
+		if err != nil {
+			return err
+		}
+		resp, err := appl.ApplyOperations(ops, vault)
+		if err != nil {
+			return fmt.Errorf("cannot apply operations: %s", err)
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "#\tOp\tSecretID\tStatus\tError")
+		for i, r := range resp.Results {
+			status := "ok"
+			if r.Status != 200 {
+				status = strconv.Itoa(r.Status)
+			}
+			if r.Status == 0 {
+				status = "-"
+			}
+			fmt.Fprintf(w, "%d\t%s\t%d\t%s\t%s\n", i+1, r.Op, r.ID, status, r.Error)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		if !resp.Committed {
+			return fmt.Errorf("%d of %d operations failed, nothing was changed", len(resp.Failed()), len(ops))
+		}
+		return nil
+	},
+}
+
+var staleCmd = &cobra.Command{
+	Use:   "stale",
+	Short: "List secrets which are no longer used.",
+	Long:  "Report secrets whose value has not been read for the given period, least recently used first. Secrets which were never read are reported once they are older than the period.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		unused, err := app.ParseDuration(unusedFor)
+		if err != nil {
+			return err
+		}
+		appl := app.GetApplication()
+		secrets, err := appl.StaleSecrets(unused)
+		if err != nil {
+			return fmt.Errorf("cannot get secrets: %s", err)
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "SecretID\tPath\tType\tName\tLast accessed\tAccesses\tUpdated")
+		for _, s := range secrets {
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%d\t%s\n", s.ID, s.Path, s.SecretType, s.TypedMeta().Label(),
+				app.FormatTime(s.LastAccessedAt), s.AccessCount, app.FormatTime(&s.UpdatedAt))
+		}
+		return w.Flush()
+	},
+}
+
+var expiringCmd = &cobra.Command{
+	Use:   "expiring",
+	Short: "List secrets which expire soon.",
+	Long:  "Report secrets whose expiry date falls within the given period. Secrets which already expired and were moved to the archive are included. The report covers the current vault or the one given with --vault.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		within, err := app.ParseDuration(expiringWithin)
+		if err != nil {
+			return err
+		}
+		appl := app.GetApplication()
+		vault, err := currentVault(appl)
+		if err != nil {
+			return err
+		}
+		secrets, err := appl.ExpiringSecrets(vault, within)
+		if err != nil {
+			return fmt.Errorf("cannot get secrets: %s", err)
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "SecretID\tPath\tName\tExpires\tStatus")
+		for _, s := range secrets {
+			status := fmt.Sprintf("%d days left", int(time.Until(*s.ExpiresAt).Hours()/24))
+			if s.ArchivedAt != nil {
+				status = "archived"
+			} else if s.Expired(time.Now()) {
+				status = "expired"
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n", s.ID, s.Path, s.TypedMeta().Label(), s.ExpiresAt.Local().Format("2006-01-02 15:04"), status)
+		}
+		return w.Flush()
+	},
+}
+
+var certsExpiringCmd = &cobra.Command{
+	Use:   "expiring",
+	Short: "List certificates which expire soon.",
+	Long:  "Report stored certificates whose validity ends within the given period. Already expired certificates are included.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		within, err := app.ParseDuration(certsWithin)
+		if err != nil {
+			return err
+		}
+		appl := app.GetApplication()
+		certs, err := appl.ExpiringCertificates(within)
+		if err != nil {
+			return fmt.Errorf("cannot get certificates: %s", err)
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "SecretID\tSubject\tSANs\tNot after\tDays left")
+		for _, c := range certs {
+			daysLeft := int(time.Until(c.NotAfter).Hours() / 24)
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%d\n", c.SecretID, c.Subject, c.SANs, c.NotAfter.Format("2006-01-02"), daysLeft)
+		}
+		return w.Flush()
+	},
+}
+
+// newSecret returns the options of the new command for the secret it creates.
+func newSecret() (app.NewSecret, error) {
+	target := app.NewSecret{Path: newPath}
+	vault, err := currentVault(app.GetApplication())
+	if err != nil {
+		return target, err
+	}
+	target.Vault = vault
+	if expires == "" {
+		return target, nil
+	}
+	expiresAt, err := app.ParseExpiry(expires, time.Now())
+	if err != nil {
+		return target, err
+	}
+	target.ExpiresAt = expiresAt
+	return target, nil
+}
+
+// currentVault returns the ID of the vault the command works on, the one
+// given with --vault or the current one.
+func currentVault(appl *app.Application) (uint, error) {
+	return appl.CurrentVault(vaultRef)
+}
+
+// resolveID turns the secret reference into its ID, looking paths up in the
+// vault the command works on. IDs are returned without resolving the vault.
+func resolveID(appl *app.Application, ref string) (string, error) {
+	if _, err := strconv.ParseUint(ref, 10, 64); err == nil {
+		return ref, nil
+	}
+	vault, err := currentVault(appl)
+	if err != nil {
+		return "", err
+	}
+	return appl.ResolveID(ref, vault)
+}
+
+var orgCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "Create an organization.",
+	Long:  "Create an organization with you as its owner, e.g. passKeeper org create acme.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 1 {
+			return fmt.Errorf("wrong number of arguments. expected the organization name")
+		}
+		appl := app.GetApplication()
+		o, err := appl.CreateOrganization(args[0])
+		if err != nil {
+			return fmt.Errorf("cannot create organization: %s", err)
+		}
+		fmt.Printf("Created organization %s\n", o.Name)
+		return nil
+	},
+}
+
+var orgListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List your organizations.",
+	Long:  "List the organizations you are a member of with your role.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		appl := app.GetApplication()
+		orgs, err := appl.Organizations()
+		if err != nil {
+			return fmt.Errorf("cannot get organizations: %s", err)
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "Organization\tRole\tCreated")
+		for _, o := range orgs {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", o.Name, o.Role, app.FormatTime(&o.CreatedAt))
+		}
+		return w.Flush()
+	},
+}
+
+var orgMembersCmd = &cobra.Command{
+	Use:   "members",
+	Short: "List the members of an organization.",
+	Long:  "List the members of an organization with their roles, e.g. passKeeper org members acme.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 1 {
+			return fmt.Errorf("wrong number of arguments. expected the organization name")
+		}
+		appl := app.GetApplication()
+		members, err := appl.Members(args[0])
+		if err != nil {
+			return fmt.Errorf("cannot get members: %s", err)
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "User\tRole\tJoined")
+		for _, m := range members {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", m.Login, m.Role, app.FormatTime(&m.CreatedAt))
+		}
+		return w.Flush()
+	},
+}
+
+var orgAddCmd = &cobra.Command{
+	Use:   "add",
+	Short: "Add a member to an organization or change its role.",
+	Long:  "Add a user to an organization with a role, e.g. passKeeper org add acme alice --role editor. Adding an existing member changes its role. Admins manage editors and viewers, owners manage every member.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 2 {
+			return fmt.Errorf("wrong number of arguments. expected the organization and the user")
+		}
+		appl := app.GetApplication()
+		member, err := appl.SetMember(args[0], args[1], memberRole)
+		if err != nil {
+			return fmt.Errorf("cannot add member: %s", err)
+		}
+		fmt.Printf("%s is %s of %s\n", member.Login, member.Role, args[0])
+		return nil
+	},
+}
+
+var orgRemoveCmd = &cobra.Command{
+	Use:   "remove",
+	Short: "Remove a member from an organization.",
+	Long:  "Remove a user from an organization, e.g. passKeeper org remove acme alice. The last owner cannot be removed.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 2 {
+			return fmt.Errorf("wrong number of arguments. expected the organization and the user")
+		}
+		appl := app.GetApplication()
+		return appl.RemoveMember(args[0], args[1])
+	},
+}
+
+var vaultCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "Create a vault.",
+	Long:  "Create a personal vault, e.g. passKeeper vault create work, or a vault in an organization you are an admin or owner of, e.g. passKeeper vault create acme/prod.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 1 {
+			return fmt.Errorf("wrong number of arguments. expected the vault name or org/vault")
+		}
+		appl := app.GetApplication()
+		if _, err := appl.CreateVault(args[0]); err != nil {
+			return fmt.Errorf("cannot create vault: %s", err)
+		}
+		fmt.Printf("Created vault %s\n", args[0])
+		return nil
+	},
+}
+
+var vaultListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List vaults.",
+	Long:  "List your personal vaults and the vaults of your organizations, or only the vaults of one organization, e.g. passKeeper vault list acme. The current vault is marked with *.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) > 1 {
+			return fmt.Errorf("wrong number of arguments. expected at most one organization")
+		}
+		appl := app.GetApplication()
+		current, err := app.VaultRef("")
+		if err != nil {
+			return err
+		}
+		var refs []string
+		orgNames := args
+		if len(orgNames) == 0 {
+			refs = append(refs, org.DefaultVault)
+			vaults, err := appl.PersonalVaults()
+			if err != nil {
+				return fmt.Errorf("cannot get vaults: %s", err)
+			}
+			for _, v := range vaults {
+				refs = append(refs, v.Name)
+			}
+			orgs, err := appl.Organizations()
+			if err != nil {
+				return fmt.Errorf("cannot get organizations: %s", err)
+			}
+			for _, o := range orgs {
+				orgNames = append(orgNames, o.Name)
+			}
+		}
+		for _, name := range orgNames {
+			vaults, err := appl.Vaults(name)
+			if err != nil {
+				return fmt.Errorf("cannot get vaults of %s: %s", name, err)
+			}
+			for _, v := range vaults {
+				refs = append(refs, name+"/"+v.Name)
+			}
+		}
+		for _, ref := range refs {
+			marker := " "
+			if ref == current {
+				marker = "*"
+			}
+			fmt.Printf("%s %s\n", marker, ref)
+		}
+		return nil
+	},
+}
+
+var vaultUseCmd = &cobra.Command{
+	Use:   "use",
+	Short: "Select the current vault.",
+	Long:  "Select the vault which list, search, ls and new work on and in which paths are resolved, e.g. passKeeper vault use work. The choice is remembered in config.yaml, passKeeper vault use default returns to your default vault.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 1 {
+			return fmt.Errorf("wrong number of arguments. expected the vault name or org/vault")
+		}
+		appl := app.GetApplication()
+		if err := appl.UseVault(args[0]); err != nil {
+			return fmt.Errorf("cannot use vault: %s", err)
+		}
+		fmt.Printf("Using vault %s\n", args[0])
+		return nil
+	},
+}
+
+var vaultDeleteCmd = &cobra.Command{
+	Use:   "delete",
+	Short: "Delete a vault.",
+	Long:  "Delete a personal vault together with all of its secrets, e.g. passKeeper vault delete work. The vault of an organization is only deleted when it holds no secrets, e.g. passKeeper vault delete acme/old; deleted secrets of the vault must be purged from the trash first.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 1 {
+			return fmt.Errorf("wrong number of arguments. expected the vault name or org/vault")
+		}
+		ref := strings.ToLower(args[0])
+		if !strings.Contains(ref, "/") && !deleteYes &&
+			!confirm(fmt.Sprintf("Permanently delete vault %s with all of its secrets?", ref)) {
+			fmt.Println("Nothing deleted.")
+			return nil
+		}
+		appl := app.GetApplication()
+		n, err := appl.DeleteVault(ref)
+		if err != nil {
+			return fmt.Errorf("cannot delete vault: %s", err)
+		}
+		if !strings.Contains(ref, "/") {
+			fmt.Printf("Deleted vault %s with %d secret(s)\n", ref, n)
+		}
+		if current, err := app.VaultRef(""); err == nil && current == ref {
+			return appl.UseVault(org.DefaultVault)
+		}
+		return nil
+	},
+}
+
+// formatWait formats a waiting period in days if possible.
+func formatWait(hours int) string {
+	if hours%24 == 0 {
+		return fmt.Sprintf("%dd", hours/24)
+	}
+	return fmt.Sprintf("%dh", hours)
+}
+
+var emergencyAddCmd = &cobra.Command{
+	Use:   "add",
+	Short: "Add an emergency contact or change its waiting period.",
+	Long:  "Designate a user as your emergency contact, e.g. passKeeper emergency add alice --wait 3d. Requests of the contact are granted after the waiting period unless you reject them.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 1 {
+			return fmt.Errorf("wrong number of arguments. expected the user")
+		}
+		wait, err := app.ParseDuration(emergencyWait)
+		if err != nil {
+			return fmt.Errorf("invalid waiting period %q", emergencyWait)
+		}
+		appl := app.GetApplication()
+		contact, err := appl.AddEmergencyContact(args[0], wait)
+		if err != nil {
+			return fmt.Errorf("cannot add emergency contact: %s", err)
+		}
+		fmt.Printf("%s is your emergency contact with a waiting period of %s\n", contact.Login, formatWait(contact.WaitHours))
+		return nil
+	},
+}
+
+var emergencyContactsCmd = &cobra.Command{
+	Use:   "contacts",
+	Short: "List your emergency contacts.",
+	Long:  "List your emergency contacts with the state of their access and when a pending request is granted.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		appl := app.GetApplication()
+		contacts, err := appl.EmergencyContacts()
+		if err != nil {
+			return fmt.Errorf("cannot get emergency contacts: %s", err)
+		}
+		return printEmergencyContacts(contacts)
+	},
+}
+
+var emergencyRemoveCmd = &cobra.Command{
+	Use:   "remove",
+	Short: "Remove an emergency contact.",
+	Long:  "Remove an emergency contact, e.g. passKeeper emergency remove alice. Access granted to it ends immediately.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 1 {
+			return fmt.Errorf("wrong number of arguments. expected the user")
+		}
+		appl := app.GetApplication()
+		return appl.RemoveEmergencyContact(args[0])
+	},
+}
+
+var emergencyApproveCmd = &cobra.Command{
+	Use:   "approve",
+	Short: "Grant a pending request for emergency access.",
+	Long:  "Grant the pending request of your emergency contact without waiting, e.g. passKeeper emergency approve alice.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return changeEmergencyAccess(args, em.ActionApprove)
+	},
+}
+
+var emergencyRejectCmd = &cobra.Command{
+	Use:   "reject",
+	Short: "Reject a pending request for emergency access.",
+	Long:  "Reject the pending request of your emergency contact, e.g. passKeeper emergency reject alice. The contact may request access again.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return changeEmergencyAccess(args, em.ActionReject)
+	},
+}
+
+var emergencyRevokeCmd = &cobra.Command{
+	Use:   "revoke",
+	Short: "Revoke granted emergency access.",
+	Long:  "Revoke the access granted to your emergency contact, e.g. passKeeper emergency revoke alice. The contact stays designated and may request access again.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return changeEmergencyAccess(args, em.ActionRevoke)
+	},
+}
+
+var emergencyOwnersCmd = &cobra.Command{
+	Use:   "owners",
+	Short: "List the users who trust you as an emergency contact.",
+	Long:  "List the users who designated you as their emergency contact with the state of your access.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		appl := app.GetApplication()
+		owners, err := appl.EmergencyOwners()
+		if err != nil {
+			return fmt.Errorf("cannot get emergency owners: %s", err)
+		}
+		return printEmergencyContacts(owners)
+	},
+}
+
+var emergencyRequestCmd = &cobra.Command{
+	Use:   "request",
+	Short: "Request emergency access to the secrets of a user.",
+	Long:  "Request access to the secrets of a user who designated you as an emergency contact, e.g. passKeeper emergency request bob. The request is granted after the waiting period unless the user rejects it.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return changeEmergencyAccess(args, em.ActionRequest)
+	},
+}
+
+var emergencyCancelCmd = &cobra.Command{
+	Use:   "cancel",
+	Short: "Cancel your pending request for emergency access.",
+	Long:  "Cancel your pending request for access to the secrets of a user, e.g. passKeeper emergency cancel bob.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return changeEmergencyAccess(args, em.ActionCancel)
+	},
+}
+
+var emergencySecretsCmd = &cobra.Command{
+	Use:   "secrets",
+	Short: "List the secrets you were granted emergency access to.",
+	Long:  "List the secrets of a user who granted you emergency access, e.g. passKeeper emergency secrets bob. Use their ID with describe or dump.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 1 {
+			return fmt.Errorf("wrong number of arguments. expected the user")
+		}
+		appl := app.GetApplication()
+		secrets, err := appl.EmergencySecrets(args[0])
+		if err != nil {
+			return fmt.Errorf("cannot get secrets: %s", err)
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "SecretID\tPath\tType\tName")
+		for _, s := range secrets {
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", s.ID, s.Path, s.SecretType, s.TypedMeta().Label())
+		}
+		return w.Flush()
+	},
+}
+
+var emergencyEventsCmd = &cobra.Command{
+	Use:   "events",
+	Short: "Show the history of an emergency access.",
+	Long:  "Show who requested, approved, rejected or revoked the emergency access of your contact, e.g. passKeeper emergency events alice, or of your access to the secrets of a user with --owner, e.g. passKeeper emergency events bob --owner.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 1 {
+			return fmt.Errorf("wrong number of arguments. expected the user")
+		}
+		appl := app.GetApplication()
+		events, err := appl.EmergencyEvents(args[0], !emergencyOwner)
+		if err != nil {
+			return fmt.Errorf("cannot get events: %s", err)
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "Time\tBy\tAction\tFrom\tTo")
+		for _, e := range events {
+			actor := e.Actor
+			if e.ActorID == 0 {
+				actor = "server"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", app.FormatTime(&e.CreatedAt), actor, e.Action, e.From, e.To)
+		}
+		return w.Flush()
+	},
+}
+
+// changeEmergencyAccess applies the action to the emergency access between
+// the user and the account given as the only argument.
+func changeEmergencyAccess(args []string, action string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("wrong number of arguments. expected the user")
+	}
+	appl := app.GetApplication()
+	contact, err := appl.ChangeEmergencyAccess(args[0], action)
+	if err != nil {
+		return fmt.Errorf("cannot %s emergency access: %s", action, err)
+	}
+	if grantAt := contact.GrantAt(); grantAt != nil {
+		fmt.Printf("Emergency access of %s is %s and will be granted at %s\n", contact.Login, contact.State, app.FormatTime(grantAt))
+		return nil
+	}
+	fmt.Printf("Emergency access of %s is %s\n", contact.Login, contact.State)
+	return nil
+}
+
+func printEmergencyContacts(contacts []em.EmergencyContact) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "User\tState\tWaiting period\tRequested\tGranted at")
+	for _, c := range contacts {
+		grantAt := "-"
+		if at := c.GrantAt(); at != nil {
+			grantAt = app.FormatTime(at)
+		}
+		requested := "-"
+		if c.RequestedAt != nil {
+			requested = app.FormatTime(c.RequestedAt)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", c.Login, c.State, formatWait(c.WaitHours), requested, grantAt)
+	}
+	return w.Flush()
+}
+
+var auditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "Show the audit log.",
+	Long:  "Show who created, read, changed, deleted or shared your secrets and your logins, newest first, e.g. passKeeper audit --since 7d --secret prod/db. Reads of your secrets by other users are included.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var within time.Duration
+		if auditSince != "" {
+			var err error
+			if within, err = app.ParseDuration(auditSince); err != nil {
+				return fmt.Errorf("invalid period %q", auditSince)
+			}
+		}
+		appl := app.GetApplication()
+		var id string
+		if auditSecret != "" {
+			var err error
+			if id, err = resolveID(appl, auditSecret); err != nil {
+				return err
+			}
+		}
+		events, err := appl.AuditEvents(within, id, auditLimit)
+		if err != nil {
+			return fmt.Errorf("cannot get audit events: %s", err)
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "Time\tUser\tAction\tSecretID\tResult\tIP\tClient")
+		for _, e := range events {
+			actor, secretID := e.Actor, "-"
+			if actor == "" {
+				actor = "-"
+			}
+			if e.SecretID != 0 {
+				secretID = strconv.FormatUint(uint64(e.SecretID), 10)
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", app.FormatTime(&e.CreatedAt), actor, e.Action, secretID,
+				e.Result, e.ClientIP, e.UserAgent)
+		}
+		return w.Flush()
+	},
+}
+
+var webhookCmd = &cobra.Command{
+	Use:   "webhook",
+	Short: "Manage webhooks.",
+	Long:  "Post the changes of your secrets, or of the vaults of an organization you administer, to URLs of other systems.",
+}
+
+var webhookAddCmd = &cobra.Command{
+	Use:   "add",
+	Short: "Add a webhook.",
+	Long:  "Post events of your secrets to a URL, e.g. passKeeper webhook add https://ci.example.com/hooks --events updated,deleted. Deliveries are signed with the secret shown once, see the README for verifying them.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 1 {
+			return fmt.Errorf("wrong number of arguments. expected the URL")
+		}
+		appl := app.GetApplication()
+		created, err := appl.AddWebhook(args[0], webhookEvents, webhookOrg, webhookSecret)
+		if err != nil {
+			return fmt.Errorf("cannot add webhook: %s", err)
+		}
+		fmt.Printf("Webhook %d posts %s to %s\n", created.ID, created.Events, created.URL)
+		fmt.Printf("Signing secret: %s\n", created.Secret)
+		fmt.Println("Store the secret now, it is not shown again.")
+		return nil
+	},
+}
+
+var webhookListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List webhooks.",
+	Long:  "List your webhooks, or those of an organization you administer with --org.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		appl := app.GetApplication()
+		hooks, err := appl.Webhooks(webhookOrg)
+		if err != nil {
+			return fmt.Errorf("cannot get webhooks: %s", err)
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tURL\tEvents\tCreated")
+		for _, h := range hooks {
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", h.ID, h.URL, h.Events, app.FormatTime(&h.CreatedAt))
+		}
+		return w.Flush()
+	},
+}
+
+var webhookRemoveCmd = &cobra.Command{
+	Use:   "remove",
+	Short: "Remove a webhook.",
+	Long:  "Stop posting events to a webhook and remove its deliveries, e.g. passKeeper webhook remove 4.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 1 {
+			return fmt.Errorf("wrong number of arguments. expected the webhook ID")
+		}
+		appl := app.GetApplication()
+		if err := appl.RemoveWebhook(args[0]); err != nil {
+			return fmt.Errorf("cannot remove webhook: %s", err)
+		}
+		fmt.Printf("Webhook %s removed\n", args[0])
+		return nil
+	},
+}
+
+var webhookDeliveriesCmd = &cobra.Command{
+	Use:   "deliveries",
+	Short: "Show the deliveries of a webhook.",
+	Long:  "Show the last deliveries of a webhook with their state, attempts and last response, e.g. passKeeper webhook deliveries 4.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 1 {
+			return fmt.Errorf("wrong number of arguments. expected the webhook ID")
+		}
+		appl := app.GetApplication()
+		deliveries, err := appl.WebhookDeliveries(args[0])
+		if err != nil {
+			return fmt.Errorf("cannot get deliveries: %s", err)
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tTime\tEvent\tSecretID\tState\tAttempts\tStatus\tError")
+		for _, d := range deliveries {
+			status := "-"
+			if d.LastStatus != 0 {
+				status = strconv.Itoa(d.LastStatus)
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\t%d\t%s\t%d\t%s\t%s\n", d.ID, app.FormatTime(&d.CreatedAt), d.Event, d.SecretID,
+				d.State, d.Attempts, status, d.LastError)
+		}
+		return w.Flush()
+	},
+}
+
+var watchCmd = &cobra.Command{
+	Use:   "watch",
+	Short: "Show changes of secrets live.",
+	Long:  "Print the changes of the secrets you can read as they happen, until interrupted, e.g. passKeeper watch --exec \"make config\". With --exec the command is run after each change with PASSKEEPER_EVENT, PASSKEEPER_SECRET_ID and PASSKEEPER_ACTOR_ID set, and with PASSKEEPER_EVENT=resync after a lost connection, since changes may have been missed.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		appl := app.GetApplication()
+		subscribed := false
+		return appl.Watch(func(e events.Event) error {
+			if e.Type == events.Subscribed {
+				if !subscribed {
+					subscribed = true
+					fmt.Println("Watching for changes, press Ctrl+C to stop")
+					return nil
+				}
+				fmt.Println("Reconnected, changes may have been missed")
+				e.Type = "resync"
+			} else {
+				actor := "server"
+				if e.ActorID != 0 {
+					actor = fmt.Sprintf("user %d", e.ActorID)
+				}
+				fmt.Printf("%s\t%s\tsecret %d\tby %s\n", app.FormatTime(&e.OccurredAt), e.Type, e.SecretID, actor)
+			}
+			if watchExec != "" {
+				runOnChange(watchExec, e)
+			}
+			return nil
+		})
+	},
+}
+
+// runOnChange runs the shell command with the change in its environment.
+// Failures are reported without stopping the watch.
+func runOnChange(command string, e events.Event) {
+	c := exec.Command("sh", "-c", command)
+	c.Stdin, c.Stdout, c.Stderr = os.Stdin, os.Stdout, os.Stderr
+	c.Env = append(os.Environ(),
+		"PASSKEEPER_EVENT="+e.Type,
+		"PASSKEEPER_SECRET_ID="+strconv.FormatUint(uint64(e.SecretID), 10),
+		"PASSKEEPER_ACTOR_ID="+strconv.FormatUint(uint64(e.ActorID), 10))
+	if err := c.Run(); err != nil {
+		fmt.Fprintf(os.Stderr, "command failed on %s of secret %d: %s\n", e.Type, e.SecretID, err)
+	}
+}
+
+var changesCmd = &cobra.Command{
+	Use:   "changes",
+	Short: "Show the secrets changed since a revision.",
+	Long:  "Show the secrets created, updated or deleted since a revision, oldest first, and the revision to pass with --since next time, e.g. passKeeper changes --since 42. Without --since all secrets are shown.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		appl := app.GetApplication()
+		changes, revision, err := appl.Changes(changesSince)
+		if err != nil {
+			return fmt.Errorf("cannot get changes: %s", err)
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "Revision\tSecretID\tChange\tPath\tName")
+		for _, c := range changes {
+			if c.Deleted || c.Secret == nil {
+				fmt.Fprintf(w, "%d\t%d\tdeleted\t-\t-\n", c.Revision, c.SecretID)
+				continue
+			}
+			path := c.Secret.Path
+			if path == "" {
+				path = "-"
+			}
+			fmt.Fprintf(w, "%d\t%d\tchanged\t%s\t%s\n", c.Revision, c.SecretID, path, c.Secret.TypedMeta().Label())
+		}
+		if err := w.Flush(); err != nil {
+			return err
 		}
+		fmt.Printf("Revision: %d\n", revision)
 		return nil
 	},
 }
@@ -10,8 +10,25 @@ var baseStyle = lipgloss.NewStyle().
 	BorderStyle(lipgloss.NormalBorder()).
 	BorderForeground(lipgloss.Color("240"))
 
+// loadAhead is how close to the last loaded row the cursor may get before
+// the next page is requested.
+const loadAhead = 3
+
+// LoadMoreFunc returns the rows of the next page and whether there are more.
+type LoadMoreFunc func() ([]table.Row, bool, error)
+
+type rowsMsg struct {
+	rows []table.Row
+	more bool
+	err  error
+}
+
 type modelList struct {
-	table table.Model
+	table    table.Model
+	loadMore LoadMoreFunc
+	more     bool
+	loading  bool
+	err      error
 }
 
 func (m modelList) Init() tea.Cmd { return nil }
@@ -24,14 +41,53 @@ func (m modelList) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case "ctrl+c", "esc":
 			return m, tea.Quit
 		}
-
+	case rowsMsg:
+		m.loading = false
+		m.err = msg.err
+		if msg.err == nil {
+			m.table.SetRows(append(m.table.Rows(), msg.rows...))
+			m.more = msg.more
+		}
+		return m, nil
 	}
 	m.table, cmd = m.table.Update(msg)
-	return m, cmd
+	load := m.maybeLoad()
+	return m, tea.Batch(cmd, load)
+}
+
+// maybeLoad starts loading the next page when the cursor is close to the end.
+func (m *modelList) maybeLoad() tea.Cmd {
+	if !m.more || m.loading || m.err != nil || m.table.Cursor() < len(m.table.Rows())-loadAhead {
+		return nil
+	}
+	m.loading = true
+	load := m.loadMore
+	return func() tea.Msg {
+		rows, more, err := load()
+		return rowsMsg{rows: rows, more: more, err: err}
+	}
 }
 
 func (m modelList) View() string {
-	return baseStyle.Render(m.table.View()) + "\n"
+	status := ""
+	switch {
+	case m.err != nil:
+		status = "cannot load more secrets: " + m.err.Error()
+	case m.loading:
+		status = "loading more secrets..."
+	case m.more:
+		status = "scroll down to load more"
+	}
+	return baseStyle.Render(m.table.View()) + "\n" + status + "\n"
+}
+
+// NewPagedModel works like NewModel but calls loadMore for further rows when
+// the user scrolls towards the end of the table, as long as more is true.
+func NewPagedModel(columns []table.Column, rows []table.Row, more bool, loadMore LoadMoreFunc) modelList {
+	m := NewModel(columns, rows)
+	m.more = more
+	m.loadMore = loadMore
+	return m
 }
 
 func NewModel(columns []table.Column, rows []table.Row) modelList {
@@ -55,7 +111,7 @@ func NewModel(columns []table.Column, rows []table.Row) modelList {
 		Bold(false)
 	t.SetStyles(s)
 
-	return modelList{t}
+	return modelList{table: t}
 }
 
 func truncate(str string, num int) string {
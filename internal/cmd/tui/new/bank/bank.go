@@ -0,0 +1,338 @@
+package newbanksecret
+
+import (
+	"fmt"
+	app "passKeeper/internal/cmd/app"
+	secret "passKeeper/internal/models/secret"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+func EditBankTui(account secret.BankAccount, meta string, id uint) error {
+	finalModel, err := tea.NewProgram(InitialEditModel(account, meta)).Run()
+	if err != nil {
+		return err
+	}
+
+	ans := finalModel.(Model)
+
+	if !ans.Done {
+		return nil
+	}
+
+	app := app.GetApplication()
+
+	err = app.EditBankSecret(id, ans.Meta, ans.Account)
+	if err != nil {
+		return err
+	}
+
+	return nil
+
+}
+
+func NewBankTui(target app.NewSecret) error {
+	finalModel, err := tea.NewProgram(InitialModel()).Run()
+	if err != nil {
+		return err
+	}
+
+	ans := finalModel.(Model)
+
+	if !ans.Done {
+		return nil
+	}
+	app := app.GetApplication()
+
+	err = app.CreateBankSecret(target, ans.Meta, ans.Account)
+	if err != nil {
+		return err
+	}
+
+	return nil
+
+}
+
+var (
+	focusedColor = lipgloss.AdaptiveColor{Light: "236", Dark: "248"}
+	blurredColor = lipgloss.AdaptiveColor{Light: "238", Dark: "246"}
+	errorColor   = lipgloss.AdaptiveColor{Light: "160", Dark: "203"}
+
+	focusedStyle = lipgloss.NewStyle().Foreground(focusedColor)
+	blurredStyle = lipgloss.NewStyle().Foreground(blurredColor)
+	errorStyle   = lipgloss.NewStyle().Foreground(errorColor)
+	cursorStyle  = focusedStyle.Copy()
+	noStyle      = lipgloss.NewStyle()
+
+	focusedButton = focusedStyle.Copy().Bold(true).Render("[ Save ]")
+	blurredButton = fmt.Sprintf("[ %s ]", blurredStyle.Render("Save"))
+)
+
+type Model struct {
+	focusIndex int
+
+	inputs  []textinput.Model
+	Meta    string
+	Account secret.BankAccount
+	Done    bool
+	err     error
+	width   int
+	height  int
+}
+
+func (m Model) Init() tea.Cmd {
+	return textinput.Blink
+}
+
+func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		m.height = msg.Height
+
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "ctrl+c", "esc":
+			return m, tea.Quit
+
+		// Set focus to next input
+		case "tab", "shift+tab", "enter", "up", "down":
+			s := msg.String()
+
+			// Did the user press enter while the submit button was focused?
+			// If so, validate and store values provided
+			if s == "enter" && m.focusIndex == len(m.inputs) {
+				account := secret.BankAccount{
+					Holder:        m.inputs[1].Value(),
+					BankName:      m.inputs[2].Value(),
+					IBAN:          m.inputs[3].Value(),
+					BIC:           m.inputs[4].Value(),
+					AccountNumber: m.inputs[5].Value(),
+					RoutingNumber: m.inputs[6].Value(),
+				}
+				if err := account.Validate(); err != nil {
+					m.err = err
+					return m, nil
+				}
+				m.Meta = m.inputs[0].Value()
+				m.Account = account
+				m.Done = true
+				return m, tea.Quit
+			}
+
+			// Cycle indexes
+			if s == "up" || s == "shift+tab" {
+				m.focusIndex--
+			} else {
+				m.focusIndex++
+			}
+
+			if m.focusIndex > len(m.inputs) {
+				m.focusIndex = 0
+			} else if m.focusIndex < 0 {
+				m.focusIndex = len(m.inputs)
+			}
+
+			cmds := make([]tea.Cmd, len(m.inputs))
+			for i := 0; i <= len(m.inputs)-1; i++ {
+				if i == m.focusIndex {
+					// Set focused state
+					cmds[i] = m.inputs[i].Focus()
+					m.inputs[i].PromptStyle = focusedStyle
+					m.inputs[i].TextStyle = focusedStyle
+					continue
+				}
+				// Remove focused state
+				m.inputs[i].Blur()
+				m.inputs[i].PromptStyle = noStyle
+				m.inputs[i].TextStyle = noStyle
+			}
+
+			return m, tea.Batch(cmds...)
+		}
+	}
+
+	// Handle character input and blinking
+	cmd := m.updateInputs(msg)
+
+	return m, cmd
+}
+
+func (m *Model) updateInputs(msg tea.Msg) tea.Cmd {
+	cmds := make([]tea.Cmd, len(m.inputs))
+
+	// Only text inputs with Focus() set will respond, so it's safe to simply
+	// update all of them here without any further logic.
+	for i := range m.inputs {
+		m.inputs[i], cmds[i] = m.inputs[i].Update(msg)
+	}
+
+	return tea.Batch(cmds...)
+}
+
+func (m Model) View() string {
+	if m.width == 0 {
+		return "loading..."
+	}
+
+	boderColor := lipgloss.AdaptiveColor{Light: "22", Dark: "42"}
+	style := lipgloss.NewStyle().
+		BorderForeground(boderColor).
+		BorderStyle(lipgloss.NormalBorder()).
+		Width(80).
+		BorderBottom(true)
+
+	title := "\n[:New Bank Account Secret:]\n"
+	titleStyle := lipgloss.NewStyle().Foreground(boderColor).Bold(true)
+	s := titleStyle.Render(title)
+
+	var b strings.Builder
+	for i := range m.inputs {
+		b.WriteString(style.Render(m.inputs[i].View()))
+		if i < len(m.inputs)-1 {
+			b.WriteRune('\n')
+		}
+	}
+
+	button := &blurredButton
+	if m.focusIndex == len(m.inputs) {
+		button = &focusedButton
+	}
+	fmt.Fprintf(&b, "\n\n%s\n\n", *button)
+	if m.err != nil {
+		b.WriteString(errorStyle.Render(m.err.Error()))
+	}
+
+	return lipgloss.Place(
+		m.width,
+		m.height,
+		lipgloss.Center,
+		lipgloss.Left,
+		lipgloss.JoinVertical(
+			lipgloss.Left,
+			s,
+			b.String(),
+		),
+	)
+
+}
+
+func InitialEditModel(account secret.BankAccount, meta string) Model {
+	m := InitialModel()
+	values := []string{meta, account.Holder, account.BankName, account.IBAN, account.BIC, account.AccountNumber, account.RoutingNumber}
+	for i := range m.inputs {
+		m.inputs[i].SetValue(values[i])
+	}
+	m.Meta = meta
+	m.Account = account
+	return m
+}
+
+func InitialModel() Model {
+	m := Model{
+		inputs: make([]textinput.Model, 7),
+	}
+
+	var t textinput.Model
+
+	for i := range m.inputs {
+		t = textinput.New()
+		t.CursorStyle = cursorStyle
+		t.CharLimit = 255
+		t.Prompt = ""
+
+		switch i {
+		case 0:
+			t.Placeholder = "Bank account metadata"
+			t.TextStyle = focusedStyle
+			t.CharLimit = 30
+
+			t.Focus()
+		case 1:
+			t.Placeholder = "Account holder"
+			t.TextStyle = focusedStyle
+			t.CharLimit = 70
+		case 2:
+			t.Placeholder = "Bank name"
+			t.TextStyle = focusedStyle
+			t.CharLimit = 70
+		case 3:
+			t.Placeholder = "IBAN"
+			t.TextStyle = focusedStyle
+			t.Validate = ibanValidator
+			t.CharLimit = 42
+		case 4:
+			t.Placeholder = "BIC/SWIFT"
+			t.TextStyle = focusedStyle
+			t.Validate = bicValidator
+			t.CharLimit = 11
+		case 5:
+			t.Placeholder = "Account number"
+			t.TextStyle = focusedStyle
+			t.Validate = digitsValidator
+			t.CharLimit = 34
+		case 6:
+			t.Placeholder = "Routing number"
+			t.TextStyle = focusedStyle
+			t.Validate = digitsValidator
+			t.CharLimit = 15
+		}
+
+		m.inputs[i] = t
+	}
+
+	return m
+}
+
+func ibanValidator(s string) error {
+	// The IBAN starts with a two letter country code and two check digits,
+	// the rest is alphanumeric. Groups may be separated with spaces.
+	// The mod-97 checksum is verified once the whole number is entered.
+	c := strings.ToUpper(strings.ReplaceAll(s, " ", ""))
+	if len(c) > 34 {
+		return fmt.Errorf("IBAN is too long")
+	}
+	for i, r := range c {
+		isLetter := r >= 'A' && r <= 'Z'
+		isDigit := r >= '0' && r <= '9'
+		if i < 2 && !isLetter {
+			return fmt.Errorf("IBAN must start with a country code")
+		}
+		if i >= 2 && i < 4 && !isDigit {
+			return fmt.Errorf("IBAN check digits are invalid")
+		}
+		if !isLetter && !isDigit {
+			return fmt.Errorf("IBAN is invalid")
+		}
+	}
+	return nil
+}
+
+func bicValidator(s string) error {
+	// Bank and country codes are letters, location and branch codes are
+	// alphanumeric.
+	c := strings.ToUpper(s)
+	for i, r := range c {
+		isLetter := r >= 'A' && r <= 'Z'
+		isDigit := r >= '0' && r <= '9'
+		if i < 6 && !isLetter {
+			return fmt.Errorf("BIC is invalid")
+		}
+		if !isLetter && !isDigit {
+			return fmt.Errorf("BIC is invalid")
+		}
+	}
+	return nil
+}
+
+func digitsValidator(s string) error {
+	for _, r := range s {
+		if (r < '0' || r > '9') && r != ' ' && r != '-' {
+			return fmt.Errorf("only digits are allowed")
+		}
+	}
+	return nil
+}
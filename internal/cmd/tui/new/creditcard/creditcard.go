@@ -37,7 +37,7 @@ func EditCCTui(cc secret.CreditCard, meta string, id uint) error {
 
 }
 
-func NewCCTui() error {
+func NewCCTui(target app.NewSecret) error {
 	finalModel, err := tea.NewProgram(InitialModel()).Run()
 	if err != nil {
 		return err
@@ -50,7 +50,7 @@ func NewCCTui() error {
 	}
 	app := app.GetApplication()
 
-	err = app.CreateCCSecret(ans.Meta, ans.CCN, ans.EXP, ans.CVV, ans.CHolder)
+	err = app.CreateCCSecret(target, ans.Meta, ans.CCN, ans.EXP, ans.CVV, ans.CHolder)
 	if err != nil {
 		return err
 	}
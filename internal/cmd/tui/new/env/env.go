@@ -0,0 +1,370 @@
+package newenvsecret
+
+import (
+	"fmt"
+	"strings"
+
+	app "passKeeper/internal/cmd/app"
+	secret "passKeeper/internal/models/secret"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// visibleRows is the number of variables shown at once, the form scrolls to
+// keep the focused variable on screen.
+const visibleRows = 8
+
+func EditEnvTui(bundle secret.EnvBundle, meta string, id uint) error {
+	finalModel, err := tea.NewProgram(InitialModel(bundle, meta)).Run()
+	if err != nil {
+		return err
+	}
+
+	ans := finalModel.(Model)
+
+	if !ans.Done {
+		return nil
+	}
+	app := app.GetApplication()
+
+	err = app.EditEnvSecret(id, ans.Meta, ans.Bundle)
+	if err != nil {
+		return err
+	}
+
+	return nil
+
+}
+
+// NewEnvTui starts the Bubbletea environment bundle TUI, prefilled with the
+// variables of bundle (e.g. imported from a dotenv file).
+func NewEnvTui(target app.NewSecret, bundle secret.EnvBundle) error {
+	finalModel, err := tea.NewProgram(InitialModel(bundle, "")).Run()
+	if err != nil {
+		return err
+	}
+
+	ans := finalModel.(Model)
+
+	if !ans.Done {
+		return nil
+	}
+	app := app.GetApplication()
+
+	err = app.CreateEnvSecret(target, ans.Meta, ans.Bundle)
+	if err != nil {
+		return err
+	}
+
+	return nil
+
+}
+
+var (
+	focusedColor = lipgloss.AdaptiveColor{Light: "236", Dark: "248"}
+	blurredColor = lipgloss.AdaptiveColor{Light: "238", Dark: "246"}
+	errorColor   = lipgloss.AdaptiveColor{Light: "160", Dark: "203"}
+
+	focusedStyle = lipgloss.NewStyle().Foreground(focusedColor)
+	blurredStyle = lipgloss.NewStyle().Foreground(blurredColor)
+	errorStyle   = lipgloss.NewStyle().Foreground(errorColor)
+	cursorStyle  = focusedStyle.Copy()
+	noStyle      = lipgloss.NewStyle()
+
+	focusedButton = focusedStyle.Copy().Bold(true).Render("[ Save ]")
+	blurredButton = fmt.Sprintf("[ %s ]", blurredStyle.Render("Save"))
+)
+
+type envRow struct {
+	key    textinput.Model
+	value  textinput.Model
+	hidden bool
+}
+
+// Model keeps the metadata input followed by a key and a value input for
+// every variable. focusIndex 0 is the metadata, 1..2*len(rows) are the
+// variable inputs and the last index is the Save button.
+type Model struct {
+	focusIndex int
+	offset     int
+
+	meta   textinput.Model
+	rows   []envRow
+	Meta   string
+	Bundle secret.EnvBundle
+	Done   bool
+	err    error
+	width  int
+	height int
+}
+
+func (m Model) Init() tea.Cmd {
+	return textinput.Blink
+}
+
+func (m Model) buttonIndex() int {
+	return 2*len(m.rows) + 1
+}
+
+// input returns the text input at the focus index or nil for the button.
+func (m *Model) input(index int) *textinput.Model {
+	if index == 0 {
+		return &m.meta
+	}
+	if index >= m.buttonIndex() {
+		return nil
+	}
+	row := &m.rows[(index-1)/2]
+	if (index-1)%2 == 0 {
+		return &row.key
+	}
+	return &row.value
+}
+
+func (m Model) focusedRow() int {
+	if m.focusIndex == 0 || m.focusIndex >= m.buttonIndex() {
+		return -1
+	}
+	return (m.focusIndex - 1) / 2
+}
+
+func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		m.height = msg.Height
+
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "ctrl+c", "esc":
+			return m, tea.Quit
+
+		// Add a new variable after the focused one
+		case "ctrl+n":
+			at := m.focusedRow() + 1
+			if at == 0 {
+				at = len(m.rows)
+			}
+			m.rows = append(m.rows, envRow{})
+			copy(m.rows[at+1:], m.rows[at:])
+			m.rows[at] = newRow(secret.EnvVar{})
+			return m, m.setFocus(2*at + 1)
+
+		// Remove the focused variable
+		case "ctrl+d":
+			if row := m.focusedRow(); row >= 0 {
+				m.rows = append(m.rows[:row], m.rows[row+1:]...)
+				return m, m.setFocus(m.focusIndex)
+			}
+
+		// Toggle the hidden flag of the focused variable
+		case "ctrl+t":
+			if row := m.focusedRow(); row >= 0 {
+				m.rows[row].hidden = !m.rows[row].hidden
+				m.rows[row].value.EchoMode = echoMode(m.rows[row].hidden)
+			}
+			return m, nil
+
+		// Set focus to next input
+		case "tab", "shift+tab", "enter", "up", "down":
+			s := msg.String()
+
+			// Did the user press enter while the submit button was focused?
+			// If so, validate and store values provided
+			if s == "enter" && m.focusIndex == m.buttonIndex() {
+				bundle := secret.EnvBundle{}
+				for _, row := range m.rows {
+					if row.key.Value() == "" && row.value.Value() == "" {
+						continue
+					}
+					bundle.Vars = append(bundle.Vars, secret.EnvVar{Key: row.key.Value(), Value: row.value.Value(), Hidden: row.hidden})
+				}
+				if err := bundle.Validate(); err != nil {
+					m.err = err
+					return m, nil
+				}
+				m.Meta = m.meta.Value()
+				m.Bundle = bundle
+				m.Done = true
+				return m, tea.Quit
+			}
+
+			// Cycle indexes
+			if s == "up" || s == "shift+tab" {
+				return m, m.setFocus(m.focusIndex - 1)
+			}
+			return m, m.setFocus(m.focusIndex + 1)
+		}
+	}
+
+	// Handle character input and blinking
+	cmd := m.updateInputs(msg)
+
+	return m, cmd
+}
+
+// setFocus moves the focus, wrapping around at both ends, and scrolls the
+// rows so that the focused variable is visible.
+func (m *Model) setFocus(index int) tea.Cmd {
+	if index > m.buttonIndex() {
+		index = 0
+	} else if index < 0 {
+		index = m.buttonIndex()
+	}
+	m.focusIndex = index
+
+	if row := m.focusedRow(); row >= 0 {
+		if row < m.offset {
+			m.offset = row
+		} else if row >= m.offset+visibleRows {
+			m.offset = row - visibleRows + 1
+		}
+	} else if index == m.buttonIndex() && len(m.rows) > visibleRows {
+		m.offset = len(m.rows) - visibleRows
+	} else if index == 0 {
+		m.offset = 0
+	}
+
+	var cmd tea.Cmd
+	for i := 0; i < m.buttonIndex(); i++ {
+		in := m.input(i)
+		if i == index {
+			// Set focused state
+			cmd = in.Focus()
+			in.PromptStyle = focusedStyle
+			in.TextStyle = focusedStyle
+			continue
+		}
+		// Remove focused state
+		in.Blur()
+		in.PromptStyle = noStyle
+		in.TextStyle = noStyle
+	}
+	return cmd
+}
+
+func (m *Model) updateInputs(msg tea.Msg) tea.Cmd {
+	// Only the focused input will respond, so it's safe to simply update it
+	// here without any further logic.
+	if in := m.input(m.focusIndex); in != nil {
+		var cmd tea.Cmd
+		*in, cmd = in.Update(msg)
+		return cmd
+	}
+	return nil
+}
+
+func (m Model) View() string {
+	if m.width == 0 {
+		return "loading..."
+	}
+
+	boderColor := lipgloss.AdaptiveColor{Light: "22", Dark: "42"}
+	style := lipgloss.NewStyle().
+		BorderForeground(boderColor).
+		BorderStyle(lipgloss.NormalBorder()).
+		Width(80).
+		BorderBottom(true)
+	keyStyle := style.Copy().Width(30)
+	valueStyle := style.Copy().Width(48)
+
+	title := "\n[:Environment Bundle Secret:]\n"
+	titleStyle := lipgloss.NewStyle().Foreground(boderColor).Bold(true)
+	s := titleStyle.Render(title)
+
+	var b strings.Builder
+	b.WriteString(style.Render(m.meta.View()))
+	b.WriteRune('\n')
+
+	end := m.offset + visibleRows
+	if end > len(m.rows) {
+		end = len(m.rows)
+	}
+	if m.offset > 0 {
+		fmt.Fprintf(&b, "%s\n", blurredStyle.Render(fmt.Sprintf("↑ %d more", m.offset)))
+	}
+	for _, row := range m.rows[m.offset:end] {
+		flag := " "
+		if row.hidden {
+			flag = "*"
+		}
+		b.WriteString(lipgloss.JoinHorizontal(lipgloss.Bottom, keyStyle.Render(row.key.View()), " = ", valueStyle.Render(row.value.View()), " ", flag))
+		b.WriteRune('\n')
+	}
+	if end < len(m.rows) {
+		fmt.Fprintf(&b, "%s\n", blurredStyle.Render(fmt.Sprintf("↓ %d more", len(m.rows)-end)))
+	}
+
+	button := &blurredButton
+	if m.focusIndex == m.buttonIndex() {
+		button = &focusedButton
+	}
+	fmt.Fprintf(&b, "\n%s\n\n", *button)
+	b.WriteString(blurredStyle.Render("ctrl+n add variable • ctrl+d remove variable • ctrl+t toggle hidden (*)"))
+	if m.err != nil {
+		b.WriteString("\n" + errorStyle.Render(m.err.Error()))
+	}
+
+	return lipgloss.Place(
+		m.width,
+		m.height,
+		lipgloss.Center,
+		lipgloss.Left,
+		lipgloss.JoinVertical(
+			lipgloss.Left,
+			s,
+			b.String(),
+		),
+	)
+
+}
+
+func InitialModel(bundle secret.EnvBundle, meta string) Model {
+	t := textinput.New()
+	t.CursorStyle = cursorStyle
+	t.CharLimit = 255
+	t.Prompt = ""
+	t.Placeholder = "Meta"
+	t.TextStyle = focusedStyle
+	t.SetValue(meta)
+	t.Focus()
+
+	m := Model{meta: t, Meta: meta, Bundle: bundle}
+	for _, v := range bundle.Vars {
+		m.rows = append(m.rows, newRow(v))
+	}
+	if len(m.rows) == 0 {
+		m.rows = append(m.rows, newRow(secret.EnvVar{}))
+	}
+
+	return m
+}
+
+func newRow(v secret.EnvVar) envRow {
+	key := textinput.New()
+	key.CursorStyle = cursorStyle
+	key.CharLimit = 255
+	key.Prompt = ""
+	key.Placeholder = "KEY"
+	key.SetValue(v.Key)
+
+	value := textinput.New()
+	value.CursorStyle = cursorStyle
+	value.CharLimit = 4096
+	value.Prompt = ""
+	value.Placeholder = "Value"
+	value.EchoMode = echoMode(v.Hidden)
+	value.EchoCharacter = '•'
+	value.SetValue(v.Value)
+
+	return envRow{key: key, value: value, hidden: v.Hidden}
+}
+
+func echoMode(hidden bool) textinput.EchoMode {
+	if hidden {
+		return textinput.EchoPassword
+	}
+	return textinput.EchoNormal
+}
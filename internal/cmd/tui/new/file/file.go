@@ -13,7 +13,7 @@ import (
 )
 
 // ConfigTui starts the Bubbletea Configuration TUI
-func FileTui() error {
+func FileTui(target app.NewSecret) error {
 	finalModel, err := tea.NewProgram(InitialModel()).Run()
 	if err != nil {
 		return err
@@ -31,7 +31,7 @@ func FileTui() error {
 
 	app := app.GetApplication()
 
-	err = app.CreateFileSecret(ans.Metadata, ans.Path)
+	err = app.CreateFileSecret(target, ans.Name, ans.Description, ans.Path)
 	if err != nil {
 		return err
 	}
@@ -56,12 +56,13 @@ var (
 type Model struct {
 	focusIndex int
 
-	inputs   []textinput.Model
-	Metadata string
-	Path     string
-	Done     bool
-	width    int
-	height   int
+	inputs      []textinput.Model
+	Name        string
+	Description string
+	Path        string
+	Done        bool
+	width       int
+	height      int
 }
 
 func (m Model) Init() tea.Cmd {
@@ -86,8 +87,9 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			// Did the user press enter while the submit button was focused?
 			// If so, store values provided
 			if s == "enter" && m.focusIndex == len(m.inputs) {
-				m.Metadata = m.inputs[0].Value()
-				m.Path = m.inputs[1].Value()
+				m.Name = m.inputs[0].Value()
+				m.Description = m.inputs[1].Value()
+				m.Path = m.inputs[2].Value()
 				m.Done = true
 				return m, tea.Quit
 			}
@@ -188,7 +190,7 @@ func (m Model) View() string {
 
 func InitialModel() Model {
 	m := Model{
-		inputs: make([]textinput.Model, 2),
+		inputs: make([]textinput.Model, 3),
 	}
 
 	var t textinput.Model
@@ -201,10 +203,12 @@ func InitialModel() Model {
 
 		switch i {
 		case 0:
-			t.Placeholder = "Metadata"
+			t.Placeholder = "Name (defaults to the filename)"
 			t.TextStyle = focusedStyle
 			t.Focus()
 		case 1:
+			t.Placeholder = "Description"
+		case 2:
 			t.Placeholder = "Absolute Path to the file"
 
 		}
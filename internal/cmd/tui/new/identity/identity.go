@@ -0,0 +1,328 @@
+package newidentitysecret
+
+import (
+	"fmt"
+	app "passKeeper/internal/cmd/app"
+	secret "passKeeper/internal/models/secret"
+	client "passKeeper/pkg"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+func EditIdentityTui(identity secret.Identity, meta string, id uint) error {
+	finalModel, err := tea.NewProgram(InitialEditModel(identity, meta)).Run()
+	if err != nil {
+		return err
+	}
+
+	ans := finalModel.(Model)
+
+	if !ans.Done {
+		return nil
+	}
+
+	app := app.GetApplication()
+
+	err = app.EditIdentitySecret(id, ans.Meta, ans.Identity, ans.Scans)
+	if err != nil {
+		return err
+	}
+
+	return nil
+
+}
+
+func NewIdentityTui(target app.NewSecret) error {
+	finalModel, err := tea.NewProgram(InitialModel()).Run()
+	if err != nil {
+		return err
+	}
+
+	ans := finalModel.(Model)
+
+	if !ans.Done {
+		return nil
+	}
+	app := app.GetApplication()
+
+	err = app.CreateIdentitySecret(target, ans.Meta, ans.Identity, ans.Scans)
+	if err != nil {
+		return err
+	}
+
+	return nil
+
+}
+
+var (
+	focusedColor = lipgloss.AdaptiveColor{Light: "236", Dark: "248"}
+	blurredColor = lipgloss.AdaptiveColor{Light: "238", Dark: "246"}
+	errorColor   = lipgloss.AdaptiveColor{Light: "160", Dark: "203"}
+
+	focusedStyle = lipgloss.NewStyle().Foreground(focusedColor)
+	blurredStyle = lipgloss.NewStyle().Foreground(blurredColor)
+	errorStyle   = lipgloss.NewStyle().Foreground(errorColor)
+	cursorStyle  = focusedStyle.Copy()
+	noStyle      = lipgloss.NewStyle()
+
+	focusedButton = focusedStyle.Copy().Bold(true).Render("[ Save ]")
+	blurredButton = fmt.Sprintf("[ %s ]", blurredStyle.Render("Save"))
+)
+
+type Model struct {
+	focusIndex int
+
+	inputs   []textinput.Model
+	Meta     string
+	Identity secret.Identity
+	Scans    []string
+	Done     bool
+	err      error
+	width    int
+	height   int
+}
+
+func (m Model) Init() tea.Cmd {
+	return textinput.Blink
+}
+
+func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		m.height = msg.Height
+
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "ctrl+c", "esc":
+			return m, tea.Quit
+
+		// Set focus to next input
+		case "tab", "shift+tab", "enter", "up", "down":
+			s := msg.String()
+
+			// Did the user press enter while the submit button was focused?
+			// If so, validate and store values provided
+			if s == "enter" && m.focusIndex == len(m.inputs) {
+				identity := secret.Identity{
+					DocumentType:  m.inputs[1].Value(),
+					Number:        m.inputs[2].Value(),
+					Country:       strings.ToUpper(m.inputs[3].Value()),
+					NameAsPrinted: m.inputs[4].Value(),
+					IssueDate:     m.inputs[5].Value(),
+					ExpiryDate:    m.inputs[6].Value(),
+				}
+				if err := identity.Validate(); err != nil {
+					m.err = err
+					return m, nil
+				}
+				scans := splitPaths(m.inputs[7].Value())
+				for _, path := range scans {
+					if !client.FileExists(path) {
+						m.err = fmt.Errorf("file \"%s\" not exist", path)
+						return m, nil
+					}
+				}
+				m.Meta = m.inputs[0].Value()
+				m.Identity = identity
+				m.Scans = scans
+				m.Done = true
+				return m, tea.Quit
+			}
+
+			// Cycle indexes
+			if s == "up" || s == "shift+tab" {
+				m.focusIndex--
+			} else {
+				m.focusIndex++
+			}
+
+			if m.focusIndex > len(m.inputs) {
+				m.focusIndex = 0
+			} else if m.focusIndex < 0 {
+				m.focusIndex = len(m.inputs)
+			}
+
+			cmds := make([]tea.Cmd, len(m.inputs))
+			for i := 0; i <= len(m.inputs)-1; i++ {
+				if i == m.focusIndex {
+					// Set focused state
+					cmds[i] = m.inputs[i].Focus()
+					m.inputs[i].PromptStyle = focusedStyle
+					m.inputs[i].TextStyle = focusedStyle
+					continue
+				}
+				// Remove focused state
+				m.inputs[i].Blur()
+				m.inputs[i].PromptStyle = noStyle
+				m.inputs[i].TextStyle = noStyle
+			}
+
+			return m, tea.Batch(cmds...)
+		}
+	}
+
+	// Handle character input and blinking
+	cmd := m.updateInputs(msg)
+
+	return m, cmd
+}
+
+func (m *Model) updateInputs(msg tea.Msg) tea.Cmd {
+	cmds := make([]tea.Cmd, len(m.inputs))
+
+	// Only text inputs with Focus() set will respond, so it's safe to simply
+	// update all of them here without any further logic.
+	for i := range m.inputs {
+		m.inputs[i], cmds[i] = m.inputs[i].Update(msg)
+	}
+
+	return tea.Batch(cmds...)
+}
+
+func (m Model) View() string {
+	if m.width == 0 {
+		return "loading..."
+	}
+
+	boderColor := lipgloss.AdaptiveColor{Light: "22", Dark: "42"}
+	style := lipgloss.NewStyle().
+		BorderForeground(boderColor).
+		BorderStyle(lipgloss.NormalBorder()).
+		Width(80).
+		BorderBottom(true)
+
+	title := "\n[:New Identity Document Secret:]\n"
+	titleStyle := lipgloss.NewStyle().Foreground(boderColor).Bold(true)
+	s := titleStyle.Render(title)
+
+	var b strings.Builder
+	for i := range m.inputs {
+		b.WriteString(style.Render(m.inputs[i].View()))
+		if i < len(m.inputs)-1 {
+			b.WriteRune('\n')
+		}
+	}
+
+	button := &blurredButton
+	if m.focusIndex == len(m.inputs) {
+		button = &focusedButton
+	}
+	fmt.Fprintf(&b, "\n\n%s\n\n", *button)
+	if m.err != nil {
+		b.WriteString(errorStyle.Render(m.err.Error()))
+	}
+
+	return lipgloss.Place(
+		m.width,
+		m.height,
+		lipgloss.Center,
+		lipgloss.Left,
+		lipgloss.JoinVertical(
+			lipgloss.Left,
+			s,
+			b.String(),
+		),
+	)
+
+}
+
+func InitialEditModel(identity secret.Identity, meta string) Model {
+	m := InitialModel()
+	values := []string{meta, identity.DocumentType, identity.Number, identity.Country, identity.NameAsPrinted, identity.IssueDate, identity.ExpiryDate}
+	for i := range values {
+		m.inputs[i].SetValue(values[i])
+	}
+	m.inputs[7].Placeholder = "Absolute paths of additional scans, comma separated"
+	m.Meta = meta
+	m.Identity = identity
+	return m
+}
+
+func InitialModel() Model {
+	m := Model{
+		inputs: make([]textinput.Model, 8),
+	}
+
+	var t textinput.Model
+
+	for i := range m.inputs {
+		t = textinput.New()
+		t.CursorStyle = cursorStyle
+		t.CharLimit = 255
+		t.Prompt = ""
+
+		switch i {
+		case 0:
+			t.Placeholder = "Identity document metadata"
+			t.TextStyle = focusedStyle
+			t.CharLimit = 30
+
+			t.Focus()
+		case 1:
+			t.Placeholder = "Document type (passport, ID card, ...)"
+			t.TextStyle = focusedStyle
+			t.CharLimit = 30
+		case 2:
+			t.Placeholder = "Document number"
+			t.TextStyle = focusedStyle
+			t.CharLimit = 30
+		case 3:
+			t.Placeholder = "Issuing country"
+			t.TextStyle = focusedStyle
+			t.CharLimit = 56
+		case 4:
+			t.Placeholder = "Name as printed"
+			t.TextStyle = focusedStyle
+			t.CharLimit = 70
+		case 5:
+			t.Placeholder = "Issue date (YYYY-MM-DD)"
+			t.TextStyle = focusedStyle
+			t.Validate = dateValidator
+			t.CharLimit = 10
+		case 6:
+			t.Placeholder = "Expiry date (YYYY-MM-DD)"
+			t.TextStyle = focusedStyle
+			t.Validate = dateValidator
+			t.CharLimit = 10
+		case 7:
+			t.Placeholder = "Absolute paths of scanned images, comma separated"
+			t.TextStyle = focusedStyle
+			t.CharLimit = 1024
+		}
+
+		m.inputs[i] = t
+	}
+
+	return m
+}
+
+func dateValidator(s string) error {
+	// Dates are entered as YYYY-MM-DD, dashes are expected at the 5th and
+	// 8th characters, the rest should be digits.
+	for i, r := range s {
+		if i == 4 || i == 7 {
+			if r != '-' {
+				return fmt.Errorf("date must be in YYYY-MM-DD format")
+			}
+			continue
+		}
+		if r < '0' || r > '9' {
+			return fmt.Errorf("date must be in YYYY-MM-DD format")
+		}
+	}
+	return nil
+}
+
+func splitPaths(s string) []string {
+	var paths []string
+	for _, p := range strings.Split(s, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			paths = append(paths, p)
+		}
+	}
+	return paths
+}
@@ -35,7 +35,7 @@ func EditKVTui(kv secret.KeyValue, meta string, id uint) error {
 }
 
 // ConfigTui starts the Bubbletea Configuration TUI
-func NewKVTui() error {
+func NewKVTui(target app.NewSecret) error {
 	finalModel, err := tea.NewProgram(InitialModel()).Run()
 	if err != nil {
 		return err
@@ -48,7 +48,7 @@ func NewKVTui() error {
 	}
 	app := app.GetApplication()
 
-	err = app.CreateKVSecret(ans.Meta, ans.Key, ans.Value)
+	err = app.CreateKVSecret(target, ans.Meta, ans.Key, ans.Value)
 	if err != nil {
 		return err
 	}
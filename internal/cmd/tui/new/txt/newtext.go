@@ -60,7 +60,7 @@ func EditTextTui(txt secret.Text, meta string, id uint) error {
 
 }
 
-func NewTextTui() error {
+func NewTextTui(target cmd.NewSecret) error {
 	finalModel, err := tea.NewProgram(newTextSecretModel()).Run()
 	if err != nil {
 		return err
@@ -74,7 +74,7 @@ func NewTextTui() error {
 
 	app := cmd.GetApplication()
 
-	err = app.CreateTextSecret(ans.Metadata, ans.Data)
+	err = app.CreateTextSecret(target, ans.Metadata, ans.Data)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,79 @@
+// Package events distributes the changes of secrets inside the server, e.g.
+// to the webhooks of their owners.
+package events
+
+import (
+	"sync"
+	"time"
+
+	audit "passKeeper/internal/models/audit"
+	wh "passKeeper/internal/models/webhook"
+)
+
+// Event is a change of a secret. It never holds the value of the secret.
+type Event struct {
+	Type     string `json:"event"`
+	SecretID uint   `json:"secret_id"`
+	// ActorID is the account which made the change, zero for the server.
+	ActorID    uint      `json:"actor_id,omitempty"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+// auditEvents maps the audited actions to the events they publish. Reads,
+// purges of secrets already deleted and logins publish none.
+var auditEvents = map[string]string{
+	audit.ActionCreate:  wh.EventCreated,
+	audit.ActionUpdate:  wh.EventUpdated,
+	audit.ActionRestore: wh.EventUpdated,
+	audit.ActionDelete:  wh.EventDeleted,
+	audit.ActionShare:   wh.EventShared,
+	audit.ActionUnshare: wh.EventShared,
+}
+
+// FromAudit returns the event published for a successful audited action on a
+// secret, if any.
+func FromAudit(e audit.AuditEvent) (Event, bool) {
+	typ, ok := auditEvents[e.Action]
+	if !ok || e.Result != audit.ResultSuccess || e.SecretID == 0 {
+		return Event{}, false
+	}
+	return Event{Type: typ, SecretID: e.SecretID, ActorID: e.ActorID, OccurredAt: e.CreatedAt}, true
+}
+
+// Bus passes published events to its subscribers. A nil bus drops them.
+type Bus struct {
+	mu          sync.RWMutex
+	next        int
+	subscribers map[int]func(Event)
+}
+
+func NewBus() *Bus {
+	return &Bus{subscribers: map[int]func(Event){}}
+}
+
+// Subscribe calls fn with every event published until the returned function
+// is called. fn is called on the goroutine of the publisher and must not
+// block.
+func (b *Bus) Subscribe(fn func(Event)) func() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	id := b.next
+	b.next++
+	b.subscribers[id] = fn
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.subscribers, id)
+	}
+}
+
+func (b *Bus) Publish(e Event) {
+	if b == nil {
+		return
+	}
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, fn := range b.subscribers {
+		fn(e)
+	}
+}
@@ -0,0 +1,87 @@
+package events
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+// reservedNets are the ranges besides the loopback, private, link-local,
+// multicast and unspecified addresses which webhooks must not reach.
+var reservedNets = parseCIDRs(
+	"0.0.0.0/8",     // this network
+	"100.64.0.0/10", // shared address space
+	"192.0.0.0/24",  // IETF protocol assignments
+	"198.18.0.0/15", // benchmarking
+	"240.0.0.0/4",   // reserved, including broadcast
+	"64:ff9b::/96",  // NAT64, may translate to any IPv4 address
+	"2002::/16",     // 6to4, may embed any IPv4 address
+)
+
+func parseCIDRs(cidrs ...string) []*net.IPNet {
+	nets := make([]*net.IPNet, len(cidrs))
+	for i, cidr := range cidrs {
+		_, n, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		nets[i] = n
+	}
+	return nets
+}
+
+// PublicAddress reports whether the IP is reachable on the internet, as
+// opposed to the loopback, private, link-local and other special ranges
+// which would let a webhook reach the server's own network.
+func PublicAddress(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return false
+	}
+	for _, n := range reservedNets {
+		if n.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// dialPublicOnly refuses connections to addresses which are not public. It
+// runs after the name was resolved, so a host name cannot resolve to a
+// private address between checking and connecting.
+func dialPublicOnly(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !PublicAddress(ip) {
+		return fmt.Errorf("webhook target %s is not a public address", host)
+	}
+	return nil
+}
+
+// NewWebhookClient returns the client posting webhook deliveries. It connects
+// only to public addresses unless allowPrivate is set, e.g. for receivers in
+// the same network, and never follows redirects, which the receiver could
+// use to point the request elsewhere.
+func NewWebhookClient(timeout time.Duration, allowPrivate bool) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout}
+	if !allowPrivate {
+		dialer.Control = dialPublicOnly
+	}
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: timeout,
+			MaxIdleConns:        10,
+			IdleConnTimeout:     90 * time.Second,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
@@ -0,0 +1,70 @@
+package events
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPublicAddress(t *testing.T) {
+	tests := []struct {
+		ip     string
+		public bool
+	}{
+		{"93.184.216.34", true},
+		{"2606:2800:220:1:248:1893:25c8:1946", true},
+		{"127.0.0.1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"100.64.0.1", false},
+		{"0.0.0.0", false},
+		{"::1", false},
+		{"fd00::1", false},
+		{"fe80::1", false},
+		{"::ffff:127.0.0.1", false},
+		{"64:ff9b::a01:203", false},
+	}
+	for _, tt := range tests {
+		if got := PublicAddress(net.ParseIP(tt.ip)); got != tt.public {
+			t.Errorf("PublicAddress(%s) = %v, want %v", tt.ip, got, tt.public)
+		}
+	}
+}
+
+func TestWebhookClientRefusesPrivateTargets(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer receiver.Close()
+
+	_, err := NewWebhookClient(time.Second, false).Post(receiver.URL, "application/json", nil)
+	if err == nil || !strings.Contains(err.Error(), "not a public address") {
+		t.Errorf("expected the loopback receiver to be refused, got %v", err)
+	}
+
+	resp, err := NewWebhookClient(time.Second, true).Post(receiver.URL, "application/json", nil)
+	if err != nil {
+		t.Fatalf("didn't expect error with private targets allowed, got %v", err)
+	}
+	resp.Body.Close()
+}
+
+func TestWebhookClientRefusesRedirects(t *testing.T) {
+	followed := false
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { followed = true }))
+	defer target.Close()
+	receiver := httptest.NewServer(http.RedirectHandler(target.URL, http.StatusTemporaryRedirect))
+	defer receiver.Close()
+
+	resp, err := NewWebhookClient(time.Second, true).Post(receiver.URL, "application/json", nil)
+	if err != nil {
+		t.Fatalf("didn't expect error, got %v", err)
+	}
+	resp.Body.Close()
+	if followed || resp.StatusCode != http.StatusTemporaryRedirect {
+		t.Errorf("expected the redirect to be returned, got status %d, followed %v", resp.StatusCode, followed)
+	}
+}
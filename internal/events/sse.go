@@ -0,0 +1,54 @@
+package events
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Subscribed is the type of the first event of a stream, sent once the
+// server delivers the changes to the client. It has no secret.
+const Subscribed = "subscribed"
+
+// WriteSSE writes the event as a Server-Sent Event named after its type, with
+// the JSON of the event as data.
+func WriteSSE(w io.Writer, e Event) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.Type, data)
+	return err
+}
+
+// ReadSSE calls fn with every event of the Server-Sent Events stream until it
+// ends or fn fails. Comments, such as keep-alives, are skipped.
+func ReadSSE(r io.Reader, fn func(Event) error) error {
+	scanner := bufio.NewScanner(r)
+	var data strings.Builder
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if data.Len() == 0 {
+				continue
+			}
+			var e Event
+			if err := json.Unmarshal([]byte(data.String()), &e); err != nil {
+				return fmt.Errorf("invalid event: %s", err)
+			}
+			data.Reset()
+			if err := fn(e); err != nil {
+				return err
+			}
+		case strings.HasPrefix(line, "data:"):
+			if data.Len() > 0 {
+				data.WriteByte('\n')
+			}
+			data.WriteString(strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,49 @@
+package events
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	wh "passKeeper/internal/models/webhook"
+)
+
+func TestSSERoundTrip(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	sent := []Event{
+		{Type: wh.EventUpdated, SecretID: 7, ActorID: 2, OccurredAt: now},
+		{Type: wh.EventExpired, SecretID: 9, OccurredAt: now},
+	}
+	var buf bytes.Buffer
+	buf.WriteString(": watching\n\n")
+	for _, e := range sent {
+		if err := WriteSSE(&buf, e); err != nil {
+			t.Fatalf("didn't expect error, got %v", err)
+		}
+		buf.WriteString(": ping\n\n")
+	}
+
+	var received []Event
+	err := ReadSSE(&buf, func(e Event) error {
+		received = append(received, e)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("didn't expect error, got %v", err)
+	}
+	if len(received) != len(sent) {
+		t.Fatalf("expected %d events, got %+v", len(sent), received)
+	}
+	for i := range sent {
+		if received[i] != sent[i] {
+			t.Errorf("expected %+v, got %+v", sent[i], received[i])
+		}
+	}
+}
+
+func TestReadSSERejectsInvalidData(t *testing.T) {
+	err := ReadSSE(bytes.NewBufferString("event: updated\ndata: {\n\n"), func(Event) error { return nil })
+	if err == nil {
+		t.Error("expected an error for invalid data")
+	}
+}
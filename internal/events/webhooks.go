@@ -0,0 +1,185 @@
+package events
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	wh "passKeeper/internal/models/webhook"
+)
+
+// WebhookStore keeps the webhooks and their delivery log.
+type WebhookStore interface {
+	GetEventScope(secretID uint) (userID, orgID uint, err error)
+	GetMatchingWebhooks(userID, orgID uint) ([]wh.Webhook, error)
+	GetWebhook(id uint) (*wh.Webhook, error)
+	CreateDelivery(d *wh.Delivery) error
+	ClaimDelivery(id uint, now, until time.Time) (bool, error)
+	SaveDelivery(d *wh.Delivery) error
+	GetDueDeliveries(now time.Time, limit int) ([]wh.Delivery, error)
+}
+
+// Defaults of the Dispatcher.
+const (
+	DefaultMaxAttempts = 6
+	DefaultBackoff     = 30 * time.Second
+	// deliveryLease is how long an attempt may take before the delivery is
+	// retried by another attempt.
+	deliveryLease = time.Minute
+	// retryBatch is the number of due deliveries retried at a time.
+	retryBatch = 100
+)
+
+// Dispatcher posts events to the webhooks subscribed to them. Every delivery
+// is logged before its first attempt and retried with exponential backoff,
+// starting at Backoff, until it succeeds or MaxAttempts were made.
+type Dispatcher struct {
+	Repo        WebhookStore
+	Client      *http.Client
+	MaxAttempts int
+	Backoff     time.Duration
+}
+
+// NewDispatcher returns a dispatcher with the default retries, posting with
+// the client.
+func NewDispatcher(repo WebhookStore, client *http.Client) *Dispatcher {
+	return &Dispatcher{Repo: repo, Client: client, MaxAttempts: DefaultMaxAttempts, Backoff: DefaultBackoff}
+}
+
+// Handle delivers the event in the background, it is subscribed to the bus.
+func (d *Dispatcher) Handle(e Event) {
+	go func() {
+		deliveries, err := d.Enqueue(e)
+		if err != nil {
+			log.Printf("cannot queue webhook deliveries of secret %d - %s", e.SecretID, err)
+			return
+		}
+		for i := range deliveries {
+			d.Attempt(&deliveries[i], time.Now())
+		}
+	}()
+}
+
+// Enqueue logs a pending delivery of the event to every webhook subscribed to
+// it and returns the deliveries.
+func (d *Dispatcher) Enqueue(e Event) ([]wh.Delivery, error) {
+	userID, orgID, err := d.Repo.GetEventScope(e.SecretID)
+	if err != nil {
+		return nil, err
+	}
+	hooks, err := d.Repo.GetMatchingWebhooks(userID, orgID)
+	if err != nil {
+		return nil, err
+	}
+	var deliveries []wh.Delivery
+	for _, hook := range hooks {
+		if !hook.Subscribes(e.Type) {
+			continue
+		}
+		payload, err := json.Marshal(wh.Payload{Webhook: hook.ID, Event: e.Type, SecretID: e.SecretID,
+			ActorID: e.ActorID, OccurredAt: e.OccurredAt.UTC()})
+		if err != nil {
+			return deliveries, err
+		}
+		delivery := wh.Delivery{WebhookID: hook.ID, Event: e.Type, SecretID: e.SecretID, Payload: string(payload),
+			State: wh.DeliveryPending, NextAttemptAt: e.OccurredAt}
+		if err := d.Repo.CreateDelivery(&delivery); err != nil {
+			return deliveries, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries, nil
+}
+
+// RetryDue attempts the pending deliveries whose next attempt is due at now
+// and returns their number.
+func (d *Dispatcher) RetryDue(now time.Time) (int, error) {
+	due, err := d.Repo.GetDueDeliveries(now, retryBatch)
+	if err != nil {
+		return 0, err
+	}
+	for i := range due {
+		d.Attempt(&due[i], now)
+	}
+	return len(due), nil
+}
+
+// Start retries due deliveries every interval.
+func (d *Dispatcher) Start(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if _, err := d.RetryDue(time.Now()); err != nil {
+				log.Printf("cannot retry webhook deliveries: %s", err)
+			}
+		}
+	}()
+}
+
+// Attempt posts the delivery unless another attempt claimed it, and logs the
+// outcome.
+func (d *Dispatcher) Attempt(delivery *wh.Delivery, now time.Time) {
+	claimed, err := d.Repo.ClaimDelivery(delivery.ID, now, now.Add(deliveryLease))
+	if err != nil {
+		log.Printf("cannot claim webhook delivery %d - %s", delivery.ID, err)
+		return
+	}
+	if !claimed {
+		return
+	}
+	delivery.Attempts++
+	hook, err := d.Repo.GetWebhook(delivery.WebhookID)
+	if err != nil {
+		delivery.State = wh.DeliveryFailed
+		delivery.LastError = "webhook was removed"
+	} else {
+		delivery.LastStatus, err = d.post(hook, delivery, now)
+		switch {
+		case err == nil:
+			delivery.State = wh.DeliveryDelivered
+			delivery.LastError = ""
+		case delivery.Attempts >= d.MaxAttempts:
+			delivery.State = wh.DeliveryFailed
+			delivery.LastError = err.Error()
+		default:
+			delivery.LastError = err.Error()
+			delivery.NextAttemptAt = now.Add(d.Backoff << (delivery.Attempts - 1))
+		}
+	}
+	if err := d.Repo.SaveDelivery(delivery); err != nil {
+		log.Printf("cannot log webhook delivery %d - %s", delivery.ID, err)
+	}
+}
+
+// post sends the delivery and returns the status of the response, which must
+// be 2xx.
+func (d *Dispatcher) post(hook *wh.Webhook, delivery *wh.Delivery, now time.Time) (int, error) {
+	body := []byte(delivery.Payload)
+	req, err := http.NewRequest("POST", hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := now.Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "passKeeper-webhook")
+	req.Header.Set(wh.HeaderEvent, delivery.Event)
+	req.Header.Set(wh.HeaderDelivery, strconv.FormatUint(uint64(delivery.ID), 10))
+	req.Header.Set(wh.HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(wh.HeaderSignature, wh.Sign(hook.Secret, timestamp, body))
+	resp, err := d.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("receiver responded with %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,194 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	wh "passKeeper/internal/models/webhook"
+)
+
+// memoryStore keeps webhooks and deliveries in memory. All secrets belong to
+// user 1.
+type memoryStore struct {
+	mu         sync.Mutex
+	hooks      []wh.Webhook
+	deliveries map[uint]*wh.Delivery
+}
+
+func (m *memoryStore) GetEventScope(secretID uint) (uint, uint, error) {
+	return 1, 0, nil
+}
+
+func (m *memoryStore) GetMatchingWebhooks(userID, orgID uint) ([]wh.Webhook, error) {
+	var hooks []wh.Webhook
+	for _, h := range m.hooks {
+		if h.OrgID == 0 && h.UserID == userID {
+			hooks = append(hooks, h)
+		}
+	}
+	return hooks, nil
+}
+
+func (m *memoryStore) GetWebhook(id uint) (*wh.Webhook, error) {
+	for _, h := range m.hooks {
+		if h.ID == id {
+			return &h, nil
+		}
+	}
+	return nil, fmt.Errorf("webhook %d not found", id)
+}
+
+func (m *memoryStore) CreateDelivery(d *wh.Delivery) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	d.ID = uint(len(m.deliveries) + 1)
+	stored := *d
+	m.deliveries[d.ID] = &stored
+	return nil
+}
+
+func (m *memoryStore) ClaimDelivery(id uint, now, until time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	d := m.deliveries[id]
+	if d.State != wh.DeliveryPending || d.NextAttemptAt.After(now) {
+		return false, nil
+	}
+	d.NextAttemptAt = until
+	return true, nil
+}
+
+func (m *memoryStore) SaveDelivery(d *wh.Delivery) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stored := *d
+	m.deliveries[d.ID] = &stored
+	return nil
+}
+
+func (m *memoryStore) GetDueDeliveries(now time.Time, limit int) ([]wh.Delivery, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var due []wh.Delivery
+	for _, d := range m.deliveries {
+		if d.State == wh.DeliveryPending && !d.NextAttemptAt.After(now) {
+			due = append(due, *d)
+		}
+	}
+	return due, nil
+}
+
+func TestDispatcherDeliversSignedEvents(t *testing.T) {
+	var received []wh.Payload
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		timestamp, _ := strconv.ParseInt(r.Header.Get(wh.HeaderTimestamp), 10, 64)
+		if !wh.VerifySignature("s3cret", timestamp, body, r.Header.Get(wh.HeaderSignature)) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var payload wh.Payload
+		json.Unmarshal(body, &payload)
+		received = append(received, payload)
+	}))
+	defer receiver.Close()
+
+	store := &memoryStore{deliveries: map[uint]*wh.Delivery{}, hooks: []wh.Webhook{
+		{ID: 1, UserID: 1, URL: receiver.URL, Events: "updated,deleted", Secret: "s3cret"},
+		{ID: 2, UserID: 1, URL: receiver.URL, Events: "created", Secret: "s3cret"},
+		{ID: 3, UserID: 2, URL: receiver.URL, Events: "updated", Secret: "s3cret"},
+	}}
+	d := NewDispatcher(store, receiver.Client())
+	now := time.Now()
+	deliveries, err := d.Enqueue(Event{Type: wh.EventUpdated, SecretID: 7, ActorID: 1, OccurredAt: now})
+	if err != nil {
+		t.Fatalf("didn't expect error, got %v", err)
+	}
+	if len(deliveries) != 1 || deliveries[0].WebhookID != 1 {
+		t.Fatalf("expected one delivery to webhook 1, got %+v", deliveries)
+	}
+	d.Attempt(&deliveries[0], now)
+
+	if len(received) != 1 || received[0].SecretID != 7 || received[0].Event != wh.EventUpdated {
+		t.Errorf("unexpected payloads %+v", received)
+	}
+	if got := store.deliveries[1]; got.State != wh.DeliveryDelivered || got.Attempts != 1 || got.LastStatus != 200 {
+		t.Errorf("unexpected delivery log %+v", got)
+	}
+}
+
+func TestDispatcherRetriesWithBackoff(t *testing.T) {
+	failures := 2
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failures > 0 {
+			failures--
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer receiver.Close()
+
+	store := &memoryStore{deliveries: map[uint]*wh.Delivery{}, hooks: []wh.Webhook{
+		{ID: 1, UserID: 1, URL: receiver.URL, Events: "expired", Secret: "s3cret"},
+	}}
+	d := NewDispatcher(store, receiver.Client())
+	now := time.Now()
+	deliveries, _ := d.Enqueue(Event{Type: wh.EventExpired, SecretID: 7, OccurredAt: now})
+	d.Attempt(&deliveries[0], now)
+
+	got := store.deliveries[1]
+	if got.State != wh.DeliveryPending || got.LastStatus != 503 || !got.NextAttemptAt.Equal(now.Add(d.Backoff)) {
+		t.Fatalf("expected a retry after %v, got %+v", d.Backoff, got)
+	}
+	if n, _ := d.RetryDue(now.Add(d.Backoff - time.Second)); n != 0 {
+		t.Errorf("expected no retry before the backoff passed, got %d", n)
+	}
+	d.RetryDue(now.Add(d.Backoff))
+	if got := store.deliveries[1]; !got.NextAttemptAt.Equal(now.Add(d.Backoff).Add(2 * d.Backoff)) {
+		t.Fatalf("expected the backoff to double, got %+v", got)
+	}
+	d.RetryDue(now.Add(3 * d.Backoff))
+	if got := store.deliveries[1]; got.State != wh.DeliveryDelivered || got.Attempts != 3 {
+		t.Errorf("expected delivery on the third attempt, got %+v", got)
+	}
+}
+
+func TestDispatcherGivesUp(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer receiver.Close()
+
+	store := &memoryStore{deliveries: map[uint]*wh.Delivery{}, hooks: []wh.Webhook{
+		{ID: 1, UserID: 1, URL: receiver.URL, Events: "deleted", Secret: "s3cret"},
+	}}
+	d := NewDispatcher(store, receiver.Client())
+	d.MaxAttempts = 2
+	now := time.Now()
+	deliveries, _ := d.Enqueue(Event{Type: wh.EventDeleted, SecretID: 7, OccurredAt: now})
+	d.Attempt(&deliveries[0], now)
+	d.RetryDue(now.Add(time.Hour))
+	if got := store.deliveries[1]; got.State != wh.DeliveryFailed || got.Attempts != 2 || got.LastError == "" {
+		t.Errorf("expected a failed delivery after 2 attempts, got %+v", got)
+	}
+}
+
+func TestBus(t *testing.T) {
+	bus := NewBus()
+	var got []Event
+	unsubscribe := bus.Subscribe(func(e Event) { got = append(got, e) })
+	bus.Publish(Event{Type: wh.EventCreated, SecretID: 1})
+	unsubscribe()
+	bus.Publish(Event{Type: wh.EventCreated, SecretID: 2})
+	if len(got) != 1 || got[0].SecretID != 1 {
+		t.Errorf("unexpected events %+v", got)
+	}
+	var nilBus *Bus
+	nilBus.Publish(Event{Type: wh.EventCreated})
+}
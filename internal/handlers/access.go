@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	auth "passKeeper/internal/models/auth"
+	db "passKeeper/internal/models/database"
+	sec "passKeeper/internal/models/secret"
+	server "passKeeper/internal/models/server"
+	"time"
+)
+
+// recordAccess counts the read of the secret and updates the returned secret
+// accordingly. Failures are only logged, the secret is served regardless.
+func recordAccess(repo db.SecretRepository, secret *sec.Secret) {
+	now := time.Now()
+	if err := repo.RecordAccess(secret, now); err != nil {
+		log.Printf("cannot record access to secret %d - %s", secret.ID, err)
+		return
+	}
+	secret.LastAccessedAt = &now
+	secret.AccessCount++
+}
+
+// GetStaleSecrets returns the secrets which have not been read for the
+// duration given by the unused_for query parameter.
+func (sh *secretHandler) GetStaleSecrets(w http.ResponseWriter, r *http.Request) {
+	user, ok := auth.GetUserFromContext(r.Context())
+	if !ok {
+		server.RespondWithMessage(w, 500, "Could not get user from context")
+		return
+	}
+	unusedFor, err := time.ParseDuration(r.URL.Query().Get("unused_for"))
+	if err != nil || unusedFor < 0 {
+		server.RespondWithMessage(w, 400, "Bad request. Invalid unused_for duration.")
+		return
+	}
+	secrets, err := sh.Repo.GetStaleSecrets(user, time.Now().Add(-unusedFor))
+	if err != nil {
+		server.RespondWithMessage(w, 500, "Could not get secrets")
+		return
+	}
+	server.RespondWithMessage(w, 200, secrets)
+}
@@ -4,26 +4,29 @@ import (
 	"encoding/json"
 	"net/http"
 	acc "passKeeper/internal/models/account"
+	audit "passKeeper/internal/models/audit"
 	auth "passKeeper/internal/models/auth"
 	db "passKeeper/internal/models/database"
 	server "passKeeper/internal/models/server"
+	"passKeeper/internal/server/controllers"
 
 	"github.com/go-chi/chi"
 )
 
 type accountHandler struct {
 	Repo        db.AccountRepository
+	Audit       db.AuditRepository
 	jwtSettings auth.JWTSettings
 }
 
-func NewAccountHandler(repo db.AccountRepository, jwtSettings auth.JWTSettings) *accountHandler {
-	return &accountHandler{Repo: repo, jwtSettings: jwtSettings}
+func NewAccountHandler(repo db.AccountRepository, auditRepo db.AuditRepository, jwtSettings auth.JWTSettings) *accountHandler {
+	return &accountHandler{Repo: repo, Audit: auditRepo, jwtSettings: jwtSettings}
 }
 
 func (ah *accountHandler) Route() *chi.Mux {
 	router := chi.NewRouter()
 	router.Post("/register", ah.CreateAccount)
-	router.Post("/login", ah.Authenticate)
+	router.With(controllers.AuditMiddleware(ah.Audit, nil, audit.ActionLogin)).Post("/login", ah.Authenticate)
 	return router
 }
 
@@ -46,6 +49,10 @@ func (ah *accountHandler) Authenticate(w http.ResponseWriter, r *http.Request) {
 	if err != nil || acc.Login == "" || acc.Password == "" {
 		server.RespondWithMessage(w, 400, "Invalid request")
 	}
+	// Failed logins are recorded for the account they tried to log in to.
+	if account, err := ah.Repo.GetAccountByLogin(acc.Login); err == nil {
+		audit.FromContext(r.Context()).ActorID = account.ID
+	}
 	resp := ah.Repo.LoginAccount(acc.Login, acc.Password, ah.jwtSettings)
 	w.Header().Add("Authorization", resp.Message.(string))
 	server.RespondWithMessage(w, resp.ServerCode, resp.Message)
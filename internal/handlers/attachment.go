@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	sec "passKeeper/internal/models/secret"
+	server "passKeeper/internal/models/server"
+
+	"github.com/go-chi/chi"
+)
+
+func (sh *secretHandler) AddAttachment(w http.ResponseWriter, r *http.Request) {
+	secret, ok := sh.accessibleSecret(w, r, accessWrite)
+	if !ok {
+		return
+	}
+
+	var req sec.Attachment
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		server.RespondWithMessage(w, 400, "Invalid request")
+		return
+	}
+	attachment, err := sec.NewAttachment(secret.ID, req.Filename, req.MimeType, req.Data)
+	if err != nil {
+		server.RespondWithMessage(w, 400, err.Error())
+		return
+	}
+	if req.Checksum != "" && req.Checksum != attachment.Checksum {
+		server.RespondWithMessage(w, 400, "Checksum mismatch")
+		return
+	}
+	if existing, err := sh.Repo.GetAttachment(secret.ID, attachment.Filename); err == nil && existing != nil {
+		server.RespondWithMessage(w, 409, "Attachment with this name already exists")
+		return
+	}
+
+	if err := sh.Repo.SaveAttachment(&attachment); err != nil {
+		log.Printf("cannot save attachment for secret %d - %s", secret.ID, err)
+		server.RespondWithMessage(w, 500, "Could not save attachment")
+		return
+	}
+	attachment.Data = nil
+	server.RespondWithMessage(w, 200, attachment)
+}
+
+func (sh *secretHandler) GetAttachments(w http.ResponseWriter, r *http.Request) {
+	secret, ok := sh.accessibleSecret(w, r, accessRead)
+	if !ok || !servable(w, secret) {
+		return
+	}
+	attachments, err := sh.Repo.GetAttachments(secret.ID)
+	if err != nil {
+		server.RespondWithMessage(w, 500, "Could not get attachments")
+		return
+	}
+	server.RespondWithMessage(w, 200, attachments)
+}
+
+func (sh *secretHandler) GetAttachment(w http.ResponseWriter, r *http.Request) {
+	secret, ok := sh.accessibleSecret(w, r, accessRead)
+	if !ok || !servable(w, secret) {
+		return
+	}
+	attachment, err := sh.Repo.GetAttachment(secret.ID, chi.URLParam(r, "name"))
+	if err != nil {
+		server.RespondWithMessage(w, 404, "Attachment not found")
+		return
+	}
+	recordAccess(sh.Repo, secret)
+	server.RespondWithMessage(w, 200, attachment)
+}
+
+func (sh *secretHandler) DeleteAttachment(w http.ResponseWriter, r *http.Request) {
+	secret, ok := sh.accessibleSecret(w, r, accessWrite)
+	if !ok {
+		return
+	}
+	if err := sh.Repo.DeleteAttachment(secret.ID, chi.URLParam(r, "name")); err != nil {
+		server.RespondWithMessage(w, 404, "Attachment not found")
+		return
+	}
+	server.RespondWithMessage(w, 200, nil)
+}
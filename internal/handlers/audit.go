@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"log"
+	"net/http"
+	audit "passKeeper/internal/models/audit"
+	auth "passKeeper/internal/models/auth"
+	db "passKeeper/internal/models/database"
+	server "passKeeper/internal/models/server"
+	"passKeeper/internal/server/controllers"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi"
+)
+
+type auditHandler struct {
+	Repo        db.AuditRepository
+	exportToken string
+	jwtSettings auth.JWTSettings
+}
+
+func NewAuditHandler(repo db.AuditRepository, exportToken string, jwtConf auth.JWTSettings) *auditHandler {
+	return &auditHandler{
+		Repo:        repo,
+		exportToken: exportToken,
+		jwtSettings: jwtConf,
+	}
+}
+
+// Route serves the audit events of accounts, and the whole log to auditors
+// holding the export token.
+func (ah *auditHandler) Route() *chi.Mux {
+	router := chi.NewRouter()
+	router.Group(func(r chi.Router) {
+		r.Use(controllers.JwtAuthenticationMiddleware(ah.jwtSettings))
+		r.Get("/", ah.GetEvents)
+	})
+	router.Get("/export", ah.Export)
+	return router
+}
+
+// GetEvents returns the audit events of the caller, newest first: its own
+// actions and the actions of others on its secrets. The query parameters
+// since (RFC 3339), secret and limit narrow them down.
+func (ah *auditHandler) GetEvents(w http.ResponseWriter, r *http.Request) {
+	user, ok := auth.GetUserFromContext(r.Context())
+	if !ok {
+		server.RespondWithMessage(w, 500, "Could not get user from context")
+		return
+	}
+	query := r.URL.Query()
+	q := audit.Query{Limit: audit.DefaultQueryLimit}
+	if v := query.Get("since"); v != "" {
+		since, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			server.RespondWithMessage(w, 400, "Bad request. since must be an RFC 3339 time.")
+			return
+		}
+		q.Since = &since
+	}
+	if v := query.Get("secret"); v != "" {
+		id, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			server.RespondWithMessage(w, 400, "Bad request. Invalid secret.")
+			return
+		}
+		q.SecretID = uint(id)
+	}
+	if v := query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > audit.MaxQueryLimit {
+			server.RespondWithMessage(w, 400, "Bad request. Invalid limit.")
+			return
+		}
+		q.Limit = limit
+	}
+	events, err := ah.Repo.GetAuditEvents(user, q)
+	if err != nil {
+		server.RespondWithMessage(w, 500, "Could not get audit events")
+		return
+	}
+	server.RespondWithMessage(w, 200, events)
+}
+
+// Export writes the whole audit log with its checkpoints as JSON lines, see
+// audit.Verify. The X-Audit-Token header must hold the configured export
+// token, without one the export is disabled.
+func (ah *auditHandler) Export(w http.ResponseWriter, r *http.Request) {
+	token := r.Header.Get("X-Audit-Token")
+	if ah.exportToken == "" || subtle.ConstantTimeCompare([]byte(token), []byte(ah.exportToken)) != 1 {
+		server.RespondWithMessage(w, 404, "Not found")
+		return
+	}
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	enc := json.NewEncoder(w)
+	err := ah.Repo.ExportAudit(func(rec audit.ExportRecord) error {
+		return enc.Encode(rec)
+	})
+	if err != nil {
+		// The status is sent already, the export just ends early.
+		log.Printf("cannot export audit log - %s", err)
+	}
+}
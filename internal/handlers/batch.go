@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	audit "passKeeper/internal/models/audit"
+	auth "passKeeper/internal/models/auth"
+	db "passKeeper/internal/models/database"
+	sec "passKeeper/internal/models/secret"
+	server "passKeeper/internal/models/server"
+)
+
+// maxBatchSize is the largest number of operations accepted in one batch.
+const maxBatchSize = 500
+
+// errBatchFailed rolls back a batch after one of its operations failed.
+var errBatchFailed = errors.New("batch operation failed")
+
+// ApplyBatch applies the create, update and delete operations of the request
+// in one transaction and reports the result of every operation. The batch
+// stops and is rolled back at the first operation which fails.
+func (sh *secretHandler) ApplyBatch(w http.ResponseWriter, r *http.Request) {
+	user, ok := auth.GetUserFromContext(r.Context())
+	if !ok {
+		server.RespondWithMessage(w, 500, "Could not get user from context")
+		return
+	}
+	var req sec.BatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		server.RespondWithMessage(w, 400, "Invalid request")
+		return
+	}
+	if len(req.Operations) == 0 || len(req.Operations) > maxBatchSize {
+		server.RespondWithMessage(w, 400, fmt.Sprintf("A batch must have between 1 and %d operations", maxBatchSize))
+		return
+	}
+
+	results := make([]sec.BatchResult, len(req.Operations))
+	for i, op := range req.Operations {
+		results[i] = sec.BatchResult{Op: op.Op, Error: "not attempted"}
+	}
+	err := sh.Repo.Transaction(func(repo db.SecretRepository) error {
+		for i, op := range req.Operations {
+			results[i] = applyOperation(repo, user, op)
+			if results[i].Status != 200 {
+				return errBatchFailed
+			}
+		}
+		return nil
+	})
+	auditBatch(audit.FromContext(r.Context()), results, err == nil)
+	if err != nil && err != errBatchFailed {
+		log.Printf("cannot apply batch - %s", err)
+		server.RespondWithMessage(w, 500, "Could not apply batch")
+		return
+	}
+	server.RespondWithMessage(w, 200, sec.BatchResponse{Committed: err == nil, Results: results})
+}
+
+// batchAuditActions maps the batch operations to the actions in the audit log.
+var batchAuditActions = map[string]string{
+	sec.OpCreate: audit.ActionCreate,
+	sec.OpUpdate: audit.ActionUpdate,
+	sec.OpDelete: audit.ActionDelete,
+}
+
+// auditBatch adds an audit event for every attempted operation of a batch.
+// Operations which succeeded in a batch which was rolled back failed.
+func auditBatch(trail *audit.Trail, results []sec.BatchResult, committed bool) {
+	for _, res := range results {
+		action, ok := batchAuditActions[res.Op]
+		if !ok || res.Status == 0 {
+			continue
+		}
+		result := audit.ResultOf(res.Status)
+		if !committed && result == audit.ResultSuccess {
+			result = audit.ResultFailure
+		}
+		trail.Add(action, res.ID, res.OwnerID, result)
+	}
+}
+
+func applyOperation(repo db.SecretRepository, user uint, op sec.BatchOperation) sec.BatchResult {
+	result := sec.BatchResult{Op: op.Op, ID: op.ID}
+	fail := func(status int, err error) sec.BatchResult {
+		result.Status = status
+		result.Error = err.Error()
+		return result
+	}
+
+	switch op.Op {
+	case sec.OpCreate:
+		if op.Secret == nil {
+			return fail(400, fmt.Errorf("secret is missing"))
+		}
+		req := *op.Secret
+		req.ID = 0
+		secret, status, err := saveSecretRequest(repo, user, req)
+		if err != nil {
+			return fail(status, err)
+		}
+		result.ID, result.OwnerID = secret.ID, secret.UserID
+	case sec.OpUpdate:
+		if op.Secret == nil {
+			return fail(400, fmt.Errorf("secret is missing"))
+		}
+		existing, status, err := addressedSecret(repo, user, op, accessWrite)
+		if err != nil {
+			return fail(status, err)
+		}
+		result.ID, result.OwnerID = existing.ID, existing.UserID
+		req := *op.Secret
+		req.ID = existing.ID
+		if _, status, err := saveSecretRequest(repo, user, req); err != nil {
+			return fail(status, err)
+		}
+	case sec.OpDelete:
+		existing, status, err := addressedSecret(repo, user, op, accessOwner)
+		if err != nil {
+			return fail(status, err)
+		}
+		result.ID, result.OwnerID = existing.ID, existing.UserID
+		if err := repo.DeleteSecret(existing); err != nil {
+			log.Printf("cannot delete secret %d - %s", existing.ID, err)
+			return fail(500, fmt.Errorf("Could not delete secret"))
+		}
+	default:
+		return fail(400, fmt.Errorf("unknown operation %q, expected create, update or delete", op.Op))
+	}
+	result.Status = 200
+	return result
+}
+
+// addressedSecret loads the secret which an update or delete operation refers
+// to by ID or, without an ID, by path. The user must have the access level
+// which the same operation on a single secret requires: write access to update
+// it, owner access to delete it.
+func addressedSecret(repo db.SecretRepository, user uint, op sec.BatchOperation, access int) (*sec.Secret, int, error) {
+	var secret *sec.Secret
+	var err error
+	if op.ID == 0 {
+		if op.Path == "" {
+			return nil, 400, fmt.Errorf("id or path is required")
+		}
+		path, pathErr := sec.NormalizePath(op.Path)
+		if pathErr != nil {
+			return nil, 400, pathErr
+		}
+		secret, err = repo.GetSecretByPath(user, op.VaultID, path)
+	} else {
+		secret, err = repo.GetSecretByID(op.ID)
+	}
+	if err != nil || !canAccess(repo, secret, user, accessRead) {
+		return nil, 404, fmt.Errorf("Secret not found")
+	}
+	if !canAccess(repo, secret, user, access) {
+		return nil, 403, fmt.Errorf("Not allowed to change secret %d", secret.ID)
+	}
+	return secret, 200, nil
+}
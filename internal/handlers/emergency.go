@@ -0,0 +1,255 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	auth "passKeeper/internal/models/auth"
+	db "passKeeper/internal/models/database"
+	em "passKeeper/internal/models/emergency"
+	server "passKeeper/internal/models/server"
+	"passKeeper/internal/server/controllers"
+	"time"
+
+	"github.com/go-chi/chi"
+)
+
+type emergencyHandler struct {
+	Repo        db.EmergencyRepository
+	Accounts    db.AccountRepository
+	jwtSettings auth.JWTSettings
+}
+
+func NewEmergencyHandler(repo db.EmergencyRepository, accounts db.AccountRepository, jwtConf auth.JWTSettings) *emergencyHandler {
+	return &emergencyHandler{
+		Repo:        repo,
+		Accounts:    accounts,
+		jwtSettings: jwtConf,
+	}
+}
+
+// Route serves owners under /contacts, addressing their emergency contacts by
+// login, and contacts under /owners, addressing the accounts which trust them
+// by login.
+func (eh *emergencyHandler) Route() *chi.Mux {
+	router := chi.NewRouter()
+	router.Use(controllers.JwtAuthenticationMiddleware(eh.jwtSettings))
+	router.Get("/contacts", eh.GetContacts)
+	router.Put("/contacts/{login}", eh.SetContact)
+	router.Delete("/contacts/{login}", eh.RemoveContact)
+	router.Get("/contacts/{login}/events", eh.GetContactEvents)
+	router.Post("/contacts/{login}/{action}", eh.DecideRequest)
+	router.Get("/owners", eh.GetOwners)
+	router.Get("/owners/{login}/events", eh.GetOwnerEvents)
+	router.Get("/owners/{login}/secrets", eh.GetOwnerSecrets)
+	router.Post("/owners/{login}/{action}", eh.RequestAccess)
+	return router
+}
+
+func (eh *emergencyHandler) GetContacts(w http.ResponseWriter, r *http.Request) {
+	user, ok := auth.GetUserFromContext(r.Context())
+	if !ok {
+		server.RespondWithMessage(w, 500, "Could not get user from context")
+		return
+	}
+	contacts, err := eh.Repo.GetEmergencyContacts(user)
+	if err != nil {
+		server.RespondWithMessage(w, 500, "Could not get emergency contacts")
+		return
+	}
+	server.RespondWithMessage(w, 200, contacts)
+}
+
+// SetContact designates the account {login} as an emergency contact of the
+// caller or changes its waiting period. A changed waiting period applies to
+// a pending request as well.
+func (eh *emergencyHandler) SetContact(w http.ResponseWriter, r *http.Request) {
+	user, ok := auth.GetUserFromContext(r.Context())
+	if !ok {
+		server.RespondWithMessage(w, 500, "Could not get user from context")
+		return
+	}
+	var req em.ContactRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		server.RespondWithMessage(w, 400, "Invalid request")
+		return
+	}
+	if err := req.Validate(); err != nil {
+		server.RespondWithMessage(w, 400, err.Error())
+		return
+	}
+	account, err := eh.Accounts.GetAccountByLogin(chi.URLParam(r, "login"))
+	if err != nil {
+		server.RespondWithMessage(w, 404, "User not found")
+		return
+	}
+	if account.ID == user {
+		server.RespondWithMessage(w, 400, "Cannot be your own emergency contact")
+		return
+	}
+	contact, err := eh.Repo.GetEmergencyContact(user, account.ID)
+	if err == nil {
+		err = eh.Repo.SetEmergencyWait(contact, req.WaitHours)
+		if err == nil {
+			// Reload the state, a transition may have happened meanwhile.
+			contact, err = eh.Repo.GetEmergencyContact(user, account.ID)
+		}
+	} else {
+		contact = &em.EmergencyContact{OwnerID: user, ContactID: account.ID, State: em.StateIdle, WaitHours: req.WaitHours}
+		err = eh.Repo.CreateEmergencyContact(contact)
+	}
+	if err != nil {
+		log.Printf("cannot save emergency contact %d of user %d - %s", account.ID, user, err)
+		server.RespondWithMessage(w, 500, "Could not save emergency contact")
+		return
+	}
+	contact.Login = account.Login
+	server.RespondWithMessage(w, 200, contact)
+}
+
+// RemoveContact removes the emergency contact {login}, ending any access it
+// was granted.
+func (eh *emergencyHandler) RemoveContact(w http.ResponseWriter, r *http.Request) {
+	contact, _, ok := eh.relation(w, r, true)
+	if !ok {
+		return
+	}
+	if err := eh.Repo.DeleteEmergencyContact(contact); err != nil {
+		log.Printf("cannot delete emergency contact %d - %s", contact.ID, err)
+		server.RespondWithMessage(w, 500, "Could not remove emergency contact")
+		return
+	}
+	server.RespondWithMessage(w, 200, nil)
+}
+
+// DecideRequest approves or rejects the pending request of the emergency
+// contact {login}, or revokes the access it was granted.
+func (eh *emergencyHandler) DecideRequest(w http.ResponseWriter, r *http.Request) {
+	action := chi.URLParam(r, "action")
+	if !em.OwnerAction(action) {
+		server.RespondWithMessage(w, 404, "Unknown action")
+		return
+	}
+	contact, user, ok := eh.relation(w, r, true)
+	if !ok {
+		return
+	}
+	eh.transition(w, contact, action, user)
+}
+
+func (eh *emergencyHandler) GetOwners(w http.ResponseWriter, r *http.Request) {
+	user, ok := auth.GetUserFromContext(r.Context())
+	if !ok {
+		server.RespondWithMessage(w, 500, "Could not get user from context")
+		return
+	}
+	owners, err := eh.Repo.GetEmergencyOwners(user)
+	if err != nil {
+		server.RespondWithMessage(w, 500, "Could not get emergency owners")
+		return
+	}
+	server.RespondWithMessage(w, 200, owners)
+}
+
+// RequestAccess requests emergency access to the secrets of the account
+// {login}, or cancels the pending request.
+func (eh *emergencyHandler) RequestAccess(w http.ResponseWriter, r *http.Request) {
+	action := chi.URLParam(r, "action")
+	if !em.ContactAction(action) {
+		server.RespondWithMessage(w, 404, "Unknown action")
+		return
+	}
+	contact, user, ok := eh.relation(w, r, false)
+	if !ok {
+		return
+	}
+	eh.transition(w, contact, action, user)
+}
+
+func (eh *emergencyHandler) GetContactEvents(w http.ResponseWriter, r *http.Request) {
+	if contact, _, ok := eh.relation(w, r, true); ok {
+		eh.respondWithEvents(w, contact)
+	}
+}
+
+func (eh *emergencyHandler) GetOwnerEvents(w http.ResponseWriter, r *http.Request) {
+	if contact, _, ok := eh.relation(w, r, false); ok {
+		eh.respondWithEvents(w, contact)
+	}
+}
+
+// GetOwnerSecrets lists the secrets of the account {login} without their
+// values once the caller was granted emergency access. The values are read
+// like shared secrets.
+func (eh *emergencyHandler) GetOwnerSecrets(w http.ResponseWriter, r *http.Request) {
+	contact, _, ok := eh.relation(w, r, false)
+	if !ok {
+		return
+	}
+	if contact.State != em.StateGranted {
+		server.RespondWithMessage(w, 403, fmt.Sprintf("Emergency access is %s", contact.State))
+		return
+	}
+	secrets, err := eh.Repo.GetEmergencySecrets(contact.OwnerID)
+	if err != nil {
+		server.RespondWithMessage(w, 500, "Could not get secrets")
+		return
+	}
+	server.RespondWithMessage(w, 200, secrets)
+}
+
+// relation loads the emergency contact between the caller and the account
+// {login}, with the caller as the owner or as the contact. On failure the
+// response is already written.
+func (eh *emergencyHandler) relation(w http.ResponseWriter, r *http.Request, asOwner bool) (*em.EmergencyContact, uint, bool) {
+	user, ok := auth.GetUserFromContext(r.Context())
+	if !ok {
+		server.RespondWithMessage(w, 500, "Could not get user from context")
+		return nil, 0, false
+	}
+	account, err := eh.Accounts.GetAccountByLogin(chi.URLParam(r, "login"))
+	if err != nil {
+		server.RespondWithMessage(w, 404, "User not found")
+		return nil, 0, false
+	}
+	ownerID, contactID := account.ID, user
+	if asOwner {
+		ownerID, contactID = user, account.ID
+	}
+	contact, err := eh.Repo.GetEmergencyContact(ownerID, contactID)
+	if err != nil {
+		server.RespondWithMessage(w, 404, "Emergency contact not found")
+		return nil, 0, false
+	}
+	contact.Login = account.Login
+	return contact, user, true
+}
+
+// transition applies the action of the user to the emergency contact and
+// responds with the contact in its new state.
+func (eh *emergencyHandler) transition(w http.ResponseWriter, contact *em.EmergencyContact, action string, user uint) {
+	if _, err := em.Transition(contact.State, action); err != nil {
+		server.RespondWithMessage(w, 409, err.Error())
+		return
+	}
+	if err := eh.Repo.TransitionEmergencyAccess(contact, action, user, time.Now()); err != nil {
+		if err == db.ErrEmergencyStateChanged {
+			server.RespondWithMessage(w, 409, "Emergency access changed in the meantime, try again")
+			return
+		}
+		log.Printf("cannot %s emergency access %d - %s", action, contact.ID, err)
+		server.RespondWithMessage(w, 500, "Could not change emergency access")
+		return
+	}
+	server.RespondWithMessage(w, 200, contact)
+}
+
+func (eh *emergencyHandler) respondWithEvents(w http.ResponseWriter, contact *em.EmergencyContact) {
+	events, err := eh.Repo.GetEmergencyEvents(contact.ID)
+	if err != nil {
+		server.RespondWithMessage(w, 500, "Could not get events")
+		return
+	}
+	server.RespondWithMessage(w, 200, events)
+}
@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	auth "passKeeper/internal/models/auth"
+	sec "passKeeper/internal/models/secret"
+	server "passKeeper/internal/models/server"
+	"time"
+)
+
+type expiryRequest struct {
+	// ExpiresAt is the new expiry date, null removes it.
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// servable checks that the secret has neither expired nor been archived. On
+// failure the response is already written.
+func servable(w http.ResponseWriter, secret *sec.Secret) bool {
+	if secret.ArchivedAt != nil || secret.Expired(time.Now()) {
+		server.RespondWithMessage(w, 410, "Secret has expired")
+		return false
+	}
+	return true
+}
+
+// GetExpiringSecrets returns the secrets which expire within the duration
+// given by the within query parameter, including the archived ones, out of
+// the personal secrets or the vault given by the vault query parameter.
+func (sh *secretHandler) GetExpiringSecrets(w http.ResponseWriter, r *http.Request) {
+	user, ok := auth.GetUserFromContext(r.Context())
+	if !ok {
+		server.RespondWithMessage(w, 500, "Could not get user from context")
+		return
+	}
+	within, err := time.ParseDuration(r.URL.Query().Get("within"))
+	if err != nil {
+		server.RespondWithMessage(w, 400, "Bad request. Invalid within duration.")
+		return
+	}
+	vault, ok := sh.readableVault(w, r, user)
+	if !ok {
+		return
+	}
+	secrets, err := sh.Repo.GetExpiringSecrets(user, vault, time.Now().Add(within))
+	if err != nil {
+		server.RespondWithMessage(w, 500, "Could not get secrets")
+		return
+	}
+	server.RespondWithMessage(w, 200, secrets)
+}
+
+// SetExpiry changes the expiry date of a secret. A new date in the future
+// takes an expired secret out of the archive.
+func (sh *secretHandler) SetExpiry(w http.ResponseWriter, r *http.Request) {
+	secret, ok := sh.ownedSecret(w, r)
+	if !ok {
+		return
+	}
+	var req expiryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		server.RespondWithMessage(w, 400, "Invalid request")
+		return
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		server.RespondWithMessage(w, 400, "Expiry date must be in the future")
+		return
+	}
+	if err := sh.Repo.SetSecretExpiry(secret, req.ExpiresAt); err != nil {
+		log.Printf("cannot set expiry of secret %d - %s", secret.ID, err)
+		server.RespondWithMessage(w, 500, "Could not set expiry")
+		return
+	}
+	secret.ExpiresAt = req.ExpiresAt
+	secret.ArchivedAt = nil
+	server.RespondWithMessage(w, 200, secret)
+}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"log"
+	"net/http"
+	auth "passKeeper/internal/models/auth"
+	db "passKeeper/internal/models/database"
+	sec "passKeeper/internal/models/secret"
+	server "passKeeper/internal/models/server"
+	"passKeeper/internal/server/controllers"
+	"time"
+
+	"github.com/go-chi/chi"
+)
+
+// oneTimeTokenSize is the number of random bytes of one-time share tokens.
+const oneTimeTokenSize = 24
+
+type oneTimeHandler struct {
+	Repo        db.SecretRepository
+	jwtSettings auth.JWTSettings
+}
+
+func NewOneTimeHandler(repo db.SecretRepository, jwtConf auth.JWTSettings) *oneTimeHandler {
+	return &oneTimeHandler{
+		Repo:        repo,
+		jwtSettings: jwtConf,
+	}
+}
+
+// Route creates one-time shares for accounts and opens them for anybody
+// holding the token. Opening uses POST, so that link previews do not use up
+// the views of a share.
+func (oh *oneTimeHandler) Route() *chi.Mux {
+	router := chi.NewRouter()
+	router.Group(func(r chi.Router) {
+		r.Use(controllers.JwtAuthenticationMiddleware(oh.jwtSettings))
+		r.Post("/", oh.CreateOneTimeShare)
+	})
+	router.Post("/{token}", oh.OpenOneTimeShare)
+	return router
+}
+
+func (oh *oneTimeHandler) CreateOneTimeShare(w http.ResponseWriter, r *http.Request) {
+	user, ok := auth.GetUserFromContext(r.Context())
+	if !ok {
+		server.RespondWithMessage(w, 500, "Could not get user from context")
+		return
+	}
+	var req sec.OneTimeShareRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 2*sec.MaxOneTimeSize)).Decode(&req); err != nil {
+		server.RespondWithMessage(w, 400, "Invalid request")
+		return
+	}
+	if err := req.Validate(time.Now()); err != nil {
+		server.RespondWithMessage(w, 400, err.Error())
+		return
+	}
+	token := make([]byte, oneTimeTokenSize)
+	if _, err := rand.Read(token); err != nil {
+		server.RespondWithMessage(w, 500, "Could not create token")
+		return
+	}
+	share := sec.OneTimeShare{
+		Token:      base64.RawURLEncoding.EncodeToString(token),
+		UserID:     user,
+		Ciphertext: req.Ciphertext,
+		ViewsLeft:  req.MaxViews,
+		ExpiresAt:  req.ExpiresAt,
+	}
+	if err := oh.Repo.SaveOneTimeShare(&share); err != nil {
+		log.Printf("cannot save one-time share of user %d - %s", user, err)
+		server.RespondWithMessage(w, 500, "Could not create one-time share")
+		return
+	}
+	server.RespondWithMessage(w, 200, sec.OneTimeShareCreated{Token: share.Token, ExpiresAt: share.ExpiresAt})
+}
+
+// OpenOneTimeShare returns the ciphertext of the share {token} and counts
+// the view. Used up, expired and unknown shares are all reported as gone.
+func (oh *oneTimeHandler) OpenOneTimeShare(w http.ResponseWriter, r *http.Request) {
+	share, err := oh.Repo.OpenOneTimeShare(chi.URLParam(r, "token"), time.Now())
+	if err != nil {
+		server.RespondWithMessage(w, 410, "Share does not exist anymore")
+		return
+	}
+	server.RespondWithMessage(w, 200, sec.OneTimeShareOpened{Ciphertext: share.Ciphertext, ViewsLeft: share.ViewsLeft})
+}
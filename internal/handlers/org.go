@@ -0,0 +1,320 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	auth "passKeeper/internal/models/auth"
+	db "passKeeper/internal/models/database"
+	org "passKeeper/internal/models/org"
+	server "passKeeper/internal/models/server"
+	"passKeeper/internal/server/controllers"
+	"strconv"
+
+	"github.com/go-chi/chi"
+)
+
+type orgHandler struct {
+	Repo        db.OrgRepository
+	Accounts    db.AccountRepository
+	jwtSettings auth.JWTSettings
+}
+
+func NewOrgHandler(repo db.OrgRepository, accounts db.AccountRepository, jwtConf auth.JWTSettings) *orgHandler {
+	return &orgHandler{
+		Repo:        repo,
+		Accounts:    accounts,
+		jwtSettings: jwtConf,
+	}
+}
+
+// Route addresses organizations and their vaults by name.
+func (oh *orgHandler) Route() *chi.Mux {
+	router := chi.NewRouter()
+	router.Use(controllers.JwtAuthenticationMiddleware(oh.jwtSettings))
+	router.Post("/", oh.CreateOrganization)
+	router.Get("/", oh.GetOrganizations)
+	router.Get("/{org}/members", oh.GetMembers)
+	router.Put("/{org}/members/{login}", oh.SetMember)
+	router.Delete("/{org}/members/{login}", oh.RemoveMember)
+	router.Get("/{org}/vaults", oh.GetVaults)
+	router.Post("/{org}/vaults", oh.CreateVault)
+	router.Delete("/{org}/vaults/{vault}", oh.DeleteVault)
+	return router
+}
+
+// CreateOrganization creates an organization with the caller as its owner.
+func (oh *orgHandler) CreateOrganization(w http.ResponseWriter, r *http.Request) {
+	user, ok := auth.GetUserFromContext(r.Context())
+	if !ok {
+		server.RespondWithMessage(w, 500, "Could not get user from context")
+		return
+	}
+	var req org.NameRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		server.RespondWithMessage(w, 400, "Invalid request")
+		return
+	}
+	name, err := org.NormalizeName(req.Name)
+	if err != nil {
+		server.RespondWithMessage(w, 400, err.Error())
+		return
+	}
+	if _, err := oh.Repo.GetOrganizationByName(name); err == nil {
+		server.RespondWithMessage(w, 409, fmt.Sprintf("Organization %s already exists", name))
+		return
+	}
+	o := org.Organization{Name: name}
+	if err := oh.Repo.CreateOrganization(&o, user); err != nil {
+		log.Printf("cannot create organization %s - %s", name, err)
+		server.RespondWithMessage(w, 500, "Could not create organization")
+		return
+	}
+	o.Role = org.RoleOwner
+	server.RespondWithMessage(w, 200, o)
+}
+
+// GetOrganizations lists the organizations of the caller with its role.
+func (oh *orgHandler) GetOrganizations(w http.ResponseWriter, r *http.Request) {
+	user, ok := auth.GetUserFromContext(r.Context())
+	if !ok {
+		server.RespondWithMessage(w, 500, "Could not get user from context")
+		return
+	}
+	orgs, err := oh.Repo.GetOrganizations(user)
+	if err != nil {
+		server.RespondWithMessage(w, 500, "Could not get organizations")
+		return
+	}
+	server.RespondWithMessage(w, 200, orgs)
+}
+
+func (oh *orgHandler) GetMembers(w http.ResponseWriter, r *http.Request) {
+	o, _, ok := oh.membership(w, r, org.RoleViewer)
+	if !ok {
+		return
+	}
+	members, err := oh.Repo.GetMembers(o.ID)
+	if err != nil {
+		server.RespondWithMessage(w, 500, "Could not get members")
+		return
+	}
+	server.RespondWithMessage(w, 200, members)
+}
+
+// SetMember adds the account {login} to the organization or changes its
+// role. Admins manage editors and viewers, owners manage every member.
+func (oh *orgHandler) SetMember(w http.ResponseWriter, r *http.Request) {
+	o, caller, ok := oh.membership(w, r, org.RoleAdmin)
+	if !ok {
+		return
+	}
+	var req org.MemberRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		server.RespondWithMessage(w, 400, "Invalid request")
+		return
+	}
+	if !org.ValidRole(req.Role) {
+		server.RespondWithMessage(w, 400, "Role must be owner, admin, editor or viewer")
+		return
+	}
+	member, current, ok := oh.targetMember(w, r, o, caller, req.Role)
+	if !ok {
+		return
+	}
+	if current.Role == org.RoleOwner && req.Role != org.RoleOwner && !oh.otherOwnerExists(w, o) {
+		return
+	}
+	member.Role = req.Role
+	if err := oh.Repo.SaveMember(member); err != nil {
+		log.Printf("cannot save member of organization %d - %s", o.ID, err)
+		server.RespondWithMessage(w, 500, "Could not save member")
+		return
+	}
+	server.RespondWithMessage(w, 200, member)
+}
+
+// RemoveMember removes the account {login} from the organization.
+func (oh *orgHandler) RemoveMember(w http.ResponseWriter, r *http.Request) {
+	o, caller, ok := oh.membership(w, r, org.RoleAdmin)
+	if !ok {
+		return
+	}
+	member, current, ok := oh.targetMember(w, r, o, caller, "")
+	if !ok {
+		return
+	}
+	if current.Role == "" {
+		server.RespondWithMessage(w, 404, "Member not found")
+		return
+	}
+	if current.Role == org.RoleOwner && !oh.otherOwnerExists(w, o) {
+		return
+	}
+	if err := oh.Repo.DeleteMember(o.ID, member.UserID); err != nil {
+		server.RespondWithMessage(w, 500, "Could not remove member")
+		return
+	}
+	server.RespondWithMessage(w, 200, nil)
+}
+
+func (oh *orgHandler) GetVaults(w http.ResponseWriter, r *http.Request) {
+	o, _, ok := oh.membership(w, r, org.RoleViewer)
+	if !ok {
+		return
+	}
+	vaults, err := oh.Repo.GetVaults(o.ID)
+	if err != nil {
+		server.RespondWithMessage(w, 500, "Could not get vaults")
+		return
+	}
+	server.RespondWithMessage(w, 200, vaults)
+}
+
+func (oh *orgHandler) CreateVault(w http.ResponseWriter, r *http.Request) {
+	o, _, ok := oh.membership(w, r, org.RoleAdmin)
+	if !ok {
+		return
+	}
+	var req org.NameRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		server.RespondWithMessage(w, 400, "Invalid request")
+		return
+	}
+	name, err := org.NormalizeName(req.Name)
+	if err != nil {
+		server.RespondWithMessage(w, 400, err.Error())
+		return
+	}
+	if _, err := oh.Repo.GetVaultByName(o.ID, name); err == nil {
+		server.RespondWithMessage(w, 409, fmt.Sprintf("Vault %s/%s already exists", o.Name, name))
+		return
+	}
+	vault := org.Vault{OrgID: o.ID, Name: name}
+	if err := oh.Repo.CreateVault(&vault); err != nil {
+		log.Printf("cannot create vault %s/%s - %s", o.Name, name, err)
+		server.RespondWithMessage(w, 500, "Could not create vault")
+		return
+	}
+	server.RespondWithMessage(w, 200, vault)
+}
+
+// DeleteVault deletes the vault {vault}, which must not hold any secrets.
+func (oh *orgHandler) DeleteVault(w http.ResponseWriter, r *http.Request) {
+	o, _, ok := oh.membership(w, r, org.RoleAdmin)
+	if !ok {
+		return
+	}
+	vault, err := oh.Repo.GetVaultByName(o.ID, chi.URLParam(r, "vault"))
+	if err != nil {
+		server.RespondWithMessage(w, 404, "Vault not found")
+		return
+	}
+	if err := oh.Repo.DeleteVault(vault); err != nil {
+		if err == db.ErrVaultNotEmpty {
+			server.RespondWithMessage(w, 409, "Vault still holds secrets, delete and purge them first")
+			return
+		}
+		log.Printf("cannot delete vault %d - %s", vault.ID, err)
+		server.RespondWithMessage(w, 500, "Could not delete vault")
+		return
+	}
+	server.RespondWithMessage(w, 200, nil)
+}
+
+// membership loads the organization from the {org} URL parameter and the
+// membership of the caller, which must have at least the required role.
+// Organizations the caller is no member of are reported as not found. On
+// failure the response is already written.
+func (oh *orgHandler) membership(w http.ResponseWriter, r *http.Request, required string) (*org.Organization, *org.Member, bool) {
+	user, ok := auth.GetUserFromContext(r.Context())
+	if !ok {
+		server.RespondWithMessage(w, 500, "Could not get user from context")
+		return nil, nil, false
+	}
+	o, err := oh.Repo.GetOrganizationByName(chi.URLParam(r, "org"))
+	if err != nil {
+		server.RespondWithMessage(w, 404, "Organization not found")
+		return nil, nil, false
+	}
+	member, err := oh.Repo.GetMember(o.ID, user)
+	if err != nil {
+		server.RespondWithMessage(w, 404, "Organization not found")
+		return nil, nil, false
+	}
+	if !org.RoleAllows(member.Role, required) {
+		server.RespondWithMessage(w, 403, fmt.Sprintf("Requires the %s role", required))
+		return nil, nil, false
+	}
+	return o, member, true
+}
+
+// targetMember loads the account {login} as a member of the organization and
+// checks that the caller may give it the role, or remove it for an empty
+// role. Admins may only manage editors and viewers. current holds the role
+// before the change, which is empty for accounts which are no member yet. On
+// failure the response is already written.
+func (oh *orgHandler) targetMember(w http.ResponseWriter, r *http.Request, o *org.Organization, caller *org.Member, role string) (member *org.Member, current org.Member, ok bool) {
+	account, err := oh.Accounts.GetAccountByLogin(chi.URLParam(r, "login"))
+	if err != nil {
+		server.RespondWithMessage(w, 404, "User not found")
+		return nil, current, false
+	}
+	member = &org.Member{OrgID: o.ID, UserID: account.ID, Login: account.Login}
+	if existing, err := oh.Repo.GetMember(o.ID, account.ID); err == nil {
+		current = *existing
+		member.ID = existing.ID
+	}
+	if caller.Role != org.RoleOwner && (org.RoleAllows(current.Role, org.RoleAdmin) || org.RoleAllows(role, org.RoleAdmin)) {
+		server.RespondWithMessage(w, 403, "Only owners manage owners and admins")
+		return nil, current, false
+	}
+	return member, current, true
+}
+
+// otherOwnerExists checks that the organization keeps an owner when one owner
+// is demoted or removed. On failure the response is already written.
+func (oh *orgHandler) otherOwnerExists(w http.ResponseWriter, o *org.Organization) bool {
+	owners, err := oh.Repo.CountOwners(o.ID)
+	if err != nil {
+		server.RespondWithMessage(w, 500, "Could not count owners")
+		return false
+	}
+	if owners <= 1 {
+		server.RespondWithMessage(w, 409, "Cannot remove the last owner of the organization")
+		return false
+	}
+	return true
+}
+
+// vaultRoleAllows reports whether the user has at least the required role in
+// the organization owning the vault.
+func vaultRoleAllows(repo db.SecretRepository, vaultID, user uint, required string) bool {
+	role, err := repo.GetVaultRole(vaultID, user)
+	if err != nil {
+		log.Printf("cannot get role of user %d in vault %d - %s", user, vaultID, err)
+		return false
+	}
+	return org.RoleAllows(role, required)
+}
+
+// readableVault reads the vault ID from the vault query parameter and checks
+// that the caller may read its secrets. Without the parameter it returns zero
+// for the personal secrets. On failure the response is already written.
+func (sh *secretHandler) readableVault(w http.ResponseWriter, r *http.Request, user uint) (uint, bool) {
+	v := r.URL.Query().Get("vault")
+	if v == "" {
+		return 0, true
+	}
+	id, err := strconv.ParseUint(v, 10, 64)
+	if err != nil || id == 0 {
+		server.RespondWithMessage(w, 400, "Bad request. Invalid vault.")
+		return 0, false
+	}
+	if !vaultRoleAllows(sh.Repo, uint(id), user, org.RoleViewer) {
+		server.RespondWithMessage(w, 404, "Vault not found")
+		return 0, false
+	}
+	return uint(id), true
+}
@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	audit "passKeeper/internal/models/audit"
+	auth "passKeeper/internal/models/auth"
+	sec "passKeeper/internal/models/secret"
+	server "passKeeper/internal/models/server"
+)
+
+// moveRequest is the body of move and copy requests.
+type moveRequest struct {
+	Path string `json:"path"`
+}
+
+func (sh *secretHandler) GetSecretByPath(w http.ResponseWriter, r *http.Request) {
+	user, ok := auth.GetUserFromContext(r.Context())
+	if !ok {
+		server.RespondWithMessage(w, 500, "Could not get user from context")
+		return
+	}
+	path, err := sec.NormalizePath(r.URL.Query().Get("path"))
+	if err != nil {
+		server.RespondWithMessage(w, 400, err.Error())
+		return
+	}
+	vault, ok := sh.readableVault(w, r, user)
+	if !ok {
+		return
+	}
+	secret, err := sh.Repo.GetSecretByPath(user, vault, path)
+	if err != nil {
+		server.RespondWithMessage(w, 404, "Secret not found")
+		return
+	}
+	audit.FromContext(r.Context()).SetSecret(secret.ID, secret.UserID)
+	if !servable(w, secret) {
+		return
+	}
+	recordAccess(sh.Repo, secret)
+	server.RespondWithMessage(w, 200, secret)
+}
+
+// ResolvePath returns the secret stored at the path query parameter without
+// its value, so that clients can turn paths into IDs. Like the listings, it
+// neither counts as an access nor is audited.
+func (sh *secretHandler) ResolvePath(w http.ResponseWriter, r *http.Request) {
+	user, ok := auth.GetUserFromContext(r.Context())
+	if !ok {
+		server.RespondWithMessage(w, 500, "Could not get user from context")
+		return
+	}
+	path, err := sec.NormalizePath(r.URL.Query().Get("path"))
+	if err != nil {
+		server.RespondWithMessage(w, 400, err.Error())
+		return
+	}
+	vault, ok := sh.readableVault(w, r, user)
+	if !ok {
+		return
+	}
+	secret, err := sh.Repo.GetSecretByPath(user, vault, path)
+	if err != nil {
+		server.RespondWithMessage(w, 404, "Secret not found")
+		return
+	}
+	secret.Value = nil
+	server.RespondWithMessage(w, 200, secret)
+}
+
+// ListPath returns the secrets below the prefix query parameter without their
+// values.
+func (sh *secretHandler) ListPath(w http.ResponseWriter, r *http.Request) {
+	user, ok := auth.GetUserFromContext(r.Context())
+	if !ok {
+		server.RespondWithMessage(w, 500, "Could not get user from context")
+		return
+	}
+	prefix, err := sec.NormalizePrefix(r.URL.Query().Get("prefix"))
+	if err != nil {
+		server.RespondWithMessage(w, 400, err.Error())
+		return
+	}
+	vault, ok := sh.readableVault(w, r, user)
+	if !ok {
+		return
+	}
+	secrets, err := sh.Repo.GetSecretsByPathPrefix(user, vault, prefix)
+	if err != nil {
+		server.RespondWithMessage(w, 500, "Could not get secrets")
+		return
+	}
+	server.RespondWithMessage(w, 200, secrets)
+}
+
+func (sh *secretHandler) MoveSecret(w http.ResponseWriter, r *http.Request) {
+	secret, ok := sh.ownedSecret(w, r)
+	if !ok {
+		return
+	}
+	var req moveRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		server.RespondWithMessage(w, 400, "Invalid request")
+		return
+	}
+	path, err := sec.NormalizePath(req.Path)
+	if err != nil {
+		server.RespondWithMessage(w, 400, err.Error())
+		return
+	}
+	if other, err := sh.Repo.GetSecretByPath(secret.UserID, secret.VaultID, path); err == nil && other.ID != secret.ID {
+		server.RespondWithMessage(w, 409, fmt.Sprintf("Path %s is already used by secret %d", path, other.ID))
+		return
+	}
+	if err := sh.Repo.MoveSecret(secret, path); err != nil {
+		log.Printf("cannot move secret %d - %s", secret.ID, err)
+		server.RespondWithMessage(w, 500, "Could not move secret")
+		return
+	}
+	secret.Path = path
+	server.RespondWithMessage(w, 200, secret)
+}
+
+// CopySecret duplicates the secret with its metadata, tags and attachments
+// under a new path.
+func (sh *secretHandler) CopySecret(w http.ResponseWriter, r *http.Request) {
+	secret, ok := sh.ownedSecret(w, r)
+	if !ok {
+		return
+	}
+	if !servable(w, secret) {
+		return
+	}
+	var req moveRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		server.RespondWithMessage(w, 400, "Invalid request")
+		return
+	}
+	path, err := sec.NormalizePath(req.Path)
+	if err != nil {
+		server.RespondWithMessage(w, 400, err.Error())
+		return
+	}
+	if other, err := sh.Repo.GetSecretByPath(secret.UserID, secret.VaultID, path); err == nil {
+		server.RespondWithMessage(w, 409, fmt.Sprintf("Path %s is already used by secret %d", path, other.ID))
+		return
+	}
+	copied, err := sh.Repo.CopySecret(secret, path)
+	if err != nil {
+		log.Printf("cannot copy secret %d - %s", secret.ID, err)
+		server.RespondWithMessage(w, 500, "Could not copy secret")
+		return
+	}
+	audit.FromContext(r.Context()).SetSecret(copied.ID, copied.UserID)
+	server.RespondWithMessage(w, 200, copied)
+}
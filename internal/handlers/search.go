@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	auth "passKeeper/internal/models/auth"
+	sec "passKeeper/internal/models/secret"
+	server "passKeeper/internal/models/server"
+	"strconv"
+)
+
+const (
+	defaultSearchLimit = 50
+	maxSearchLimit     = 500
+)
+
+// SearchSecrets matches the q query parameter against metadata, paths, tags
+// and types and returns the secrets found without their values.
+func (sh *secretHandler) SearchSecrets(w http.ResponseWriter, r *http.Request) {
+	user, ok := auth.GetUserFromContext(r.Context())
+	if !ok {
+		server.RespondWithMessage(w, 500, "Could not get user from context")
+		return
+	}
+	q, err := sec.ParseSearchQuery(r.URL.Query().Get("q"))
+	if err != nil {
+		server.RespondWithMessage(w, 400, err.Error())
+		return
+	}
+	limit := defaultSearchLimit
+	if l := r.URL.Query().Get("limit"); l != "" {
+		limit, err = strconv.Atoi(l)
+		if err != nil || limit <= 0 || limit > maxSearchLimit {
+			server.RespondWithMessage(w, 400, "Bad request. Invalid limit.")
+			return
+		}
+	}
+	if q.VaultID, ok = sh.readableVault(w, r, user); !ok {
+		return
+	}
+	secrets, err := sh.Repo.SearchSecrets(user, q, limit)
+	if err != nil {
+		log.Printf("cannot search secrets - %s", err)
+		server.RespondWithMessage(w, 500, "Could not search secrets")
+		return
+	}
+	server.RespondWithMessage(w, 200, secrets)
+}
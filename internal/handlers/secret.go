@@ -2,26 +2,40 @@ package handlers
 
 import (
 	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
+	"passKeeper/internal/events"
+	audit "passKeeper/internal/models/audit"
 	auth "passKeeper/internal/models/auth"
 	db "passKeeper/internal/models/database"
+	org "passKeeper/internal/models/org"
 	sec "passKeeper/internal/models/secret"
 	server "passKeeper/internal/models/server"
 	"passKeeper/internal/server/controllers"
 	"strconv"
+	"time"
 
 	"github.com/go-chi/chi"
 )
 
+// maxPageSize is the largest number of secrets returned in one page.
+const maxPageSize = 200
+
 type secretHandler struct {
 	Repo        db.SecretRepository
+	Accounts    db.AccountRepository
+	Audit       db.AuditRepository
+	Events      *events.Bus
 	jwtSettings auth.JWTSettings
 }
 
-func NewSecretHandler(repo db.SecretRepository, jwtConf auth.JWTSettings) *secretHandler {
+func NewSecretHandler(repo db.SecretRepository, accounts db.AccountRepository, auditRepo db.AuditRepository, bus *events.Bus, jwtConf auth.JWTSettings) *secretHandler {
 	return &secretHandler{
 		Repo:        repo,
+		Accounts:    accounts,
+		Audit:       auditRepo,
+		Events:      bus,
 		jwtSettings: jwtConf,
 	}
 }
@@ -29,13 +43,46 @@ func NewSecretHandler(repo db.SecretRepository, jwtConf auth.JWTSettings) *secre
 func (sh *secretHandler) Route() *chi.Mux {
 	router := chi.NewRouter()
 	router.Use(controllers.JwtAuthenticationMiddleware(sh.jwtSettings))
-	router.Get("/{id}", sh.GetSecret)
-	router.Post("/", sh.CreateSecret)
-	router.Delete("/{id}", sh.DeleteSecret)
+	router.With(sh.audited(audit.ActionRead)).Get("/{id}", sh.GetSecret)
+	router.With(sh.audited(audit.ActionCreate)).Post("/", sh.CreateSecret)
+	router.With(sh.audited("")).Post("/batch", sh.ApplyBatch)
+	router.With(sh.audited(audit.ActionDelete)).Delete("/{id}", sh.DeleteSecret)
 	router.Get("/secrets", sh.GetSecrets)
+	router.With(sh.audited("")).Get("/changes", sh.GetChanges)
+	router.Get("/trash", sh.GetTrash)
+	router.With(sh.audited("")).Delete("/trash", sh.EmptyTrash)
+	router.With(sh.audited(audit.ActionRestore)).Post("/trash/{id}/restore", sh.RestoreSecret)
+	router.With(sh.audited(audit.ActionPurge)).Delete("/trash/{id}", sh.PurgeSecret)
+	router.Get("/search", sh.SearchSecrets)
+	router.Get("/certs/expiring", sh.GetExpiringCertificates)
+	router.Get("/expiring", sh.GetExpiringSecrets)
+	router.Get("/stale", sh.GetStaleSecrets)
+	router.With(sh.audited(audit.ActionRead)).Get("/path", sh.GetSecretByPath)
+	router.Get("/resolve", sh.ResolvePath)
+	router.Get("/ls", sh.ListPath)
+	router.With(sh.audited(audit.ActionUpdate)).Put("/{id}/path", sh.MoveSecret)
+	router.With(sh.audited(audit.ActionUpdate)).Put("/{id}/vault", sh.MoveSecretToVault)
+	router.With(sh.audited(audit.ActionCreate)).Post("/{id}/copy", sh.CopySecret)
+	router.With(sh.audited(audit.ActionUpdate)).Put("/{id}/expiry", sh.SetExpiry)
+	router.Get("/{id}/attachments", sh.GetAttachments)
+	router.With(sh.audited(audit.ActionUpdate)).Post("/{id}/attachments", sh.AddAttachment)
+	router.With(sh.audited(audit.ActionRead)).Get("/{id}/attachments/{name}", sh.GetAttachment)
+	router.With(sh.audited(audit.ActionUpdate)).Delete("/{id}/attachments/{name}", sh.DeleteAttachment)
+	router.Get("/shared", sh.GetSharedSecrets)
+	router.Get("/{id}/shares", sh.GetShares)
+	router.With(sh.audited(audit.ActionShare)).Post("/{id}/shares", sh.ShareSecret)
+	router.With(sh.audited(audit.ActionUnshare)).Delete("/{id}/shares/{login}", sh.RevokeShare)
+	router.With(sh.audited(audit.ActionUpdate)).Put("/{id}/tags/{tag}", sh.AddTag)
+	router.With(sh.audited(audit.ActionUpdate)).Delete("/{id}/tags/{tag}", sh.RemoveTag)
 	return router
 }
 
+// audited records the action of a route in the audit log and publishes the
+// changes. Listings without values are not audited.
+func (sh *secretHandler) audited(action string) func(http.Handler) http.Handler {
+	return controllers.AuditMiddleware(sh.Audit, sh.Events, action)
+}
+
 func (sh *secretHandler) CreateSecret(w http.ResponseWriter, r *http.Request) {
 	user, ok := auth.GetUserFromContext(r.Context())
 	if !ok {
@@ -50,51 +97,126 @@ func (sh *secretHandler) CreateSecret(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	value, err := sec.GetSecretFromRequest(req, user)
+	trail := audit.FromContext(r.Context())
+	if req.ID != 0 {
+		trail.Action = audit.ActionUpdate
+		trail.SetSecret(req.ID, 0)
+	}
+	savedSecret, code, err := saveSecretRequest(sh.Repo, user, req)
 	if err != nil {
-		server.RespondWithMessage(w, 500, "Could not create secret from request")
+		server.RespondWithMessage(w, code, err.Error())
 		return
 	}
+	trail.SetSecret(savedSecret.ID, savedSecret.UserID)
+	server.RespondWithMessage(w, 200, savedSecret)
+}
+
+// saveSecretRequest creates the secret of the request, or updates it when the
+// request has an ID. On failure it returns the HTTP status code and an error
+// which can be shown to the client.
+//
+// Updates are allowed to the owner, to users the secret is shared with
+// read-write and, for secrets of a vault, to the editors of its organization.
+// The secret keeps its owner and vault, whose attachments and paths apply.
+func saveSecretRequest(repo db.SecretRepository, user uint, req sec.SecretRequest) (*sec.Secret, int, error) {
+	var existing *sec.Secret
+	owner := user
+	vault := req.VaultID
+	if req.ID == 0 && vault != 0 && !vaultRoleAllows(repo, vault, user, org.RoleEditor) {
+		return nil, 403, fmt.Errorf("Not allowed to add secrets to vault %d", vault)
+	}
+	if req.ID != 0 {
+		var err error
+		existing, err = repo.GetSecretByID(req.ID)
+		if err != nil || !canAccess(repo, existing, user, accessRead) {
+			return nil, 404, fmt.Errorf("Secret not found")
+		}
+		if !canAccess(repo, existing, user, accessWrite) {
+			return nil, 403, fmt.Errorf("Secret is shared read-only")
+		}
+		owner = existing.UserID
+		vault = existing.VaultID
+	}
 
-	secret, err := sec.NewSecret(user, req.Type, value, req.Meta)
+	value, err := sec.GetSecretFromRequest(req, owner)
 	if err != nil {
-		server.RespondWithMessage(w, 500, "Could not create secret")
-		return
+		return nil, 500, fmt.Errorf("Could not create secret from request")
 	}
 
-	if req.ID != 0 {
-		secret.ID = req.ID
+	if v, ok := value.(sec.Validator); ok {
+		if err := v.Validate(); err != nil {
+			return nil, 400, err
+		}
+	}
+	meta, legacyMeta := sec.MetadataFromRequest(req)
+	secret, err := sec.NewSecret(owner, req.Type, value, legacyMeta)
+	if err != nil {
+		return nil, 500, fmt.Errorf("Could not create secret")
+	}
+	secret.Meta = meta
+	secret.VaultID = vault
+
+	if req.Path != "" {
+		if secret.Path, err = sec.NormalizePath(req.Path); err != nil {
+			return nil, 400, err
+		}
+	}
+
+	if req.ExpiresAt != nil && !req.NoExpiry {
+		if !req.ExpiresAt.After(time.Now()) {
+			return nil, 400, fmt.Errorf("Expiry date must be in the future")
+		}
+		secret.ExpiresAt = req.ExpiresAt
+	}
+
+	if existing != nil {
+		secret.ID = existing.ID
+		secret.CreatedAt = existing.CreatedAt
+		if secret.Path == "" {
+			secret.Path = existing.Path
+		}
+		// A new expiry date takes the secret out of the archive, otherwise
+		// the expiry and archive state are kept unless explicitly removed.
+		if req.ExpiresAt == nil && !req.NoExpiry {
+			secret.ExpiresAt = existing.ExpiresAt
+			secret.ArchivedAt = existing.ArchivedAt
+		}
+		secret.LastAccessedAt = existing.LastAccessedAt
+		secret.AccessCount = existing.AccessCount
+		if secret.Path != existing.Path && !canAccess(repo, existing, user, accessOwner) {
+			return nil, 403, fmt.Errorf("Only the owner can move the secret")
+		}
+	}
+
+	if secret.Path != "" {
+		if other, err := repo.GetSecretByPath(owner, vault, secret.Path); err == nil && other.ID != secret.ID {
+			return nil, 409, fmt.Errorf("Path %s is already used by secret %d", secret.Path, other.ID)
+		}
 	}
 
-	savedSecret, err := sh.Repo.SaveSecret(&secret)
+	savedSecret, err := repo.SaveSecret(&secret)
 	if err != nil {
 		log.Printf("cannot create secret - %s", secret.SecretType)
-		server.RespondWithMessage(w, 500, "Could not save secret")
-		return
+		return nil, 500, fmt.Errorf("Could not save secret")
 	}
 
-	server.RespondWithMessage(w, 200, savedSecret)
+	if cert, ok := value.(*sec.Certificate); ok {
+		info := cert.Info(savedSecret.ID, owner)
+		if err := repo.SaveCertificateInfo(&info); err != nil {
+			log.Printf("cannot save certificate info for secret %d - %s", savedSecret.ID, err)
+		}
+	}
+
+	return savedSecret, 200, nil
 }
 
 func (sh *secretHandler) DeleteSecret(w http.ResponseWriter, r *http.Request) {
-	user, ok := auth.GetUserFromContext(r.Context())
+	secretToDelete, ok := sh.ownedSecret(w, r)
 	if !ok {
-		server.RespondWithMessage(w, 500, "Could not get user from context")
 		return
 	}
-	id := chi.URLParam(r, "id")
-	if id == "" {
-		server.RespondWithMessage(w, 400, "Bad request. Id Is missing.")
-	}
-	i, err := strconv.Atoi(id)
-	if err != nil {
-		server.RespondWithMessage(w, 400, "Bad request.")
-	}
-	var secretToDelete sec.Secret
-	secretToDelete.ID = uint(i)
-	secretToDelete.UserID = user
 
-	err = sh.Repo.DeleteSecret(&secretToDelete)
+	err := sh.Repo.DeleteSecret(secretToDelete)
 	if err != nil {
 		server.RespondWithMessage(w, 500, "Could not delete secret")
 		return
@@ -104,42 +226,179 @@ func (sh *secretHandler) DeleteSecret(w http.ResponseWriter, r *http.Request) {
 }
 
 func (sh *secretHandler) GetSecret(w http.ResponseWriter, r *http.Request) {
+	data, ok := sh.accessibleSecret(w, r, accessRead)
+	if !ok {
+		return
+	}
+	if !servable(w, data) {
+		return
+	}
+	recordAccess(sh.Repo, data)
+	server.RespondWithMessage(w, 200, data)
+}
+
+func (sh *secretHandler) GetSecrets(w http.ResponseWriter, r *http.Request) {
 	user, ok := auth.GetUserFromContext(r.Context())
 	if !ok {
 		server.RespondWithMessage(w, 500, "Could not get user from context")
+		return
 	}
-	id := chi.URLParam(r, "id")
-	if id == "" {
-		server.RespondWithMessage(w, 400, "Bad request. Id Is missing.")
+	filter, err := secretFilterFromQuery(r)
+	if err != nil {
+		server.RespondWithMessage(w, 400, err.Error())
+		return
 	}
-	i, err := strconv.Atoi(id)
+	if filter.VaultID, ok = sh.readableVault(w, r, user); !ok {
+		return
+	}
+	if filter.Limit > 0 {
+		page, err := sh.Repo.GetSecretPage(user, filter)
+		if err != nil {
+			log.Printf("cannot get secret page - %s", err)
+			server.RespondWithMessage(w, 500, "Could not get secrets")
+			return
+		}
+		server.RespondWithMessage(w, 200, page)
+		return
+	}
+	// Without a limit the whole list is returned as an array, as older
+	// clients expect.
+	secrets, err := sh.Repo.GetSecretsForUser(user, filter)
 	if err != nil {
-		server.RespondWithMessage(w, 400, "Bad request.")
+		server.RespondWithMessage(w, 500, "Could not get secrets")
+		return
 	}
+	resp := server.Response{Message: secrets, ServerCode: 200}
+	server.RespondWithMessage(w, resp.ServerCode, resp.Message)
+}
 
-	data, err := sh.Repo.GetSecretByID(uint(i))
+// GetChanges returns the changes of the secrets the caller reads after the
+// revision in the since query parameter, zero for all secrets, so that
+// clients can mirror them incrementally. Deleted secrets and secrets the
+// caller can no longer read are tombstones. Every value returned is audited
+// as a read.
+func (sh *secretHandler) GetChanges(w http.ResponseWriter, r *http.Request) {
+	user, ok := auth.GetUserFromContext(r.Context())
+	if !ok {
+		server.RespondWithMessage(w, 500, "Could not get user from context")
+		return
+	}
+	query := r.URL.Query()
+	var since uint64
+	if v := query.Get("since"); v != "" {
+		var err error
+		if since, err = strconv.ParseUint(v, 10, 64); err != nil {
+			server.RespondWithMessage(w, 400, "Bad request. Invalid revision.")
+			return
+		}
+	}
+	limit := sec.DefaultChangeLimit
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > sec.MaxChangeLimit {
+			server.RespondWithMessage(w, 400, "Bad request. Invalid limit.")
+			return
+		}
+		limit = n
+	}
+	feed, err := sh.Repo.GetChanges(user, since, limit)
 	if err != nil {
-		server.RespondWithMessage(w, 500, "Could not get Secret")
+		log.Printf("cannot get changes of user %d - %s", user, err)
+		server.RespondWithMessage(w, 500, "Could not get changes")
+		return
 	}
-	if data != nil {
-		if data.UserID == user {
-
-			server.RespondWithMessage(w, 200, data)
+	trail := audit.FromContext(r.Context())
+	for i, c := range feed.Changes {
+		if c.Secret == nil {
+			continue
+		}
+		if !canAccess(sh.Repo, c.Secret, user, accessRead) {
+			feed.Changes[i] = sec.Change{Revision: c.Revision, SecretID: c.SecretID, Deleted: true}
+			continue
 		}
+		trail.Add(audit.ActionRead, c.SecretID, c.Secret.UserID, audit.ResultSuccess)
 	}
+	server.RespondWithMessage(w, 200, feed)
 }
 
-func (sh *secretHandler) GetSecrets(w http.ResponseWriter, r *http.Request) {
+func (sh *secretHandler) GetExpiringCertificates(w http.ResponseWriter, r *http.Request) {
 	user, ok := auth.GetUserFromContext(r.Context())
 	if !ok {
 		server.RespondWithMessage(w, 500, "Could not get user from context")
 		return
 	}
-	secrets, err := sh.Repo.GetSecretsForUser(user)
+	within, err := time.ParseDuration(r.URL.Query().Get("within"))
 	if err != nil {
-		server.RespondWithMessage(w, 500, "Could not get secrets")
+		server.RespondWithMessage(w, 400, "Bad request. Invalid within duration.")
 		return
 	}
-	resp := server.Response{Message: secrets, ServerCode: 200}
-	server.RespondWithMessage(w, resp.ServerCode, resp.Message)
+	certs, err := sh.Repo.GetExpiringCertificates(user, time.Now().Add(within))
+	if err != nil {
+		server.RespondWithMessage(w, 500, "Could not get certificates")
+		return
+	}
+	server.RespondWithMessage(w, 200, certs)
+}
+
+// ownedSecret loads the secret from the {id} URL parameter and checks that it
+// belongs to the caller or, for secrets of a vault, that the caller is an
+// editor. On failure the response is already written.
+func (sh *secretHandler) ownedSecret(w http.ResponseWriter, r *http.Request) (*sec.Secret, bool) {
+	return sh.accessibleSecret(w, r, accessOwner)
+}
+
+// secretFilterFromQuery reads the list filters from the query string:
+// tag (repeatable) and match=all|any.
+func secretFilterFromQuery(r *http.Request) (db.SecretFilter, error) {
+	var filter db.SecretFilter
+	query := r.URL.Query()
+	for _, t := range query["tag"] {
+		tag, err := sec.NormalizeTag(t)
+		if err != nil {
+			return filter, err
+		}
+		filter.Tags = append(filter.Tags, tag)
+	}
+	switch query.Get("match") {
+	case "", "any":
+	case "all":
+		filter.MatchAllTags = true
+	default:
+		return filter, fmt.Errorf("match must be any or all")
+	}
+	filter.Types = query["type"]
+
+	filter.Sort = query.Get("sort")
+	if filter.Sort == "" {
+		filter.Sort = sec.SortName
+	}
+	if !sec.ValidSort(filter.Sort) {
+		return filter, fmt.Errorf("sort must be one of name, created, updated, type, accessed or accesses")
+	}
+	switch query.Get("order") {
+	case "", "asc":
+	case "desc":
+		filter.Desc = true
+	default:
+		return filter, fmt.Errorf("order must be asc or desc")
+	}
+
+	if l := query.Get("limit"); l != "" {
+		limit, err := strconv.Atoi(l)
+		if err != nil || limit <= 0 || limit > maxPageSize {
+			return filter, fmt.Errorf("limit must be between 1 and %d", maxPageSize)
+		}
+		filter.Limit = limit
+	}
+	if c := query.Get("cursor"); c != "" {
+		if filter.Limit == 0 {
+			return filter, fmt.Errorf("cursor requires a limit")
+		}
+		cursor, err := sec.DecodeCursor(c, filter.Sort)
+		if err != nil {
+			return filter, err
+		}
+		filter.Cursor = &cursor
+	}
+	return filter, nil
 }
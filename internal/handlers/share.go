@@ -0,0 +1,202 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	audit "passKeeper/internal/models/audit"
+	auth "passKeeper/internal/models/auth"
+	db "passKeeper/internal/models/database"
+	org "passKeeper/internal/models/org"
+	sec "passKeeper/internal/models/secret"
+	server "passKeeper/internal/models/server"
+	"strconv"
+
+	"github.com/go-chi/chi"
+)
+
+// Access levels to a secret. Shares grant read or write access, everything
+// else needs the owner. Sharing with other accounts needs the owner of a
+// personal secret or an admin of the vault.
+const (
+	accessRead = iota
+	accessWrite
+	accessOwner
+	accessShare
+)
+
+// canAccess reports whether the user has the given access to the secret.
+// Secrets of a vault are read by all members of its organization and changed
+// by the editors and above, whoever created them. The owner of a personal
+// vault has the owner role in it. Granted emergency contacts read the
+// personal secrets of the owner. Shares never grant access to the secrets of
+// an organization, which are only shared through its membership.
+func canAccess(repo db.SecretRepository, secret *sec.Secret, user uint, access int) bool {
+	if secret.VaultID != 0 {
+		required := org.RoleEditor
+		switch access {
+		case accessRead:
+			required = org.RoleViewer
+		case accessShare:
+			required = org.RoleAdmin
+		}
+		vault, err := repo.GetVault(secret.VaultID)
+		if err != nil {
+			log.Printf("cannot get vault %d - %s", secret.VaultID, err)
+			return false
+		}
+		role, err := repo.GetVaultRole(secret.VaultID, user)
+		if err != nil {
+			log.Printf("cannot get role of user %d in vault %d - %s", user, secret.VaultID, err)
+			return false
+		}
+		if role != "" {
+			return org.RoleAllows(role, required)
+		}
+		if !vault.Personal() {
+			return false
+		}
+	} else if secret.UserID == user {
+		return true
+	}
+	if access >= accessOwner {
+		return false
+	}
+	share, err := repo.GetShare(secret.ID, user)
+	if err == nil {
+		return access == accessRead || !share.ReadOnly
+	}
+	return access == accessRead && emergencyAccess(repo, secret, user)
+}
+
+// emergencyAccess reports whether the user was granted emergency access to
+// the personal secrets of the owner of the secret. Secrets of organizations
+// are never readable this way.
+func emergencyAccess(repo db.SecretRepository, secret *sec.Secret, user uint) bool {
+	if secret.VaultID != 0 {
+		vault, err := repo.GetVault(secret.VaultID)
+		if err != nil || !vault.Personal() {
+			return false
+		}
+	}
+	granted, err := repo.HasEmergencyAccess(secret.UserID, user)
+	if err != nil {
+		log.Printf("cannot check emergency access of user %d to user %d - %s", user, secret.UserID, err)
+		return false
+	}
+	return granted
+}
+
+// accessibleSecret loads the secret from the {id} URL parameter and checks
+// that the caller has the given access to it. Secrets the caller cannot read
+// are reported as not found. On failure the response is already written.
+func (sh *secretHandler) accessibleSecret(w http.ResponseWriter, r *http.Request, access int) (*sec.Secret, bool) {
+	user, ok := auth.GetUserFromContext(r.Context())
+	if !ok {
+		server.RespondWithMessage(w, 500, "Could not get user from context")
+		return nil, false
+	}
+	i, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		server.RespondWithMessage(w, 400, "Bad request.")
+		return nil, false
+	}
+	secret, err := sh.Repo.GetSecretByID(uint(i))
+	if err == nil {
+		audit.FromContext(r.Context()).SetSecret(secret.ID, secret.UserID)
+	}
+	if err != nil || !canAccess(sh.Repo, secret, user, accessRead) {
+		server.RespondWithMessage(w, 404, "Secret not found")
+		return nil, false
+	}
+	if !canAccess(sh.Repo, secret, user, access) {
+		server.RespondWithMessage(w, 403, "Not allowed for a shared secret")
+		return nil, false
+	}
+	return secret, true
+}
+
+// ShareSecret grants another account read-only or read-write access to the
+// secret. Sharing again with the same account changes its access.
+func (sh *secretHandler) ShareSecret(w http.ResponseWriter, r *http.Request) {
+	secret, ok := sh.accessibleSecret(w, r, accessShare)
+	if !ok {
+		return
+	}
+	var req sec.ShareRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		server.RespondWithMessage(w, 400, "Invalid request")
+		return
+	}
+	recipient, err := sh.Accounts.GetAccountByLogin(req.Login)
+	if err != nil {
+		server.RespondWithMessage(w, 404, "User not found")
+		return
+	}
+	if secret.VaultID != 0 {
+		if vault, err := sh.Repo.GetVault(secret.VaultID); err != nil || !vault.Personal() {
+			server.RespondWithMessage(w, 400, "Secrets of a vault are shared through its organization")
+			return
+		}
+	}
+	if recipient.ID == secret.UserID {
+		server.RespondWithMessage(w, 400, "Cannot share a secret with its owner")
+		return
+	}
+	share := sec.Share{SecretID: secret.ID, UserID: recipient.ID, ReadOnly: req.ReadOnly}
+	if err := sh.Repo.SaveShare(&share); err != nil {
+		log.Printf("cannot share secret %d - %s", secret.ID, err)
+		server.RespondWithMessage(w, 500, "Could not share secret")
+		return
+	}
+	share.Login = recipient.Login
+	server.RespondWithMessage(w, 200, share)
+}
+
+// GetShares lists the accounts the secret is shared with.
+func (sh *secretHandler) GetShares(w http.ResponseWriter, r *http.Request) {
+	secret, ok := sh.accessibleSecret(w, r, accessShare)
+	if !ok {
+		return
+	}
+	shares, err := sh.Repo.GetShares(secret.ID)
+	if err != nil {
+		server.RespondWithMessage(w, 500, "Could not get shares")
+		return
+	}
+	server.RespondWithMessage(w, 200, shares)
+}
+
+// RevokeShare removes the access of the account {login} to the secret.
+func (sh *secretHandler) RevokeShare(w http.ResponseWriter, r *http.Request) {
+	secret, ok := sh.accessibleSecret(w, r, accessShare)
+	if !ok {
+		return
+	}
+	recipient, err := sh.Accounts.GetAccountByLogin(chi.URLParam(r, "login"))
+	if err != nil {
+		server.RespondWithMessage(w, 404, "User not found")
+		return
+	}
+	if err := sh.Repo.DeleteShare(secret.ID, recipient.ID); err != nil {
+		server.RespondWithMessage(w, 404, "Secret is not shared with this user")
+		return
+	}
+	server.RespondWithMessage(w, 200, nil)
+}
+
+// GetSharedSecrets lists the secrets other accounts shared with the caller,
+// without their values.
+func (sh *secretHandler) GetSharedSecrets(w http.ResponseWriter, r *http.Request) {
+	user, ok := auth.GetUserFromContext(r.Context())
+	if !ok {
+		server.RespondWithMessage(w, 500, "Could not get user from context")
+		return
+	}
+	shared, err := sh.Repo.GetSharedSecrets(user)
+	if err != nil {
+		server.RespondWithMessage(w, 500, "Could not get shared secrets")
+		return
+	}
+	server.RespondWithMessage(w, 200, shared)
+}
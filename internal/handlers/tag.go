@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	sec "passKeeper/internal/models/secret"
+	server "passKeeper/internal/models/server"
+
+	"github.com/go-chi/chi"
+)
+
+func (sh *secretHandler) AddTag(w http.ResponseWriter, r *http.Request) {
+	secret, ok := sh.ownedSecret(w, r)
+	if !ok {
+		return
+	}
+	tag, err := sec.NormalizeTag(chi.URLParam(r, "tag"))
+	if err != nil {
+		server.RespondWithMessage(w, 400, err.Error())
+		return
+	}
+	if err := sh.Repo.AddTag(secret, tag); err != nil {
+		log.Printf("cannot tag secret %d - %s", secret.ID, err)
+		server.RespondWithMessage(w, 500, "Could not add tag")
+		return
+	}
+	server.RespondWithMessage(w, 200, nil)
+}
+
+func (sh *secretHandler) RemoveTag(w http.ResponseWriter, r *http.Request) {
+	secret, ok := sh.ownedSecret(w, r)
+	if !ok {
+		return
+	}
+	tag, err := sec.NormalizeTag(chi.URLParam(r, "tag"))
+	if err != nil {
+		server.RespondWithMessage(w, 400, err.Error())
+		return
+	}
+	if err := sh.Repo.RemoveTag(secret, tag); err != nil {
+		log.Printf("cannot untag secret %d - %s", secret.ID, err)
+		server.RespondWithMessage(w, 500, "Could not remove tag")
+		return
+	}
+	server.RespondWithMessage(w, 200, nil)
+}
@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	audit "passKeeper/internal/models/audit"
+	auth "passKeeper/internal/models/auth"
+	org "passKeeper/internal/models/org"
+	sec "passKeeper/internal/models/secret"
+	server "passKeeper/internal/models/server"
+	"strconv"
+
+	"github.com/go-chi/chi"
+)
+
+func (sh *secretHandler) GetTrash(w http.ResponseWriter, r *http.Request) {
+	user, ok := auth.GetUserFromContext(r.Context())
+	if !ok {
+		server.RespondWithMessage(w, 500, "Could not get user from context")
+		return
+	}
+	vault, ok := sh.readableVault(w, r, user)
+	if !ok {
+		return
+	}
+	secrets, err := sh.Repo.GetTrash(user, vault)
+	if err != nil {
+		server.RespondWithMessage(w, 500, "Could not get trash")
+		return
+	}
+	server.RespondWithMessage(w, 200, secrets)
+}
+
+func (sh *secretHandler) RestoreSecret(w http.ResponseWriter, r *http.Request) {
+	secret, ok := sh.trashedSecret(w, r)
+	if !ok {
+		return
+	}
+	if secret.Path != "" {
+		if other, err := sh.Repo.GetSecretByPath(secret.UserID, secret.VaultID, secret.Path); err == nil {
+			server.RespondWithMessage(w, 409, fmt.Sprintf("Path %s is already used by secret %d", secret.Path, other.ID))
+			return
+		}
+	}
+	if err := sh.Repo.RestoreSecret(secret); err != nil {
+		log.Printf("cannot restore secret %d - %s", secret.ID, err)
+		server.RespondWithMessage(w, 500, "Could not restore secret")
+		return
+	}
+	server.RespondWithMessage(w, 200, nil)
+}
+
+func (sh *secretHandler) PurgeSecret(w http.ResponseWriter, r *http.Request) {
+	secret, ok := sh.trashedSecret(w, r)
+	if !ok {
+		return
+	}
+	if err := sh.Repo.PurgeSecret(secret); err != nil {
+		log.Printf("cannot purge secret %d - %s", secret.ID, err)
+		server.RespondWithMessage(w, 500, "Could not purge secret")
+		return
+	}
+	server.RespondWithMessage(w, 200, nil)
+}
+
+// EmptyTrash purges the trash of the caller or of the vault. Every purged
+// secret is audited with its owner.
+func (sh *secretHandler) EmptyTrash(w http.ResponseWriter, r *http.Request) {
+	user, ok := auth.GetUserFromContext(r.Context())
+	if !ok {
+		server.RespondWithMessage(w, 500, "Could not get user from context")
+		return
+	}
+	vault, ok := sh.readableVault(w, r, user)
+	if !ok {
+		return
+	}
+	if vault != 0 && !vaultRoleAllows(sh.Repo, vault, user, org.RoleEditor) {
+		server.RespondWithMessage(w, 403, fmt.Sprintf("Not allowed to purge secrets of vault %d", vault))
+		return
+	}
+	purged, err := sh.Repo.EmptyTrash(user, vault)
+	trail := audit.FromContext(r.Context())
+	for _, s := range purged {
+		trail.Add(audit.ActionPurge, s.ID, s.UserID, audit.ResultSuccess)
+	}
+	if err != nil {
+		log.Printf("cannot empty trash of user %d - %s", user, err)
+		server.RespondWithMessage(w, 500, "Could not empty trash")
+		return
+	}
+	server.RespondWithMessage(w, 200, len(purged))
+}
+
+// trashedSecret loads the secret from the {id} URL parameter out of the trash
+// if the caller may delete it, i.e. owns it or is an editor of its vault. On
+// failure the response is already written.
+func (sh *secretHandler) trashedSecret(w http.ResponseWriter, r *http.Request) (*sec.Secret, bool) {
+	user, ok := auth.GetUserFromContext(r.Context())
+	if !ok {
+		server.RespondWithMessage(w, 500, "Could not get user from context")
+		return nil, false
+	}
+	i, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		server.RespondWithMessage(w, 400, "Bad request.")
+		return nil, false
+	}
+	secret, err := sh.Repo.GetTrashedSecret(uint(i))
+	if err != nil || !canAccess(sh.Repo, secret, user, accessOwner) {
+		server.RespondWithMessage(w, 404, "Secret not found in trash")
+		return nil, false
+	}
+	audit.FromContext(r.Context()).SetSecret(secret.ID, secret.UserID)
+	return secret, true
+}
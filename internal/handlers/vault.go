@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	auth "passKeeper/internal/models/auth"
+	db "passKeeper/internal/models/database"
+	org "passKeeper/internal/models/org"
+	server "passKeeper/internal/models/server"
+	"passKeeper/internal/server/controllers"
+
+	"github.com/go-chi/chi"
+)
+
+// vaultRequest is the body of requests moving a secret to another vault. A
+// vault ID of zero moves it to the default vault of the caller.
+type vaultRequest struct {
+	VaultID uint `json:"vault_id"`
+}
+
+type vaultHandler struct {
+	Repo        db.OrgRepository
+	jwtSettings auth.JWTSettings
+}
+
+func NewVaultHandler(repo db.OrgRepository, jwtConf auth.JWTSettings) *vaultHandler {
+	return &vaultHandler{
+		Repo:        repo,
+		jwtSettings: jwtConf,
+	}
+}
+
+// Route addresses the personal vaults of the caller by name.
+func (vh *vaultHandler) Route() *chi.Mux {
+	router := chi.NewRouter()
+	router.Use(controllers.JwtAuthenticationMiddleware(vh.jwtSettings))
+	router.Get("/", vh.GetVaults)
+	router.Post("/", vh.CreateVault)
+	router.Delete("/{vault}", vh.DeleteVault)
+	return router
+}
+
+func (vh *vaultHandler) GetVaults(w http.ResponseWriter, r *http.Request) {
+	user, ok := auth.GetUserFromContext(r.Context())
+	if !ok {
+		server.RespondWithMessage(w, 500, "Could not get user from context")
+		return
+	}
+	vaults, err := vh.Repo.GetPersonalVaults(user)
+	if err != nil {
+		server.RespondWithMessage(w, 500, "Could not get vaults")
+		return
+	}
+	server.RespondWithMessage(w, 200, vaults)
+}
+
+func (vh *vaultHandler) CreateVault(w http.ResponseWriter, r *http.Request) {
+	user, ok := auth.GetUserFromContext(r.Context())
+	if !ok {
+		server.RespondWithMessage(w, 500, "Could not get user from context")
+		return
+	}
+	var req org.NameRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		server.RespondWithMessage(w, 400, "Invalid request")
+		return
+	}
+	name, err := org.NormalizeName(req.Name)
+	if err != nil {
+		server.RespondWithMessage(w, 400, err.Error())
+		return
+	}
+	if name == org.DefaultVault {
+		server.RespondWithMessage(w, 400, fmt.Sprintf("The name %s is reserved", name))
+		return
+	}
+	if _, err := vh.Repo.GetPersonalVaultByName(user, name); err == nil {
+		server.RespondWithMessage(w, 409, fmt.Sprintf("Vault %s already exists", name))
+		return
+	}
+	vault := org.Vault{UserID: user, Name: name}
+	if err := vh.Repo.CreateVault(&vault); err != nil {
+		log.Printf("cannot create vault %s of user %d - %s", name, user, err)
+		server.RespondWithMessage(w, 500, "Could not create vault")
+		return
+	}
+	server.RespondWithMessage(w, 200, vault)
+}
+
+// DeleteVault permanently deletes the personal vault {vault} with all of its
+// secrets and returns the number of secrets deleted.
+func (vh *vaultHandler) DeleteVault(w http.ResponseWriter, r *http.Request) {
+	user, ok := auth.GetUserFromContext(r.Context())
+	if !ok {
+		server.RespondWithMessage(w, 500, "Could not get user from context")
+		return
+	}
+	vault, err := vh.Repo.GetPersonalVaultByName(user, chi.URLParam(r, "vault"))
+	if err != nil {
+		server.RespondWithMessage(w, 404, "Vault not found")
+		return
+	}
+	n, err := vh.Repo.PurgeVault(vault)
+	if err != nil {
+		log.Printf("cannot delete vault %d - %s", vault.ID, err)
+		server.RespondWithMessage(w, 500, "Could not delete vault")
+		return
+	}
+	server.RespondWithMessage(w, 200, n)
+}
+
+// MoveSecretToVault moves the secret to another vault in which the caller is
+// an editor, or to the default vault of the caller. Moving a secret out of
+// its organization needs an admin of its vault. The path of the secret must
+// be free in the target vault.
+func (sh *secretHandler) MoveSecretToVault(w http.ResponseWriter, r *http.Request) {
+	user, ok := auth.GetUserFromContext(r.Context())
+	if !ok {
+		server.RespondWithMessage(w, 500, "Could not get user from context")
+		return
+	}
+	secret, ok := sh.ownedSecret(w, r)
+	if !ok {
+		return
+	}
+	var req vaultRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		server.RespondWithMessage(w, 400, "Invalid request")
+		return
+	}
+	if req.VaultID == secret.VaultID {
+		server.RespondWithMessage(w, 200, secret)
+		return
+	}
+	var vault *org.Vault
+	if req.VaultID != 0 {
+		if !vaultRoleAllows(sh.Repo, req.VaultID, user, org.RoleEditor) {
+			server.RespondWithMessage(w, 404, "Vault not found")
+			return
+		}
+		var err error
+		if vault, err = sh.Repo.GetVault(req.VaultID); err != nil {
+			server.RespondWithMessage(w, 404, "Vault not found")
+			return
+		}
+	}
+	if secret.VaultID != 0 {
+		source, err := sh.Repo.GetVault(secret.VaultID)
+		if err != nil {
+			server.RespondWithMessage(w, 500, "Could not get vault")
+			return
+		}
+		leavesOrg := !source.Personal() && (vault == nil || vault.OrgID != source.OrgID)
+		if leavesOrg && !vaultRoleAllows(sh.Repo, source.ID, user, org.RoleAdmin) {
+			server.RespondWithMessage(w, 403, "Only admins may move secrets out of the organization")
+			return
+		}
+	}
+	if secret.Path != "" {
+		if other, err := sh.Repo.GetSecretByPath(user, req.VaultID, secret.Path); err == nil {
+			server.RespondWithMessage(w, 409, fmt.Sprintf("Path %s is already used by secret %d in the target vault", secret.Path, other.ID))
+			return
+		}
+	}
+	if err := sh.Repo.MoveSecretToVault(secret, vault, user); err != nil {
+		log.Printf("cannot move secret %d to vault %d - %s", secret.ID, req.VaultID, err)
+		server.RespondWithMessage(w, 500, "Could not move secret")
+		return
+	}
+	updated, err := sh.Repo.GetSecretByID(secret.ID)
+	if err != nil {
+		server.RespondWithMessage(w, 500, "Could not get secret")
+		return
+	}
+	server.RespondWithMessage(w, 200, updated)
+}
@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"passKeeper/internal/events"
+	auth "passKeeper/internal/models/auth"
+	db "passKeeper/internal/models/database"
+	server "passKeeper/internal/models/server"
+	"passKeeper/internal/server/controllers"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi"
+)
+
+const (
+	// watchBuffer is the number of events queued for a watcher. Watchers
+	// falling further behind are disconnected.
+	watchBuffer = 64
+	// watchKeepAlive is how often an idle stream sends a comment, so that
+	// proxies keep it open.
+	watchKeepAlive = 30 * time.Second
+)
+
+type watchHandler struct {
+	Repo        db.SecretRepository
+	Orgs        db.OrgRepository
+	Events      *events.Bus
+	jwtSettings auth.JWTSettings
+}
+
+func NewWatchHandler(repo db.SecretRepository, orgs db.OrgRepository, bus *events.Bus, jwtConf auth.JWTSettings) *watchHandler {
+	return &watchHandler{
+		Repo:        repo,
+		Orgs:        orgs,
+		Events:      bus,
+		jwtSettings: jwtConf,
+	}
+}
+
+func (wt *watchHandler) Route() *chi.Mux {
+	router := chi.NewRouter()
+	router.Use(controllers.JwtAuthenticationMiddleware(wt.jwtSettings))
+	router.Get("/", wt.Watch)
+	return router
+}
+
+// Watch streams the changes of the secrets the caller can read as
+// Server-Sent Events, see events.WriteSSE, until the client disconnects. The
+// first event is events.Subscribed. Events are not replayed, clients
+// reconnecting may have missed some.
+func (wt *watchHandler) Watch(w http.ResponseWriter, r *http.Request) {
+	user, ok := auth.GetUserFromContext(r.Context())
+	if !ok {
+		server.RespondWithMessage(w, 500, "Could not get user from context")
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		server.RespondWithMessage(w, 500, "Streaming is not supported")
+		return
+	}
+	queue := make(chan events.Event, watchBuffer)
+	overflow := make(chan struct{})
+	var once sync.Once
+	unsubscribe := wt.Events.Subscribe(func(e events.Event) {
+		select {
+		case queue <- e:
+		default:
+			once.Do(func() { close(overflow) })
+		}
+	})
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	if err := events.WriteSSE(w, events.Event{Type: events.Subscribed, OccurredAt: time.Now()}); err != nil {
+		return
+	}
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(watchKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-overflow:
+			log.Printf("disconnecting watcher %d, it fell %d events behind", user, watchBuffer)
+			return
+		case <-keepAlive.C:
+			if _, err := w.Write([]byte(": ping\n\n")); err != nil {
+				return
+			}
+		case e := <-queue:
+			if !wt.visible(e, user) {
+				continue
+			}
+			if err := events.WriteSSE(w, e); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
+
+// visible reports whether the user can read the secret of the event: it owns
+// the secret or its personal vault, is a member of the organization owning
+// its vault, or the secret is shared with it.
+func (wt *watchHandler) visible(e events.Event, user uint) bool {
+	owner, orgID, err := wt.Repo.GetEventScope(e.SecretID)
+	if err != nil {
+		log.Printf("cannot get the scope of secret %d - %s", e.SecretID, err)
+		return false
+	}
+	if orgID == 0 && owner == user {
+		return true
+	}
+	if orgID != 0 {
+		_, err := wt.Orgs.GetMember(orgID, user)
+		return err == nil
+	}
+	_, err = wt.Repo.GetShare(e.SecretID, user)
+	return err == nil
+}
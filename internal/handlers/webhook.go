@@ -0,0 +1,214 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	auth "passKeeper/internal/models/auth"
+	db "passKeeper/internal/models/database"
+	org "passKeeper/internal/models/org"
+	server "passKeeper/internal/models/server"
+	wh "passKeeper/internal/models/webhook"
+	"passKeeper/internal/server/controllers"
+	"strconv"
+
+	"github.com/go-chi/chi"
+)
+
+// webhookSecretSize is the number of random bytes of generated signing
+// secrets.
+const webhookSecretSize = 32
+
+type webhookHandler struct {
+	Repo        db.WebhookRepository
+	Orgs        db.OrgRepository
+	jwtSettings auth.JWTSettings
+}
+
+func NewWebhookHandler(repo db.WebhookRepository, orgs db.OrgRepository, jwtConf auth.JWTSettings) *webhookHandler {
+	return &webhookHandler{
+		Repo:        repo,
+		Orgs:        orgs,
+		jwtSettings: jwtConf,
+	}
+}
+
+func (hh *webhookHandler) Route() *chi.Mux {
+	router := chi.NewRouter()
+	router.Use(controllers.JwtAuthenticationMiddleware(hh.jwtSettings))
+	router.Get("/", hh.GetWebhooks)
+	router.Post("/", hh.CreateWebhook)
+	router.Delete("/{id}", hh.DeleteWebhook)
+	router.Get("/{id}/deliveries", hh.GetDeliveries)
+	return router
+}
+
+// GetWebhooks lists the personal webhooks of the caller, or those of the
+// organization in the org query parameter.
+func (hh *webhookHandler) GetWebhooks(w http.ResponseWriter, r *http.Request) {
+	user, ok := auth.GetUserFromContext(r.Context())
+	if !ok {
+		server.RespondWithMessage(w, 500, "Could not get user from context")
+		return
+	}
+	var hooks []wh.Webhook
+	var err error
+	if name := r.URL.Query().Get("org"); name != "" {
+		o, ok := hh.administeredOrg(w, name, user)
+		if !ok {
+			return
+		}
+		hooks, err = hh.Repo.GetOrgWebhooks(o.ID)
+	} else {
+		hooks, err = hh.Repo.GetWebhooks(user)
+	}
+	if err != nil {
+		server.RespondWithMessage(w, 500, "Could not get webhooks")
+		return
+	}
+	server.RespondWithMessage(w, 200, hooks)
+}
+
+// CreateWebhook registers a webhook of the caller, or of an organization it
+// administers. The response holds the signing secret, which is not shown
+// again.
+func (hh *webhookHandler) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	user, ok := auth.GetUserFromContext(r.Context())
+	if !ok {
+		server.RespondWithMessage(w, 500, "Could not get user from context")
+		return
+	}
+	var req wh.WebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		server.RespondWithMessage(w, 400, "Invalid request")
+		return
+	}
+	if err := wh.ValidateURL(req.URL); err != nil {
+		server.RespondWithMessage(w, 400, err.Error())
+		return
+	}
+	events, err := req.NormalizeEvents()
+	if err != nil {
+		server.RespondWithMessage(w, 400, err.Error())
+		return
+	}
+	if req.Secret != "" && len(req.Secret) < wh.MinSecretLength {
+		server.RespondWithMessage(w, 400, fmt.Sprintf("The secret must have at least %d characters", wh.MinSecretLength))
+		return
+	}
+	hook := wh.Webhook{UserID: user, URL: req.URL, Events: events, Secret: req.Secret}
+	if req.Org != "" {
+		o, ok := hh.administeredOrg(w, req.Org, user)
+		if !ok {
+			return
+		}
+		hook.OrgID = o.ID
+	}
+	if hook.Secret == "" {
+		secret := make([]byte, webhookSecretSize)
+		if _, err := rand.Read(secret); err != nil {
+			server.RespondWithMessage(w, 500, "Could not create secret")
+			return
+		}
+		hook.Secret = hex.EncodeToString(secret)
+	}
+	if err := hh.Repo.CreateWebhook(&hook); err != nil {
+		log.Printf("cannot create webhook of user %d - %s", user, err)
+		server.RespondWithMessage(w, 500, "Could not create webhook")
+		return
+	}
+	server.RespondWithMessage(w, 200, wh.WebhookCreated{Webhook: hook, Secret: hook.Secret})
+}
+
+// DeleteWebhook removes the webhook {id} with its delivery log.
+func (hh *webhookHandler) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	hook, ok := hh.managedWebhook(w, r)
+	if !ok {
+		return
+	}
+	if err := hh.Repo.DeleteWebhook(hook); err != nil {
+		log.Printf("cannot delete webhook %d - %s", hook.ID, err)
+		server.RespondWithMessage(w, 500, "Could not delete webhook")
+		return
+	}
+	server.RespondWithMessage(w, 200, nil)
+}
+
+// GetDeliveries returns the last deliveries of the webhook {id}, the most
+// recent first. The limit query parameter is at most wh.MaxDeliveryLimit.
+func (hh *webhookHandler) GetDeliveries(w http.ResponseWriter, r *http.Request) {
+	hook, ok := hh.managedWebhook(w, r)
+	if !ok {
+		return
+	}
+	limit := wh.MaxDeliveryLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > wh.MaxDeliveryLimit {
+			server.RespondWithMessage(w, 400, "Bad request. Invalid limit.")
+			return
+		}
+		limit = n
+	}
+	deliveries, err := hh.Repo.GetDeliveries(hook.ID, limit)
+	if err != nil {
+		server.RespondWithMessage(w, 500, "Could not get deliveries")
+		return
+	}
+	server.RespondWithMessage(w, 200, deliveries)
+}
+
+// managedWebhook loads the webhook from the {id} URL parameter. Personal
+// webhooks are managed by their creator, webhooks of an organization by its
+// admins. Webhooks the caller cannot manage are reported as not found. On
+// failure the response is already written.
+func (hh *webhookHandler) managedWebhook(w http.ResponseWriter, r *http.Request) (*wh.Webhook, bool) {
+	user, ok := auth.GetUserFromContext(r.Context())
+	if !ok {
+		server.RespondWithMessage(w, 500, "Could not get user from context")
+		return nil, false
+	}
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		server.RespondWithMessage(w, 400, "Bad request.")
+		return nil, false
+	}
+	hook, err := hh.Repo.GetWebhook(uint(id))
+	if err != nil {
+		server.RespondWithMessage(w, 404, "Webhook not found")
+		return nil, false
+	}
+	if hook.OrgID == 0 && hook.UserID == user {
+		return hook, true
+	}
+	if hook.OrgID != 0 {
+		if member, err := hh.Orgs.GetMember(hook.OrgID, user); err == nil && org.RoleAllows(member.Role, org.RoleAdmin) {
+			return hook, true
+		}
+	}
+	server.RespondWithMessage(w, 404, "Webhook not found")
+	return nil, false
+}
+
+// administeredOrg loads the organization by name and checks that the user is
+// one of its admins. On failure the response is already written.
+func (hh *webhookHandler) administeredOrg(w http.ResponseWriter, name string, user uint) (*org.Organization, bool) {
+	o, err := hh.Orgs.GetOrganizationByName(name)
+	if err != nil {
+		server.RespondWithMessage(w, 404, "Organization not found")
+		return nil, false
+	}
+	member, err := hh.Orgs.GetMember(o.ID, user)
+	if err != nil {
+		server.RespondWithMessage(w, 404, "Organization not found")
+		return nil, false
+	}
+	if !org.RoleAllows(member.Role, org.RoleAdmin) {
+		server.RespondWithMessage(w, 403, fmt.Sprintf("Requires the %s role", org.RoleAdmin))
+		return nil, false
+	}
+	return o, true
+}
@@ -1,19 +1,27 @@
 package models
 
 import (
+	"crypto/ed25519"
 	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	config "passKeeper/config/server"
+	"passKeeper/internal/events"
 	"passKeeper/internal/handlers"
 	acc "passKeeper/internal/models/account"
+	audit "passKeeper/internal/models/audit"
 	auth "passKeeper/internal/models/auth"
 	db "passKeeper/internal/models/database"
+	em "passKeeper/internal/models/emergency"
+	org "passKeeper/internal/models/org"
 	sec "passKeeper/internal/models/secret"
+	wh "passKeeper/internal/models/webhook"
+	"time"
 
 	"github.com/go-chi/chi"
 	"github.com/go-chi/chi/middleware"
+	"github.com/jinzhu/gorm"
 )
 
 type App struct {
@@ -21,17 +29,187 @@ type App struct {
 	accountRepo   db.AccountRepository
 	secretRepo    db.SecretRepository
 	migrationRepo db.MigrationRepository
+	orgRepo       db.OrgRepository
+	emergencyRepo db.EmergencyRepository
+	auditRepo     db.AuditRepository
+	webhookRepo   db.WebhookRepository
+	events        *events.Bus
 	Server        *http.Server
 	JWTConf       auth.JWTSettings
 }
 
-func NewApp(config config.Config, accountRepo db.AccountRepository, secretRepo db.SecretRepository, migrationRepo db.MigrationRepository) *App {
+func NewApp(config config.Config, accountRepo db.AccountRepository, secretRepo db.SecretRepository, migrationRepo db.MigrationRepository, orgRepo db.OrgRepository, emergencyRepo db.EmergencyRepository, auditRepo db.AuditRepository, webhookRepo db.WebhookRepository) *App {
 	jwt := auth.InitJWTPassword(config.JWTPassword, config.ExpirationTime)
-	return &App{config: config, accountRepo: accountRepo, secretRepo: secretRepo, migrationRepo: migrationRepo, JWTConf: jwt}
+	return &App{config: config, accountRepo: accountRepo, secretRepo: secretRepo, migrationRepo: migrationRepo, orgRepo: orgRepo, emergencyRepo: emergencyRepo, auditRepo: auditRepo, webhookRepo: webhookRepo, events: events.NewBus(), JWTConf: jwt}
 }
 
 func (a App) CreateTables() {
-	a.migrationRepo.AutoMigrate(&acc.Account{}, &sec.Secret{})
+	a.migrationRepo.AutoMigrate(&acc.Account{}, &sec.Secret{}, &sec.CertificateInfo{}, &sec.Attachment{}, &sec.Tag{}, &sec.Share{}, &sec.OneTimeShare{},
+		&sec.ChangeRevision{}, &sec.Tombstone{},
+		&org.Organization{}, &org.Member{}, &org.Vault{}, &em.EmergencyContact{}, &em.EmergencyEvent{},
+		&audit.AuditEvent{}, &audit.AuditCheckpoint{}, &wh.Webhook{}, &wh.Delivery{})
+	if n, err := a.migrationRepo.ChainAuditEvents(); err != nil {
+		log.Printf("cannot chain audit events: %s", err)
+	} else if n > 0 {
+		log.Printf("chained %d audit events", n)
+	}
+	if err := a.migrationRepo.BackfillTimestamps(); err != nil {
+		log.Printf("cannot backfill secret timestamps: %s", err)
+	}
+	if err := a.migrationRepo.EnsureIndexes(); err != nil {
+		log.Printf("cannot create indexes: %s", err)
+	}
+	if n, err := a.migrationRepo.BackfillRevisions(); err != nil {
+		log.Printf("cannot backfill secret revisions: %s", err)
+	} else if n > 0 {
+		log.Printf("gave %d secrets a revision", n)
+	}
+	if n, err := a.migrationRepo.MigrateLegacyMetadata(); err != nil {
+		log.Printf("cannot migrate secret metadata: %s", err)
+	} else if n > 0 {
+		log.Printf("migrated metadata of %d secrets", n)
+	}
+}
+
+// trashPurgeInterval is how often the retention job looks for expired trash.
+const trashPurgeInterval = time.Hour
+
+// StartTrashRetention purges secrets which have been in the trash for longer
+// than the configured number of days, now and then every trashPurgeInterval.
+// A retention of zero days or less keeps the trash forever.
+func (a *App) StartTrashRetention() {
+	if a.config.TrashRetentionDays <= 0 {
+		return
+	}
+	retention := time.Duration(a.config.TrashRetentionDays) * 24 * time.Hour
+	go func() {
+		ticker := time.NewTicker(trashPurgeInterval)
+		defer ticker.Stop()
+		for {
+			n, err := a.secretRepo.PurgeTrash(time.Now().Add(-retention))
+			if err != nil {
+				log.Printf("cannot purge trash: %s", err)
+			} else if n > 0 {
+				log.Printf("purged %d secrets from the trash", n)
+			}
+			<-ticker.C
+		}
+	}()
+}
+
+// expiryArchiveInterval is how often expired secrets are moved to the archive.
+const expiryArchiveInterval = 5 * time.Minute
+
+// StartExpiryArchiving archives the secrets whose expiry date has passed and
+// purges expired one-time shares, now and then every expiryArchiveInterval.
+// Expired secrets are not served even before they are archived. Every
+// archived secret publishes an expired event.
+func (a *App) StartExpiryArchiving() {
+	go func() {
+		ticker := time.NewTicker(expiryArchiveInterval)
+		defer ticker.Stop()
+		for {
+			now := time.Now()
+			ids, err := a.secretRepo.ArchiveExpired(now)
+			if err != nil {
+				log.Printf("cannot archive expired secrets: %s", err)
+			} else if len(ids) > 0 {
+				log.Printf("archived %d expired secrets", len(ids))
+			}
+			for _, id := range ids {
+				a.events.Publish(events.Event{Type: wh.EventExpired, SecretID: id, OccurredAt: now})
+			}
+			if n, err := a.secretRepo.PurgeExpiredOneTimeShares(time.Now()); err != nil {
+				log.Printf("cannot purge expired one-time shares: %s", err)
+			} else if n > 0 {
+				log.Printf("purged %d expired one-time shares", n)
+			}
+			<-ticker.C
+		}
+	}()
+}
+
+// emergencyGrantInterval is how often requests for emergency access are
+// checked for a passed waiting period.
+const emergencyGrantInterval = time.Minute
+
+// StartEmergencyGrants grants the requests for emergency access whose waiting
+// period passed without the owner deciding on them, now and then every
+// emergencyGrantInterval.
+func (a *App) StartEmergencyGrants() {
+	go func() {
+		ticker := time.NewTicker(emergencyGrantInterval)
+		defer ticker.Stop()
+		for {
+			n, err := a.emergencyRepo.GrantDueEmergencyRequests(time.Now())
+			if err != nil {
+				log.Printf("cannot grant emergency access: %s", err)
+			} else if n > 0 {
+				log.Printf("granted %d requests for emergency access", n)
+			}
+			<-ticker.C
+		}
+	}()
+}
+
+// StartAuditCheckpoints signs a checkpoint over the last audit event, now and
+// then every AuditCheckpointMinutes, if events were recorded since the last
+// checkpoint. Without a key file no checkpoints are signed.
+func (a *App) StartAuditCheckpoints() {
+	if a.config.AuditKeyFile == "" {
+		log.Println("audit checkpoints are disabled, no key file is configured")
+		return
+	}
+	key, err := audit.LoadSigningKey(a.config.AuditKeyFile)
+	if err != nil {
+		log.Printf("audit checkpoints are disabled, cannot load key: %s", err)
+		return
+	}
+	log.Printf("signing audit checkpoints with public key %s", audit.EncodePublicKey(key.Public().(ed25519.PublicKey)))
+	interval := time.Duration(a.config.AuditCheckpointMinutes) * time.Minute
+	if interval <= 0 {
+		interval = time.Hour
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			if err := a.signAuditCheckpoint(key); err != nil {
+				log.Printf("cannot sign audit checkpoint: %s", err)
+			}
+			<-ticker.C
+		}
+	}()
+}
+
+func (a *App) signAuditCheckpoint(key ed25519.PrivateKey) error {
+	last, err := a.auditRepo.GetLastAuditEvent()
+	if err == gorm.ErrRecordNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if prev, err := a.auditRepo.GetLastAuditCheckpoint(); err == nil && prev.EventID == last.ID {
+		return nil
+	}
+	c := audit.AuditCheckpoint{EventID: last.ID, Hash: last.Hash, CreatedAt: time.Now()}
+	c.Sign(key)
+	return a.auditRepo.SaveAuditCheckpoint(&c)
+}
+
+// webhookRetryInterval is how often due webhook deliveries are retried.
+const webhookRetryInterval = 30 * time.Second
+
+// webhookTimeout limits the time a webhook receiver takes to respond.
+const webhookTimeout = 10 * time.Second
+
+// StartWebhooks posts the published events to the webhooks subscribed to them
+// and retries failed deliveries every webhookRetryInterval.
+func (a *App) StartWebhooks() {
+	dispatcher := events.NewDispatcher(a.webhookRepo, events.NewWebhookClient(webhookTimeout, a.config.WebhookAllowPrivate))
+	a.events.Subscribe(dispatcher.Handle)
+	dispatcher.Start(webhookRetryInterval)
 }
 
 func (a *App) StartWebServer() error {
@@ -55,11 +233,25 @@ func (a *App) newRouter() *chi.Mux {
 	router := chi.NewRouter()
 	router.Use(middleware.Recoverer)
 
-	accountHandler := handlers.NewAccountHandler(a.accountRepo, a.JWTConf)
-	secretHandler := handlers.NewSecretHandler(a.secretRepo, a.JWTConf)
+	accountHandler := handlers.NewAccountHandler(a.accountRepo, a.auditRepo, a.JWTConf)
+	secretHandler := handlers.NewSecretHandler(a.secretRepo, a.accountRepo, a.auditRepo, a.events, a.JWTConf)
+	orgHandler := handlers.NewOrgHandler(a.orgRepo, a.accountRepo, a.JWTConf)
+	vaultHandler := handlers.NewVaultHandler(a.orgRepo, a.JWTConf)
+	oneTimeHandler := handlers.NewOneTimeHandler(a.secretRepo, a.JWTConf)
+	emergencyHandler := handlers.NewEmergencyHandler(a.emergencyRepo, a.accountRepo, a.JWTConf)
+	auditHandler := handlers.NewAuditHandler(a.auditRepo, a.config.AuditExportToken, a.JWTConf)
+	webhookHandler := handlers.NewWebhookHandler(a.webhookRepo, a.orgRepo, a.JWTConf)
+	watchHandler := handlers.NewWatchHandler(a.secretRepo, a.orgRepo, a.events, a.JWTConf)
 
 	router.Mount("/api/account", accountHandler.Route())
 	router.Mount("/api/secret", secretHandler.Route())
+	router.Mount("/api/org", orgHandler.Route())
+	router.Mount("/api/vault", vaultHandler.Route())
+	router.Mount("/api/once", oneTimeHandler.Route())
+	router.Mount("/api/emergency", emergencyHandler.Route())
+	router.Mount("/api/audit", auditHandler.Route())
+	router.Mount("/api/webhook", webhookHandler.Route())
+	router.Mount("/api/watch", watchHandler.Route())
 
 	return router
 }
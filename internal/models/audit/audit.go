@@ -0,0 +1,116 @@
+package models
+
+import (
+	"context"
+	"time"
+)
+
+// Actions recorded in the audit log.
+const (
+	ActionCreate  = "create"
+	ActionRead    = "read"
+	ActionUpdate  = "update"
+	ActionDelete  = "delete"
+	ActionRestore = "restore"
+	ActionPurge   = "purge"
+	ActionShare   = "share"
+	ActionUnshare = "unshare"
+	ActionLogin   = "login"
+)
+
+// Results recorded in the audit log. Requests for secrets the actor may not
+// see or change are denied, everything else which did not succeed failed.
+const (
+	ResultSuccess = "success"
+	ResultDenied  = "denied"
+	ResultFailure = "failure"
+)
+
+// Limits of audit log queries.
+const (
+	DefaultQueryLimit = 100
+	MaxQueryLimit     = 1000
+)
+
+// ResultOf returns the result recorded for a response with the HTTP status.
+func ResultOf(status int) string {
+	switch {
+	case status < 400:
+		return ResultSuccess
+	case status == 401 || status == 403 || status == 404:
+		return ResultDenied
+	default:
+		return ResultFailure
+	}
+}
+
+// AuditEvent records one action of ActorID. OwnerID is the owner of the
+// secret at the time of the action, so that owners see who read or changed
+// their secrets. Both are zero for failed logins of unknown accounts, the
+// secret ID is zero for actions on no single secret. Audit events are never
+// changed or deleted, and chained by their hashes so that changes show.
+type AuditEvent struct {
+	ID        uint `gorm:"primary_key"`
+	ActorID   uint `gorm:"index"`
+	OwnerID   uint `gorm:"index"`
+	SecretID  uint `gorm:"index"`
+	Action    string
+	Result    string
+	ClientIP  string
+	UserAgent string
+	CreatedAt time.Time `gorm:"index"`
+	// PrevHash is the hash of the event before this one, Hash the hash of
+	// this event including PrevHash, see ComputeHash.
+	PrevHash string
+	Hash     string
+	// Actor is the login of the actor, filled in for queries.
+	Actor string `gorm:"-" json:",omitempty"`
+}
+
+// Query selects the audit events of a user, optionally only the ones since a
+// time or of one secret, newest first.
+type Query struct {
+	Since    *time.Time
+	SecretID uint
+	Limit    int
+}
+
+// Trail collects what a request did for the audit log. The audit middleware
+// records Action on SecretID with the result of the response, or Events
+// instead if the handler added any for a request touching several secrets.
+type Trail struct {
+	Action   string
+	ActorID  uint
+	SecretID uint
+	OwnerID  uint
+	Events   []AuditEvent
+}
+
+// SetSecret records the secret the request acts on and its owner.
+func (t *Trail) SetSecret(id, ownerID uint) {
+	t.SecretID = id
+	t.OwnerID = ownerID
+}
+
+// Add records an action on one of several secrets of the request with its own
+// result.
+func (t *Trail) Add(action string, secretID, ownerID uint, result string) {
+	t.Events = append(t.Events, AuditEvent{Action: action, SecretID: secretID, OwnerID: ownerID, Result: result})
+}
+
+type trailKey struct{}
+
+// NewContext returns a context carrying the trail.
+func NewContext(ctx context.Context, t *Trail) context.Context {
+	return context.WithValue(ctx, trailKey{}, t)
+}
+
+// FromContext returns the trail of the request. Requests which are not
+// audited get a trail which is not recorded, so that handlers never need to
+// check.
+func FromContext(ctx context.Context) *Trail {
+	if t, ok := ctx.Value(trailKey{}).(*Trail); ok {
+		return t
+	}
+	return &Trail{}
+}
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"context"
+	"testing"
+)
+
+func TestResultOf(t *testing.T) {
+	tests := []struct {
+		status int
+		want   string
+	}{
+		{200, ResultSuccess},
+		{204, ResultSuccess},
+		{400, ResultFailure},
+		{401, ResultDenied},
+		{403, ResultDenied},
+		{404, ResultDenied},
+		{409, ResultFailure},
+		{500, ResultFailure},
+	}
+
+	for _, tt := range tests {
+		if got := ResultOf(tt.status); got != tt.want {
+			t.Errorf("ResultOf(%d) = %s, want %s", tt.status, got, tt.want)
+		}
+	}
+}
+
+func TestTrailContext(t *testing.T) {
+	trail := &Trail{Action: ActionRead}
+	ctx := NewContext(context.Background(), trail)
+	FromContext(ctx).SetSecret(7, 3)
+	if trail.SecretID != 7 || trail.OwnerID != 3 {
+		t.Errorf("expected the trail of the context to be changed, got %+v", trail)
+	}
+
+	// Requests without a trail get a fresh one which is not shared.
+	FromContext(context.Background()).SetSecret(1, 1)
+	if FromContext(context.Background()).SecretID != 0 {
+		t.Errorf("expected a fresh trail without a secret")
+	}
+}
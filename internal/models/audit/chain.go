@@ -0,0 +1,236 @@
+package models
+
+import (
+	"bufio"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// chainedFields are the fields of an audit event covered by its hash, in a
+// fixed encoding.
+type chainedFields struct {
+	PrevHash  string
+	ActorID   uint
+	OwnerID   uint
+	SecretID  uint
+	Action    string
+	Result    string
+	ClientIP  string
+	UserAgent string
+	CreatedAt string
+}
+
+// ChainTime truncates a time to the precision the database stores, so that
+// hashes computed before and after storing an event match.
+func ChainTime(t time.Time) time.Time {
+	return t.UTC().Truncate(time.Microsecond)
+}
+
+// ComputeHash returns the hash chaining the event to the event before it,
+// whose hash is PrevHash. The first event has an empty PrevHash.
+func (e *AuditEvent) ComputeHash() string {
+	data, _ := json.Marshal(chainedFields{
+		PrevHash:  e.PrevHash,
+		ActorID:   e.ActorID,
+		OwnerID:   e.OwnerID,
+		SecretID:  e.SecretID,
+		Action:    e.Action,
+		Result:    e.Result,
+		ClientIP:  e.ClientIP,
+		UserAgent: e.UserAgent,
+		CreatedAt: ChainTime(e.CreatedAt).Format(time.RFC3339Nano),
+	})
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Chain links the event to the event with the hash prev and sets its hash.
+func (e *AuditEvent) Chain(prev string) {
+	e.CreatedAt = ChainTime(e.CreatedAt)
+	e.PrevHash = prev
+	e.Hash = e.ComputeHash()
+}
+
+// AuditCheckpoint is a signature of the server over the hash of the event
+// EventID, which vouches for that event and, through the chain, for every
+// event before it. Checkpoints are never changed or deleted.
+type AuditCheckpoint struct {
+	ID        uint `gorm:"primary_key"`
+	EventID   uint `gorm:"index"`
+	Hash      string
+	CreatedAt time.Time
+	// Signature is the base64 encoded Ed25519 signature of Message.
+	Signature string
+}
+
+// Message returns the signed content of the checkpoint.
+func (c *AuditCheckpoint) Message() []byte {
+	return []byte(fmt.Sprintf("passKeeper audit checkpoint %d %s %s", c.EventID, c.Hash,
+		ChainTime(c.CreatedAt).Format(time.RFC3339Nano)))
+}
+
+// Sign sets the signature of the checkpoint.
+func (c *AuditCheckpoint) Sign(key ed25519.PrivateKey) {
+	c.CreatedAt = ChainTime(c.CreatedAt)
+	c.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(key, c.Message()))
+}
+
+// Verify reports whether the checkpoint was signed with the key.
+func (c *AuditCheckpoint) Verify(key ed25519.PublicKey) bool {
+	sig, err := base64.StdEncoding.DecodeString(c.Signature)
+	if err != nil {
+		return false
+	}
+	return ed25519.Verify(key, c.Message(), sig)
+}
+
+// ExportRecord is one line of an audit log export: an event, or a checkpoint
+// following the event it covers.
+type ExportRecord struct {
+	Event      *AuditEvent      `json:"event,omitempty"`
+	Checkpoint *AuditCheckpoint `json:"checkpoint,omitempty"`
+}
+
+// LoadSigningKey reads the Ed25519 key signing checkpoints from a PEM file,
+// creating the file with a new key if it does not exist.
+func LoadSigningKey(path string) (ed25519.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		der, err := x509.MarshalPKCS8PrivateKey(key)
+		if err != nil {
+			return nil, err
+		}
+		data = pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+		if err := os.WriteFile(path, data, 0600); err != nil {
+			return nil, err
+		}
+		return key, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s is no PEM file", path)
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := parsed.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%s holds no Ed25519 key", path)
+	}
+	return key, nil
+}
+
+// EncodePublicKey returns the base64 form of the public key given to
+// auditors.
+func EncodePublicKey(key ed25519.PublicKey) string {
+	return base64.StdEncoding.EncodeToString(key)
+}
+
+// ParsePublicKey parses the base64 form of a public key.
+func ParsePublicKey(s string) (ed25519.PublicKey, error) {
+	data, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+	if err != nil || len(data) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid public key, expected %d base64 encoded bytes", ed25519.PublicKeySize)
+	}
+	return ed25519.PublicKey(data), nil
+}
+
+// BrokenLink reports where the verification of an export failed.
+type BrokenLink struct {
+	Line    int
+	EventID uint
+	Reason  string
+}
+
+func (b *BrokenLink) Error() string {
+	if b.EventID != 0 {
+		return fmt.Sprintf("line %d (event %d): %s", b.Line, b.EventID, b.Reason)
+	}
+	return fmt.Sprintf("line %d: %s", b.Line, b.Reason)
+}
+
+// VerifyReport summarizes a verified export. Events after the last
+// checkpoint are chained but not yet vouched for by a signature.
+type VerifyReport struct {
+	Events         int
+	Checkpoints    int
+	LastEventID    uint
+	LastCheckpoint *AuditCheckpoint
+	Unsigned       int
+}
+
+// Verify checks an export from its first event: every event must chain to
+// the one before it, and every checkpoint must be signed with the key and
+// cover the event before it. The first broken link is returned as a
+// *BrokenLink error.
+func Verify(r io.Reader, key ed25519.PublicKey) (*VerifyReport, error) {
+	report := &VerifyReport{}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	prev := ""
+	line := 0
+	for scanner.Scan() {
+		line++
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var rec ExportRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			return report, &BrokenLink{Line: line, Reason: "not a record: " + err.Error()}
+		}
+		switch {
+		case rec.Event != nil:
+			e := rec.Event
+			if report.Events > 0 && e.ID <= report.LastEventID {
+				return report, &BrokenLink{Line: line, EventID: e.ID, Reason: fmt.Sprintf("event follows event %d", report.LastEventID)}
+			}
+			if e.PrevHash != prev {
+				return report, &BrokenLink{Line: line, EventID: e.ID, Reason: "does not chain to the event before it, events are missing or were changed"}
+			}
+			if e.ComputeHash() != e.Hash {
+				return report, &BrokenLink{Line: line, EventID: e.ID, Reason: "hash does not match the event, it was changed"}
+			}
+			prev = e.Hash
+			report.Events++
+			report.Unsigned++
+			report.LastEventID = e.ID
+		case rec.Checkpoint != nil:
+			c := rec.Checkpoint
+			if report.Events == 0 || c.EventID != report.LastEventID || c.Hash != prev {
+				return report, &BrokenLink{Line: line, EventID: c.EventID, Reason: "checkpoint does not cover the event before it"}
+			}
+			if !c.Verify(key) {
+				return report, &BrokenLink{Line: line, EventID: c.EventID, Reason: "checkpoint signature is invalid"}
+			}
+			report.Checkpoints++
+			report.Unsigned = 0
+			report.LastCheckpoint = c
+		default:
+			return report, &BrokenLink{Line: line, Reason: "empty record"}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return report, err
+	}
+	return report, nil
+}
@@ -0,0 +1,105 @@
+package models
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func exportOf(t *testing.T, records []ExportRecord) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, rec := range records {
+		if err := enc.Encode(rec); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return &buf
+}
+
+func chainOf(key ed25519.PrivateKey) []ExportRecord {
+	at := time.Date(2024, 3, 1, 12, 0, 0, 123456789, time.UTC)
+	events := []AuditEvent{
+		{ID: 1, ActorID: 1, Action: ActionLogin, Result: ResultSuccess, ClientIP: "10.0.0.1", CreatedAt: at},
+		{ID: 2, ActorID: 1, OwnerID: 1, SecretID: 7, Action: ActionRead, Result: ResultSuccess, CreatedAt: at.Add(time.Second)},
+		{ID: 4, ActorID: 2, OwnerID: 1, SecretID: 7, Action: ActionRead, Result: ResultDenied, CreatedAt: at.Add(2 * time.Second)},
+	}
+	var records []ExportRecord
+	prev := ""
+	for i := range events {
+		events[i].Chain(prev)
+		prev = events[i].Hash
+		records = append(records, ExportRecord{Event: &events[i]})
+		if i == 1 {
+			c := AuditCheckpoint{ID: 1, EventID: events[i].ID, Hash: events[i].Hash, CreatedAt: at.Add(time.Minute)}
+			c.Sign(key)
+			records = append(records, ExportRecord{Checkpoint: &c})
+		}
+	}
+	return records
+}
+
+func TestVerify(t *testing.T) {
+	pub, key, _ := ed25519.GenerateKey(nil)
+	otherPub, _, _ := ed25519.GenerateKey(nil)
+
+	report, err := Verify(exportOf(t, chainOf(key)), pub)
+	if err != nil {
+		t.Fatalf("didn't expect error, got %v", err)
+	}
+	if report.Events != 3 || report.Checkpoints != 1 || report.Unsigned != 1 || report.LastEventID != 4 {
+		t.Errorf("unexpected report %+v", report)
+	}
+
+	tests := []struct {
+		name   string
+		tamper func([]ExportRecord) []ExportRecord
+		key    ed25519.PublicKey
+		line   int
+	}{
+		{"changed event", func(r []ExportRecord) []ExportRecord { r[1].Event.Result = ResultDenied; return r }, pub, 2},
+		{"removed event", func(r []ExportRecord) []ExportRecord { return append(r[:1], r[2:]...) }, pub, 2},
+		{"rehashed event", func(r []ExportRecord) []ExportRecord {
+			r[0].Event.ClientIP = "10.0.0.9"
+			r[0].Event.Chain("")
+			return r
+		}, pub, 2},
+		{"foreign key", func(r []ExportRecord) []ExportRecord { return r }, otherPub, 3},
+	}
+	for _, tt := range tests {
+		records := tt.tamper(chainOf(key))
+		_, err := Verify(exportOf(t, records), tt.key)
+		var broken *BrokenLink
+		if !errors.As(err, &broken) {
+			t.Errorf("%s: expected a broken link, got %v", tt.name, err)
+			continue
+		}
+		if broken.Line != tt.line {
+			t.Errorf("%s: expected line %d to be broken, got %v", tt.name, tt.line, broken)
+		}
+	}
+}
+
+func TestLoadSigningKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.pem")
+	created, err := LoadSigningKey(path)
+	if err != nil {
+		t.Fatalf("didn't expect error, got %v", err)
+	}
+	loaded, err := LoadSigningKey(path)
+	if err != nil {
+		t.Fatalf("didn't expect error, got %v", err)
+	}
+	if !created.Equal(loaded) {
+		t.Errorf("expected the created key to be loaded again")
+	}
+	pub, err := ParsePublicKey(EncodePublicKey(loaded.Public().(ed25519.PublicKey)))
+	if err != nil || !pub.Equal(created.Public()) {
+		t.Errorf("expected the public key to round-trip, got %v", err)
+	}
+}
@@ -3,11 +3,18 @@ package models
 import (
 	"errors"
 	"log"
+	"sort"
+	"strings"
+	"time"
 
 	acc "passKeeper/internal/models/account"
+	audit "passKeeper/internal/models/audit"
 	auth "passKeeper/internal/models/auth"
+	em "passKeeper/internal/models/emergency"
+	org "passKeeper/internal/models/org"
 	sec "passKeeper/internal/models/secret"
 	server "passKeeper/internal/models/server"
+	wh "passKeeper/internal/models/webhook"
 
 	"github.com/jinzhu/gorm"
 	_ "github.com/jinzhu/gorm/dialects/postgres"
@@ -33,23 +40,184 @@ func GetMigrationRepo(db *gorm.DB) MigrationRepository {
 	return &GormRepository{db: db}
 }
 
+func GetOrgRepo(db *gorm.DB) OrgRepository {
+	return &GormRepository{db: db}
+}
+
+func GetEmergencyRepo(db *gorm.DB) EmergencyRepository {
+	return &GormRepository{db: db}
+}
+
+func GetAuditRepo(db *gorm.DB) AuditRepository {
+	return &GormRepository{db: db}
+}
+
+func GetWebhookRepo(db *gorm.DB) WebhookRepository {
+	return &GormRepository{db: db}
+}
+
 type AccountRepository interface {
 	CreateAccount(account *acc.Account, jwtSettings auth.JWTSettings) server.Response
 	ValidateAccount(account *acc.Account) server.Response
 	LoginAccount(email, password string, jwtSettings auth.JWTSettings) server.Response
+	GetAccountByLogin(login string) (*acc.Account, error)
 }
 
 type SecretRepository interface {
 	GetSecretByID(secretID uint) (*sec.Secret, error)
 	SaveSecret(s *sec.Secret) (*sec.Secret, error)
-	GetSecretsForUser(userID uint) ([]sec.Secret, error)
+	GetSecretsForUser(userID uint, filter SecretFilter) ([]sec.Secret, error)
+	GetSecretPage(userID uint, filter SecretFilter) (*sec.SecretPage, error)
 	DeleteSecret(s *sec.Secret) error
+	GetTrash(userID, vaultID uint) ([]sec.Secret, error)
+	GetTrashedSecret(secretID uint) (*sec.Secret, error)
+	RestoreSecret(s *sec.Secret) error
+	PurgeSecret(s *sec.Secret) error
+	EmptyTrash(userID, vaultID uint) ([]sec.Secret, error)
+	PurgeTrash(deletedBefore time.Time) (int, error)
+	SaveCertificateInfo(info *sec.CertificateInfo) error
+	GetExpiringCertificates(userID uint, before time.Time) ([]sec.CertificateInfo, error)
+	SaveAttachment(a *sec.Attachment) error
+	GetAttachments(secretID uint) ([]sec.Attachment, error)
+	GetAttachment(secretID uint, filename string) (*sec.Attachment, error)
+	DeleteAttachment(secretID uint, filename string) error
+	AddTag(s *sec.Secret, name string) error
+	RemoveTag(s *sec.Secret, name string) error
+	GetSecretByPath(userID, vaultID uint, path string) (*sec.Secret, error)
+	GetSecretsByPathPrefix(userID, vaultID uint, prefix string) ([]sec.Secret, error)
+	MoveSecret(s *sec.Secret, path string) error
+	CopySecret(s *sec.Secret, path string) (*sec.Secret, error)
+	SearchSecrets(userID uint, q sec.SearchQuery, limit int) ([]sec.Secret, error)
+	GetExpiringSecrets(userID, vaultID uint, before time.Time) ([]sec.Secret, error)
+	SetSecretExpiry(s *sec.Secret, expiresAt *time.Time) error
+	ArchiveExpired(now time.Time) ([]uint, error)
+	SaveOneTimeShare(share *sec.OneTimeShare) error
+	OpenOneTimeShare(token string, now time.Time) (*sec.OneTimeShare, error)
+	PurgeExpiredOneTimeShares(now time.Time) (int, error)
+	Transaction(fn func(repo SecretRepository) error) error
+	RecordAccess(s *sec.Secret, at time.Time) error
+	GetStaleSecrets(userID uint, unusedSince time.Time) ([]sec.Secret, error)
+	SaveShare(share *sec.Share) error
+	GetShare(secretID, userID uint) (*sec.Share, error)
+	GetShares(secretID uint) ([]sec.Share, error)
+	DeleteShare(secretID, userID uint) error
+	GetSharedSecrets(userID uint) ([]sec.SharedSecret, error)
+	GetVaultRole(vaultID, userID uint) (string, error)
+	GetVault(vaultID uint) (*org.Vault, error)
+	MoveSecretToVault(s *sec.Secret, vault *org.Vault, userID uint) error
+	HasEmergencyAccess(ownerID, contactID uint) (bool, error)
+	GetEventScope(secretID uint) (userID, orgID uint, err error)
+	GetChanges(userID uint, since uint64, limit int) (*sec.ChangeFeed, error)
+}
+
+type OrgRepository interface {
+	CreateOrganization(o *org.Organization, ownerID uint) error
+	GetOrganizationByName(name string) (*org.Organization, error)
+	GetOrganizations(userID uint) ([]org.Organization, error)
+	GetMember(orgID, userID uint) (*org.Member, error)
+	GetMembers(orgID uint) ([]org.Member, error)
+	SaveMember(m *org.Member) error
+	DeleteMember(orgID, userID uint) error
+	CountOwners(orgID uint) (int, error)
+	CreateVault(v *org.Vault) error
+	GetVaults(orgID uint) ([]org.Vault, error)
+	GetVaultByName(orgID uint, name string) (*org.Vault, error)
+	DeleteVault(v *org.Vault) error
+	GetPersonalVaults(userID uint) ([]org.Vault, error)
+	GetPersonalVaultByName(userID uint, name string) (*org.Vault, error)
+	PurgeVault(v *org.Vault) (int, error)
+}
+
+type EmergencyRepository interface {
+	CreateEmergencyContact(c *em.EmergencyContact) error
+	SetEmergencyWait(c *em.EmergencyContact, waitHours int) error
+	GetEmergencyContact(ownerID, contactID uint) (*em.EmergencyContact, error)
+	GetEmergencyContacts(ownerID uint) ([]em.EmergencyContact, error)
+	GetEmergencyOwners(contactID uint) ([]em.EmergencyContact, error)
+	DeleteEmergencyContact(c *em.EmergencyContact) error
+	TransitionEmergencyAccess(c *em.EmergencyContact, action string, actorID uint, now time.Time) error
+	GrantDueEmergencyRequests(now time.Time) (int, error)
+	GetEmergencyEvents(contactID uint) ([]em.EmergencyEvent, error)
+	GetEmergencySecrets(ownerID uint) ([]sec.Secret, error)
+}
+
+// AuditRepository appends to the audit log, it has no way of changing or
+// deleting audit events.
+type AuditRepository interface {
+	RecordAuditEvents(events []audit.AuditEvent) error
+	GetAuditEvents(userID uint, q audit.Query) ([]audit.AuditEvent, error)
+	GetLastAuditEvent() (*audit.AuditEvent, error)
+	GetLastAuditCheckpoint() (*audit.AuditCheckpoint, error)
+	SaveAuditCheckpoint(c *audit.AuditCheckpoint) error
+	ExportAudit(fn func(rec audit.ExportRecord) error) error
+}
+
+// WebhookRepository keeps the webhooks of accounts and organizations and the
+// log of their deliveries.
+type WebhookRepository interface {
+	CreateWebhook(h *wh.Webhook) error
+	GetWebhooks(userID uint) ([]wh.Webhook, error)
+	GetOrgWebhooks(orgID uint) ([]wh.Webhook, error)
+	GetWebhook(id uint) (*wh.Webhook, error)
+	DeleteWebhook(h *wh.Webhook) error
+	GetDeliveries(webhookID uint, limit int) ([]wh.Delivery, error)
+	GetEventScope(secretID uint) (userID, orgID uint, err error)
+	GetMatchingWebhooks(userID, orgID uint) ([]wh.Webhook, error)
+	CreateDelivery(d *wh.Delivery) error
+	ClaimDelivery(id uint, now, until time.Time) (bool, error)
+	SaveDelivery(d *wh.Delivery) error
+	GetDueDeliveries(now time.Time, limit int) ([]wh.Delivery, error)
+}
+
+// SecretFilter narrows down the secrets returned for a user, or of the vault
+// VaultID if it is not zero. Secrets match
+// Tags if they carry any of them, or all of them when MatchAllTags is set,
+// and Types if their type is one of them. Sort is one of the sec.Sort*
+// orders, pages of Limit secrets start after Cursor.
+type SecretFilter struct {
+	Tags         []string
+	MatchAllTags bool
+	Types        []string
+	VaultID      uint
+	Sort         string
+	Desc         bool
+	Limit        int
+	Cursor       *sec.PageCursor
+}
+
+// sortExpressions maps the sort orders to the SQL expression ordered by.
+// Names fall back to the filename and description like SecretMeta.Label,
+// secrets without any of them sort as the empty name like in
+// sec.CursorAfter, NULL would never match the cursor.
+var sortExpressions = map[string]string{
+	sec.SortName:    "LOWER(COALESCE(NULLIF(name, ''), NULLIF(file_filename, ''), description, ''))",
+	sec.SortCreated: "created_at",
+	sec.SortUpdated: "updated_at",
+	sec.SortType:    "secret_type",
+	// Secrets which were never read sort as the zero time, see
+	// sec.CursorAfter.
+	sec.SortAccessed: "COALESCE(last_accessed_at, '0001-01-01 00:00:00+00')",
+	sec.SortAccesses: "access_count",
 }
 
 type MigrationRepository interface {
 	AutoMigrate(models ...interface{}) error
+	EnsureIndexes() error
+	BackfillTimestamps() error
+	MigrateLegacyMetadata() (int, error)
+	ChainAuditEvents() (int, error)
+	BackfillRevisions() (int, error)
 }
 
+// secretColumnsWithoutValue selects everything but the value of a secret.
+const secretColumnsWithoutValue = "id, user_id, secret_type, metadata, name, description, " +
+	"file_filename, file_mime_type, file_size, file_sha256, file_mode, path, created_at, updated_at, deleted_at, " +
+	"expires_at, archived_at, last_accessed_at, access_count, vault_id"
+
+// servedSecrets matches the secrets which are neither archived nor expired.
+// Expired secrets are excluded before ArchiveExpired has archived them.
+const servedSecrets = "archived_at IS NULL AND (expires_at IS NULL OR expires_at > now())"
+
 type GormRepository struct {
 	db *gorm.DB
 }
@@ -61,6 +229,208 @@ func (g *GormRepository) AutoMigrate(models ...interface{}) error {
 	}
 	return nil
 }
+
+// EnsureIndexes creates the indexes which cannot be expressed with gorm tags.
+func (g *GormRepository) EnsureIndexes() error {
+	statements := []string{
+		// Paths are unique among the personal secrets of a user and among the
+		// secrets of a vault which are not in the trash, secrets without a
+		// path are not restricted.
+		`DROP INDEX IF EXISTS idx_secret_user_path`,
+		`DROP INDEX IF EXISTS idx_secret_user_live_path`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_secret_personal_path ON secrets (user_id, path) WHERE path <> '' AND deleted_at IS NULL AND vault_id = 0`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_secret_vault_path ON secrets (vault_id, path) WHERE path <> '' AND deleted_at IS NULL AND vault_id <> 0`,
+		// Vault names are unique per organization and per account.
+		`DROP INDEX IF EXISTS idx_vault_org_name`,
+		// Certificate details were stored without a primary key, so every
+		// save added a row. The newest row of a secret is kept.
+		`DELETE FROM certificate_infos a USING certificate_infos b WHERE a.secret_id = b.secret_id AND a.ctid < b.ctid`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_certificate_info_secret ON certificate_infos (secret_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_secret_user_type ON secrets (user_id, LOWER(secret_type))`,
+		// Keyset pagination walks these indexes in sort order, personal
+		// listings the ones of the user, vault listings the ones of the
+		// vault. They index exactly the expressions of sortExpressions,
+		// which Postgres needs to use them.
+		`DROP INDEX IF EXISTS idx_secret_user_name_sort`,
+		`CREATE INDEX IF NOT EXISTS idx_secret_user_name_order ON secrets (user_id, ` + sortExpressions[sec.SortName] + `, id)`,
+		`CREATE INDEX IF NOT EXISTS idx_secret_user_created ON secrets (user_id, ` + sortExpressions[sec.SortCreated] + `, id)`,
+		`CREATE INDEX IF NOT EXISTS idx_secret_user_updated ON secrets (user_id, ` + sortExpressions[sec.SortUpdated] + `, id)`,
+		`CREATE INDEX IF NOT EXISTS idx_secret_user_secret_type ON secrets (user_id, ` + sortExpressions[sec.SortType] + `, id)`,
+		`CREATE INDEX IF NOT EXISTS idx_secret_user_accessed ON secrets (user_id, ` + sortExpressions[sec.SortAccessed] + `, id)`,
+		`CREATE INDEX IF NOT EXISTS idx_secret_user_access_count ON secrets (user_id, ` + sortExpressions[sec.SortAccesses] + `, id)`,
+		`CREATE INDEX IF NOT EXISTS idx_secret_vault_name_order ON secrets (vault_id, ` + sortExpressions[sec.SortName] + `, id)`,
+		`CREATE INDEX IF NOT EXISTS idx_secret_vault_created ON secrets (vault_id, ` + sortExpressions[sec.SortCreated] + `, id)`,
+		`CREATE INDEX IF NOT EXISTS idx_secret_vault_updated ON secrets (vault_id, ` + sortExpressions[sec.SortUpdated] + `, id)`,
+		`CREATE INDEX IF NOT EXISTS idx_secret_vault_secret_type ON secrets (vault_id, ` + sortExpressions[sec.SortType] + `, id)`,
+		`CREATE INDEX IF NOT EXISTS idx_secret_vault_accessed ON secrets (vault_id, ` + sortExpressions[sec.SortAccessed] + `, id)`,
+		`CREATE INDEX IF NOT EXISTS idx_secret_vault_access_count ON secrets (vault_id, ` + sortExpressions[sec.SortAccesses] + `, id)`,
+		// Trigram indexes serve the case-insensitive LIKE patterns of
+		// SearchSecrets, including the ones with a leading wildcard.
+		`CREATE EXTENSION IF NOT EXISTS pg_trgm`,
+		`CREATE INDEX IF NOT EXISTS idx_secret_metadata_trgm ON secrets USING gin (LOWER(metadata) gin_trgm_ops)`,
+		`CREATE INDEX IF NOT EXISTS idx_secret_path_trgm ON secrets USING gin (LOWER(path) gin_trgm_ops)`,
+		`CREATE INDEX IF NOT EXISTS idx_secret_name_trgm ON secrets USING gin (LOWER(name) gin_trgm_ops)`,
+		`CREATE INDEX IF NOT EXISTS idx_secret_description_trgm ON secrets USING gin (LOWER(description) gin_trgm_ops)`,
+		`CREATE INDEX IF NOT EXISTS idx_secret_filename_trgm ON secrets USING gin (LOWER(file_filename) gin_trgm_ops)`,
+		`CREATE INDEX IF NOT EXISTS idx_tag_name_trgm ON tags USING gin (name gin_trgm_ops)`,
+		// The audit log is append-only, even for direct access to the
+		// database.
+		`CREATE OR REPLACE FUNCTION audit_events_append_only() RETURNS trigger AS $$
+		BEGIN
+			RAISE EXCEPTION 'audit events cannot be changed or deleted';
+		END $$ LANGUAGE plpgsql`,
+		`DROP TRIGGER IF EXISTS audit_events_append_only ON audit_events`,
+		`CREATE TRIGGER audit_events_append_only BEFORE UPDATE OR DELETE OR TRUNCATE ON audit_events
+		FOR EACH STATEMENT EXECUTE PROCEDURE audit_events_append_only()`,
+		`DROP TRIGGER IF EXISTS audit_checkpoints_append_only ON audit_checkpoints`,
+		`CREATE TRIGGER audit_checkpoints_append_only BEFORE UPDATE OR DELETE OR TRUNCATE ON audit_checkpoints
+		FOR EACH STATEMENT EXECUTE PROCEDURE audit_events_append_only()`,
+		// Every change of a secret takes the next revision, see
+		// sec.ChangeRevision. The counter starts above the revisions handed
+		// out per account before. Reads only count accesses and keep the
+		// revision. Setting the revision to zero asks for a new one, which
+		// changes of tags, attachments, shares and members do. Secrets
+		// leaving an account or a vault leave a tombstone behind.
+		`INSERT INTO change_revisions (id, revision)
+		SELECT 1, GREATEST((SELECT COALESCE(MAX(revision), 0) FROM secrets), (SELECT COALESCE(MAX(revision), 0) FROM tombstones))
+		ON CONFLICT (id) DO NOTHING`,
+		`DROP FUNCTION IF EXISTS next_user_revision(bigint)`,
+		`CREATE OR REPLACE FUNCTION next_revision() RETURNS bigint AS $$
+			UPDATE change_revisions SET revision = revision + 1 WHERE id = 1
+			RETURNING revision
+		$$ LANGUAGE sql`,
+		`CREATE OR REPLACE FUNCTION add_tombstone(sid bigint, uid bigint, vid bigint) RETURNS void AS $$
+			INSERT INTO tombstones (secret_id, user_id, vault_id, revision, removed_at)
+			VALUES (sid, CASE WHEN vid = 0 THEN uid ELSE 0 END, vid, next_revision(), now())
+		$$ LANGUAGE sql`,
+		`CREATE OR REPLACE FUNCTION secrets_revision() RETURNS trigger AS $$
+		BEGIN
+			IF TG_OP = 'UPDATE' AND NEW.revision <> 0 AND
+				to_jsonb(NEW) - 'revision' - 'last_accessed_at' - 'access_count' =
+				to_jsonb(OLD) - 'revision' - 'last_accessed_at' - 'access_count' THEN
+				NEW.revision := OLD.revision;
+				RETURN NEW;
+			END IF;
+			IF TG_OP = 'UPDATE' AND (NEW.user_id <> OLD.user_id OR NEW.vault_id <> OLD.vault_id) THEN
+				PERFORM add_tombstone(OLD.id, OLD.user_id, OLD.vault_id);
+			END IF;
+			NEW.revision := next_revision();
+			RETURN NEW;
+		END $$ LANGUAGE plpgsql`,
+		`DROP TRIGGER IF EXISTS secrets_revision ON secrets`,
+		`CREATE TRIGGER secrets_revision BEFORE INSERT OR UPDATE ON secrets
+		FOR EACH ROW EXECUTE PROCEDURE secrets_revision()`,
+		`CREATE OR REPLACE FUNCTION secrets_tombstone() RETURNS trigger AS $$
+		BEGIN
+			PERFORM add_tombstone(OLD.id, OLD.user_id, OLD.vault_id);
+			RETURN NULL;
+		END $$ LANGUAGE plpgsql`,
+		`DROP TRIGGER IF EXISTS secrets_tombstone ON secrets`,
+		`CREATE TRIGGER secrets_tombstone AFTER DELETE ON secrets
+		FOR EACH ROW EXECUTE PROCEDURE secrets_tombstone()`,
+		// A revoked share leaves a tombstone for the recipient, then the
+		// secret takes a new revision for those who still read it. Members
+		// leaving an organization get a tombstone for every secret of its
+		// vaults, new members a new revision of them.
+		`CREATE OR REPLACE FUNCTION shares_revision() RETURNS trigger AS $$
+		BEGIN
+			IF TG_OP = 'DELETE' THEN
+				PERFORM add_tombstone(OLD.secret_id, OLD.user_id, 0);
+				UPDATE secrets SET revision = 0 WHERE id = OLD.secret_id;
+			ELSE
+				UPDATE secrets SET revision = 0 WHERE id = NEW.secret_id;
+			END IF;
+			RETURN NULL;
+		END $$ LANGUAGE plpgsql`,
+		`DROP TRIGGER IF EXISTS shares_revision ON shares`,
+		`CREATE TRIGGER shares_revision AFTER INSERT OR DELETE ON shares
+		FOR EACH ROW EXECUTE PROCEDURE shares_revision()`,
+		`CREATE OR REPLACE FUNCTION members_revision() RETURNS trigger AS $$
+		BEGIN
+			IF TG_OP = 'DELETE' THEN
+				PERFORM add_tombstone(s.id, OLD.user_id, 0)
+				FROM secrets s JOIN vaults v ON v.id = s.vault_id WHERE v.org_id = OLD.org_id;
+			ELSE
+				UPDATE secrets SET revision = 0
+				WHERE vault_id IN (SELECT id FROM vaults WHERE org_id = NEW.org_id);
+			END IF;
+			RETURN NULL;
+		END $$ LANGUAGE plpgsql`,
+		`DROP TRIGGER IF EXISTS members_revision ON members`,
+		`CREATE TRIGGER members_revision AFTER INSERT OR DELETE ON members
+		FOR EACH ROW EXECUTE PROCEDURE members_revision()`,
+		`CREATE OR REPLACE FUNCTION secret_parts_revision() RETURNS trigger AS $$
+		BEGIN
+			IF TG_OP = 'DELETE' THEN
+				UPDATE secrets SET revision = 0 WHERE id = OLD.secret_id;
+			ELSE
+				UPDATE secrets SET revision = 0 WHERE id = NEW.secret_id;
+			END IF;
+			RETURN NULL;
+		END $$ LANGUAGE plpgsql`,
+		`DROP TRIGGER IF EXISTS secret_tags_revision ON secret_tags`,
+		`CREATE TRIGGER secret_tags_revision AFTER INSERT OR DELETE ON secret_tags
+		FOR EACH ROW EXECUTE PROCEDURE secret_parts_revision()`,
+		`DROP TRIGGER IF EXISTS attachments_revision ON attachments`,
+		`CREATE TRIGGER attachments_revision AFTER INSERT OR UPDATE OR DELETE ON attachments
+		FOR EACH ROW EXECUTE PROCEDURE secret_parts_revision()`,
+		// The change feed walks these indexes in revision order.
+		`CREATE INDEX IF NOT EXISTS idx_secret_user_revision ON secrets (user_id, revision)`,
+		`CREATE INDEX IF NOT EXISTS idx_secret_vault_revision ON secrets (vault_id, revision)`,
+		`CREATE INDEX IF NOT EXISTS idx_tombstone_user_revision ON tombstones (user_id, revision)`,
+		`CREATE INDEX IF NOT EXISTS idx_tombstone_vault_revision ON tombstones (vault_id, revision)`,
+	}
+	for _, stmt := range statements {
+		if err := g.db.Exec(stmt).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MigrateLegacyMetadata fills the typed metadata of secrets which only have
+// the legacy Metadata string and returns the number of migrated secrets.
+// The typed columns of secrets stored before they existed are NULL.
+func (g *GormRepository) MigrateLegacyMetadata() (int, error) {
+	var secrets []sec.Secret
+	err := g.db.Table("secrets").Select("id, secret_type, metadata, name, description, file_filename").
+		Where("COALESCE(metadata, '') <> '' AND COALESCE(name, '') = '' AND COALESCE(description, '') = '' AND COALESCE(file_filename, '') = ''").
+		Find(&secrets).Error
+	if err != nil {
+		return 0, err
+	}
+	migrated := 0
+	for _, s := range secrets {
+		if s.Metadata == "" || s.Meta.Name != "" || s.Meta.Description != "" || s.Meta.File.Filename != "" {
+			continue
+		}
+		meta := s.TypedMeta()
+		err := g.db.Table("secrets").Where("id = ?", s.ID).Updates(map[string]interface{}{
+			"name":          meta.Name,
+			"description":   meta.Description,
+			"file_filename": meta.File.Filename,
+		}).Error
+		if err != nil {
+			return migrated, err
+		}
+		migrated++
+	}
+	return migrated, nil
+}
+
+// BackfillRevisions gives the secrets stored before revisions existed a
+// revision and returns their number. It needs the triggers of EnsureIndexes.
+func (g *GormRepository) BackfillRevisions() (int, error) {
+	result := g.db.Exec("UPDATE secrets SET revision = 0 WHERE revision = 0")
+	return int(result.RowsAffected), result.Error
+}
+
+// BackfillTimestamps sets the creation and update time of secrets stored
+// before these columns existed to the time of the migration.
+func (g *GormRepository) BackfillTimestamps() error {
+	return g.db.Exec("UPDATE secrets SET created_at = now(), updated_at = now() WHERE created_at IS NULL").Error
+}
+
 func (g *GormRepository) LoginAccount(email, password string, jwtSettings auth.JWTSettings) server.Response {
 	account := &acc.Account{}
 	err := g.db.Table("accounts").Where("login = ?", email).First(account).Error
@@ -109,22 +479,118 @@ func (g *GormRepository) ValidateAccount(account *acc.Account) server.Response {
 	return server.Message("Requirement passed", 200)
 }
 
+// GetAccountByLogin returns the account without its password.
+func (g *GormRepository) GetAccountByLogin(login string) (*acc.Account, error) {
+	account := &acc.Account{}
+	err := g.db.Table("accounts").Select("id, login").Where("login = ?", login).First(account).Error
+	if err != nil {
+		return nil, err
+	}
+	return account, nil
+}
+
+// DeleteSecret moves the secret into the trash of its owner. Trashed secrets
+// are hidden from all other queries until they are restored or purged.
 func (g *GormRepository) DeleteSecret(s *sec.Secret) error {
-	sec, err := g.GetSecretByID(s.ID)
+	stored, err := g.GetSecretByID(s.ID)
 	if err != nil {
 		return err
 	}
-	if sec.UserID == s.UserID {
-		result := g.db.Delete(s)
-		if result.Error != nil {
-			return result.Error
-		}
+	if stored.UserID == s.UserID {
+		return g.db.Delete(stored).Error
 	}
 	return nil
 }
+
+// GetTrash returns the trashed personal secrets of the user or, for a vault ID
+// other than zero, the trashed secrets of the vault.
+func (g *GormRepository) GetTrash(userID, vaultID uint) ([]sec.Secret, error) {
+	var secrets []sec.Secret
+	result := inScope(g.db.Unscoped().Table("secrets").Select(secretColumnsWithoutValue), userID, vaultID).
+		Where("deleted_at IS NOT NULL").
+		Order("deleted_at DESC").Find(&secrets)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return secrets, nil
+}
+
+// GetTrashedSecret returns the secret if it is in the trash. Callers check
+// that the user may restore or purge it.
+func (g *GormRepository) GetTrashedSecret(secretID uint) (*sec.Secret, error) {
+	secret := sec.Secret{}
+	err := g.db.Unscoped().Table("secrets").Select(secretColumnsWithoutValue).
+		Where("id = ? AND deleted_at IS NOT NULL", secretID).
+		First(&secret).Error
+	if err != nil {
+		return nil, err
+	}
+	return &secret, nil
+}
+
+func (g *GormRepository) RestoreSecret(s *sec.Secret) error {
+	return g.db.Unscoped().Model(s).Update("deleted_at", nil).Error
+}
+
+// PurgeSecret permanently deletes the secret with its attachments, tag links
+// and certificate details.
+func (g *GormRepository) PurgeSecret(s *sec.Secret) error {
+	return g.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("secret_id = ?", s.ID).Delete(&sec.Attachment{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("DELETE FROM secret_tags WHERE secret_id = ?", s.ID).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("secret_id = ?", s.ID).Delete(&sec.CertificateInfo{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("secret_id = ?", s.ID).Delete(&sec.Share{}).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(&sec.Secret{ID: s.ID}).Error
+	})
+}
+
+// EmptyTrash purges all trashed personal secrets of the user or, for a vault
+// ID other than zero, all trashed secrets of the vault. It returns the IDs and
+// owners of the purged secrets, also the ones purged before an error.
+func (g *GormRepository) EmptyTrash(userID, vaultID uint) ([]sec.Secret, error) {
+	return g.purgeTrashed(inScope(g.db, userID, vaultID))
+}
+
+// PurgeTrash purges the secrets of all users which were moved to the trash
+// before deletedBefore.
+func (g *GormRepository) PurgeTrash(deletedBefore time.Time) (int, error) {
+	purged, err := g.purgeTrashed(g.db.Where("deleted_at < ?", deletedBefore))
+	return len(purged), err
+}
+
+func (g *GormRepository) purgeTrashed(scope *gorm.DB) ([]sec.Secret, error) {
+	var secrets []sec.Secret
+	err := scope.Unscoped().Table("secrets").Select("id, user_id").Where("deleted_at IS NOT NULL").Find(&secrets).Error
+	if err != nil {
+		return nil, err
+	}
+	for i := range secrets {
+		if err := g.PurgeSecret(&secrets[i]); err != nil {
+			return secrets[:i], err
+		}
+	}
+	return secrets, nil
+}
+
+// Transaction runs fn with a repository bound to a new transaction, which is
+// committed if fn returns nil and rolled back otherwise.
+func (g *GormRepository) Transaction(fn func(repo SecretRepository) error) error {
+	return g.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&GormRepository{db: tx})
+	})
+}
+
 func (g *GormRepository) GetSecretByID(secretID uint) (*sec.Secret, error) {
 	secret := sec.Secret{}
-	err := g.db.Table("secrets").Where("ID = ?", secretID).Find(&secret).Error
+	err := g.db.Table("secrets").Preload("Tags").Where("ID = ?", secretID).Find(&secret).Error
 	if err != nil {
 		log.Println(err)
 		return nil, err
@@ -140,11 +606,1244 @@ func (g *GormRepository) SaveSecret(s *sec.Secret) (*sec.Secret, error) {
 	}
 	return s, nil
 }
-func (g *GormRepository) GetSecretsForUser(userID uint) ([]sec.Secret, error) {
+func (g *GormRepository) GetSecretsForUser(userID uint, filter SecretFilter) ([]sec.Secret, error) {
+	var secrets []sec.Secret
+	result := g.filteredSecrets(userID, filter).Find(&secrets)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return secrets, nil
+}
+
+// GetSecretPage returns the filter.Limit secrets following filter.Cursor in
+// the sort order, and the cursor of the next page if there is one.
+func (g *GormRepository) GetSecretPage(userID uint, filter SecretFilter) (*sec.SecretPage, error) {
+	query := g.filteredSecrets(userID, filter)
+	if c := filter.Cursor; c != nil {
+		op := ">"
+		if filter.Desc {
+			op = "<"
+		}
+		var key interface{} = c.Key
+		if c.Time != nil {
+			key = *c.Time
+		} else if filter.Sort == sec.SortAccesses {
+			key = c.Count
+		}
+		query = query.Where("("+sortExpressions[filter.Sort]+", secrets.id) "+op+" (?, ?)", key, c.ID)
+	}
+
+	var secrets []sec.Secret
+	// One extra row tells whether there is a next page.
+	if err := query.Limit(filter.Limit + 1).Find(&secrets).Error; err != nil {
+		return nil, err
+	}
+	page := &sec.SecretPage{Items: secrets}
+	if len(secrets) > filter.Limit {
+		page.Items = secrets[:filter.Limit]
+		page.NextCursor = sec.CursorAfter(page.Items[filter.Limit-1], filter.Sort).Encode()
+	}
+	return page, nil
+}
+
+func (g *GormRepository) filteredSecrets(userID uint, filter SecretFilter) *gorm.DB {
+	query := inScope(g.db.Table("secrets").Preload("Tags"), userID, filter.VaultID).Where(servedSecrets)
+	if len(filter.Tags) > 0 {
+		// Tags belong to the owner of the secret, which is another member
+		// for many secrets of a vault. The scope of the query decides which
+		// secrets the user reads.
+		tagged := g.db.Table("secret_tags").
+			Select("secret_tags.secret_id").
+			Joins("JOIN tags ON tags.id = secret_tags.tag_id").
+			Where("tags.name IN (?)", filter.Tags)
+		if filter.MatchAllTags {
+			tagged = tagged.Group("secret_tags.secret_id").Having("COUNT(DISTINCT tags.name) = ?", len(filter.Tags))
+		}
+		query = query.Where("secrets.id IN (?)", tagged.SubQuery())
+	}
+	if len(filter.Types) > 0 {
+		query = query.Where("secret_type IN (?)", filter.Types)
+	}
+	if expr, ok := sortExpressions[filter.Sort]; ok {
+		dir := " ASC"
+		if filter.Desc {
+			dir = " DESC"
+		}
+		query = query.Order(expr + dir).Order("secrets.id" + dir)
+	}
+	return query
+}
+
+func (g *GormRepository) SaveCertificateInfo(info *sec.CertificateInfo) error {
+	return g.db.Save(info).Error
+}
+
+func (g *GormRepository) GetExpiringCertificates(userID uint, before time.Time) ([]sec.CertificateInfo, error) {
+	var infos []sec.CertificateInfo
+	live := g.db.Table("secrets").Select("id").Where("deleted_at IS NULL")
+	result := g.db.Where("user_id = ? AND not_after < ?", userID, before).
+		Where("secret_id IN (?)", live.SubQuery()).
+		Order("not_after").Find(&infos)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return infos, nil
+}
+
+func (g *GormRepository) SaveAttachment(a *sec.Attachment) error {
+	result := g.db.Create(a)
+	if result.Error != nil || a.ID == 0 {
+		return errors.New("failed to save attachment, connection error")
+	}
+	return nil
+}
+
+func (g *GormRepository) GetAttachments(secretID uint) ([]sec.Attachment, error) {
+	var attachments []sec.Attachment
+	result := g.db.Select("id, secret_id, filename, mime_type, size, checksum").Where("secret_id = ?", secretID).Order("filename").Find(&attachments)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return attachments, nil
+}
+
+func (g *GormRepository) GetAttachment(secretID uint, filename string) (*sec.Attachment, error) {
+	attachment := sec.Attachment{}
+	err := g.db.Where("secret_id = ? AND filename = ?", secretID, filename).First(&attachment).Error
+	if err != nil {
+		return nil, err
+	}
+	return &attachment, nil
+}
+
+func (g *GormRepository) DeleteAttachment(secretID uint, filename string) error {
+	result := g.db.Where("secret_id = ? AND filename = ?", secretID, filename).Delete(&sec.Attachment{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (g *GormRepository) AddTag(s *sec.Secret, name string) error {
+	tag := sec.Tag{UserID: s.UserID, Name: name}
+	if err := g.db.Where(tag).FirstOrCreate(&tag).Error; err != nil {
+		return err
+	}
+	return g.db.Model(s).Association("Tags").Append(&tag).Error
+}
+
+func (g *GormRepository) RemoveTag(s *sec.Secret, name string) error {
+	tag := sec.Tag{}
+	err := g.db.Where("user_id = ? AND name = ?", s.UserID, name).First(&tag).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil
+		}
+		return err
+	}
+	return g.db.Model(s).Association("Tags").Delete(&tag).Error
+}
+
+// GetSecretByPath returns the personal secret of the user at path or, for a
+// vault ID other than zero, the secret of the vault.
+func (g *GormRepository) GetSecretByPath(userID, vaultID uint, path string) (*sec.Secret, error) {
+	secret := sec.Secret{}
+	err := inScope(g.db.Table("secrets").Preload("Tags"), userID, vaultID).Where("path = ?", path).First(&secret).Error
+	if err != nil {
+		return nil, err
+	}
+	return &secret, nil
+}
+
+// GetSecretsByPathPrefix returns the personal secrets of the user, or the
+// secrets of the vault, below the prefix without their values, ordered by
+// path.
+func (g *GormRepository) GetSecretsByPathPrefix(userID, vaultID uint, prefix string) ([]sec.Secret, error) {
+	var secrets []sec.Secret
+	query := inScope(g.db.Table("secrets").Select(secretColumnsWithoutValue), userID, vaultID).
+		Where("path <> ''").Where(servedSecrets)
+	if prefix != "" {
+		query = query.Where("path LIKE ? ESCAPE '\\'", escapeLike(prefix)+"%")
+	}
+	result := query.Order("path").Find(&secrets)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return secrets, nil
+}
+
+func (g *GormRepository) MoveSecret(s *sec.Secret, path string) error {
+	return g.db.Model(s).Update("path", path).Error
+}
+
+// CopySecret stores a copy of the secret with its tags, attachments and
+// certificate details under path and returns it. The copy starts with its
+// own timestamps and without an expiry date or access history.
+func (g *GormRepository) CopySecret(s *sec.Secret, path string) (*sec.Secret, error) {
+	copied := sec.Secret{
+		UserID:     s.UserID,
+		VaultID:    s.VaultID,
+		Value:      s.Value,
+		SecretType: s.SecretType,
+		Metadata:   s.Metadata,
+		Meta:       s.Meta,
+		Path:       path,
+	}
+	err := g.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&copied).Error; err != nil {
+			return err
+		}
+		err := tx.Exec("INSERT INTO secret_tags (secret_id, tag_id) SELECT ?, tag_id FROM secret_tags WHERE secret_id = ?",
+			copied.ID, s.ID).Error
+		if err != nil {
+			return err
+		}
+		var attachments []sec.Attachment
+		if err := tx.Where("secret_id = ?", s.ID).Find(&attachments).Error; err != nil {
+			return err
+		}
+		for _, a := range attachments {
+			a.ID = 0
+			a.SecretID = copied.ID
+			if err := tx.Create(&a).Error; err != nil {
+				return err
+			}
+		}
+		var infos []sec.CertificateInfo
+		if err := tx.Where("secret_id = ?", s.ID).Find(&infos).Error; err != nil {
+			return err
+		}
+		for _, info := range infos {
+			info.SecretID = copied.ID
+			if err := tx.Create(&info).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	copied.Tags = s.Tags
+	return &copied, nil
+}
+
+// SearchSecrets returns the secrets matching the query without their values,
+// ordered by path and ID. At most limit secrets are returned.
+func (g *GormRepository) SearchSecrets(userID uint, q sec.SearchQuery, limit int) ([]sec.Secret, error) {
+	var secrets []sec.Secret
+	query := inScope(g.db.Table("secrets").Preload("Tags").Select(secretColumnsWithoutValue), userID, q.VaultID).
+		Where(servedSecrets)
+	if len(q.Types) > 0 {
+		query = query.Where("LOWER(secret_type) IN (?)", q.Types)
+	}
+	for _, term := range q.Terms {
+		text := escapeLike(term.Text)
+		pattern := "%" + text + "%"
+		pathPattern := pattern
+		if term.Prefix {
+			// A prefix also matches the start of any path segment, so
+			// "db*" finds prod/db-password.
+			pattern = text + "%"
+			pathPattern = "%/" + text + "%"
+		}
+		tagged := g.db.Table("secret_tags").
+			Select("secret_tags.secret_id").
+			Joins("JOIN tags ON tags.id = secret_tags.tag_id").
+			Where("tags.name LIKE ? ESCAPE '\\'", pattern)
+		query = query.Where("LOWER(metadata) LIKE ? ESCAPE '\\' OR LOWER(name) LIKE ? ESCAPE '\\' OR LOWER(description) LIKE ? ESCAPE '\\' "+
+			"OR LOWER(file_filename) LIKE ? ESCAPE '\\' OR LOWER(path) LIKE ? ESCAPE '\\' OR LOWER(path) LIKE ? ESCAPE '\\' "+
+			"OR LOWER(secret_type) LIKE ? ESCAPE '\\' OR secrets.id IN (?)",
+			pattern, pattern, pattern, pattern, pattern, pathPattern, pattern, tagged.SubQuery())
+	}
+	result := query.Order("path, id").Limit(limit).Find(&secrets)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return secrets, nil
+}
+
+// GetExpiringSecrets returns the personal secrets of the user or, for a vault
+// ID other than zero, the secrets of the vault which expire before the given
+// time without their values, ordered by expiry date. Secrets which already
+// expired and were archived are included.
+func (g *GormRepository) GetExpiringSecrets(userID, vaultID uint, before time.Time) ([]sec.Secret, error) {
+	var secrets []sec.Secret
+	result := inScope(g.db.Table("secrets").Preload("Tags").Select(secretColumnsWithoutValue), userID, vaultID).
+		Where("expires_at < ?", before).
+		Order("expires_at, id").Find(&secrets)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return secrets, nil
+}
+
+// SetSecretExpiry sets or, when nil, removes the expiry date of the secret
+// and takes it out of the archive.
+func (g *GormRepository) SetSecretExpiry(s *sec.Secret, expiresAt *time.Time) error {
+	return g.db.Model(s).Updates(map[string]interface{}{"expires_at": expiresAt, "archived_at": nil}).Error
+}
+
+// ArchiveExpired moves the secrets which expired at now into the archive and
+// returns their IDs.
+func (g *GormRepository) ArchiveExpired(now time.Time) ([]uint, error) {
+	var archived []struct{ ID uint }
+	err := g.db.Raw("UPDATE secrets SET archived_at = ? "+
+		"WHERE archived_at IS NULL AND expires_at <= ? AND deleted_at IS NULL RETURNING id", now, now).
+		Scan(&archived).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+	ids := make([]uint, len(archived))
+	for i, a := range archived {
+		ids[i] = a.ID
+	}
+	return ids, nil
+}
+
+// changeVaults selects the vaults whose secrets the user reads: the personal
+// vaults of the user and the vaults of the organizations of the user.
+const changeVaults = `SELECT id FROM vaults WHERE (org_id = 0 AND user_id = ?)
+	OR org_id IN (SELECT org_id FROM members WHERE user_id = ?)`
+
+// GetChanges returns the first limit changes after the revision since of the
+// secrets the user reads: the personal secrets of the user, the secrets of
+// the vaults of the user and the secrets shared with the user, including the
+// secrets in the trash and in the archive. Callers still check the access to
+// every secret returned.
+func (g *GormRepository) GetChanges(userID uint, since uint64, limit int) (*sec.ChangeFeed, error) {
+	// The revision is read first: changes committed after it are after it
+	// and returned next time.
+	current := sec.ChangeRevision{}
+	if err := g.db.Where("id = 1").First(&current).Error; err != nil && err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+	var secrets []sec.Secret
+	err := g.db.Unscoped().Table("secrets").Preload("Tags").
+		Where("revision > ?", since).
+		Where("(user_id = ? AND vault_id = 0) OR vault_id IN ("+changeVaults+") OR id IN (SELECT secret_id FROM shares WHERE user_id = ?)",
+			userID, userID, userID, userID).
+		Order("revision").Limit(limit + 1).Find(&secrets).Error
+	if err != nil {
+		return nil, err
+	}
+	var tombstones []sec.Tombstone
+	err = g.db.Where("revision > ?", since).
+		Where("(user_id = ? AND vault_id = 0) OR vault_id IN ("+changeVaults+")", userID, userID, userID).
+		Order("revision").Limit(limit + 1).Find(&tombstones).Error
+	if err != nil {
+		return nil, err
+	}
+	feed := &sec.ChangeFeed{Revision: since}
+	feed.Changes, feed.More = sec.MergeChanges(secrets, tombstones, limit, time.Now())
+	if n := len(feed.Changes); n > 0 {
+		feed.Revision = feed.Changes[n-1].Revision
+	}
+	if !feed.More && current.Revision > feed.Revision {
+		feed.Revision = current.Revision
+	}
+	return feed, nil
+}
+
+// RecordAccess counts a read of the value of the secret. It leaves the update
+// time of the secret untouched.
+func (g *GormRepository) RecordAccess(s *sec.Secret, at time.Time) error {
+	return g.db.Model(s).UpdateColumns(map[string]interface{}{
+		"last_accessed_at": at,
+		"access_count":     gorm.Expr("access_count + 1"),
+	}).Error
+}
+
+// GetStaleSecrets returns the served secrets of the user which have not been
+// read since the given time, or were never read and created before it,
+// without their values. The least recently used secrets come first.
+func (g *GormRepository) GetStaleSecrets(userID uint, unusedSince time.Time) ([]sec.Secret, error) {
 	var secrets []sec.Secret
-	result := g.db.Table("secrets").Where("User_ID = ?", userID).Find(&secrets)
+	result := g.db.Table("secrets").Preload("Tags").
+		Select(secretColumnsWithoutValue).
+		Where("user_id = ?", userID).Where(servedSecrets).
+		Where("COALESCE(last_accessed_at, created_at) < ?", unusedSince).
+		Order("COALESCE(last_accessed_at, created_at), id").Find(&secrets)
 	if result.Error != nil {
 		return nil, result.Error
 	}
 	return secrets, nil
 }
+
+// SaveShare creates the share or updates the access of an existing share of
+// the secret with the same user.
+func (g *GormRepository) SaveShare(share *sec.Share) error {
+	existing := sec.Share{}
+	err := g.db.Where("secret_id = ? AND user_id = ?", share.SecretID, share.UserID).First(&existing).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return err
+	}
+	share.ID = existing.ID
+	if err == nil {
+		share.CreatedAt = existing.CreatedAt
+	}
+	return g.db.Save(share).Error
+}
+
+func (g *GormRepository) GetShare(secretID, userID uint) (*sec.Share, error) {
+	share := sec.Share{}
+	err := g.db.Where("secret_id = ? AND user_id = ?", secretID, userID).First(&share).Error
+	if err != nil {
+		return nil, err
+	}
+	return &share, nil
+}
+
+// GetShares returns the shares of the secret with the logins of the
+// recipients, ordered by login.
+func (g *GormRepository) GetShares(secretID uint) ([]sec.Share, error) {
+	var shares []sec.Share
+	if err := g.db.Where("secret_id = ?", secretID).Find(&shares).Error; err != nil {
+		return nil, err
+	}
+	ids := make([]uint, len(shares))
+	for i, share := range shares {
+		ids[i] = share.UserID
+	}
+	logins, err := g.loginsByID(ids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range shares {
+		shares[i].Login = logins[shares[i].UserID]
+	}
+	sort.Slice(shares, func(i, j int) bool { return shares[i].Login < shares[j].Login })
+	return shares, nil
+}
+
+func (g *GormRepository) DeleteShare(secretID, userID uint) error {
+	result := g.db.Where("secret_id = ? AND user_id = ?", secretID, userID).Delete(&sec.Share{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// GetSharedSecrets returns the served secrets shared with the user without
+// their values, ordered by owner and path.
+func (g *GormRepository) GetSharedSecrets(userID uint) ([]sec.SharedSecret, error) {
+	var shares []sec.Share
+	if err := g.db.Where("user_id = ?", userID).Find(&shares).Error; err != nil {
+		return nil, err
+	}
+	if len(shares) == 0 {
+		return nil, nil
+	}
+	readOnly := make(map[uint]bool, len(shares))
+	ids := make([]uint, len(shares))
+	for i, share := range shares {
+		readOnly[share.SecretID] = share.ReadOnly
+		ids[i] = share.SecretID
+	}
+
+	var secrets []sec.Secret
+	err := g.db.Table("secrets").Select(secretColumnsWithoutValue).
+		Where("id IN (?)", ids).Where(servedSecrets).Find(&secrets).Error
+	if err != nil {
+		return nil, err
+	}
+	owners := make([]uint, len(secrets))
+	for i, s := range secrets {
+		owners[i] = s.UserID
+	}
+	logins, err := g.loginsByID(owners)
+	if err != nil {
+		return nil, err
+	}
+
+	shared := make([]sec.SharedSecret, len(secrets))
+	for i, s := range secrets {
+		shared[i] = sec.SharedSecret{Secret: s, Owner: logins[s.UserID], ReadOnly: readOnly[s.ID]}
+	}
+	sort.Slice(shared, func(i, j int) bool {
+		if shared[i].Owner != shared[j].Owner {
+			return shared[i].Owner < shared[j].Owner
+		}
+		return shared[i].Secret.Path < shared[j].Secret.Path
+	})
+	return shared, nil
+}
+
+func (g *GormRepository) loginsByID(ids []uint) (map[uint]string, error) {
+	logins := make(map[uint]string, len(ids))
+	if len(ids) == 0 {
+		return logins, nil
+	}
+	var accounts []acc.Account
+	if err := g.db.Table("accounts").Select("id, login").Where("id IN (?)", ids).Find(&accounts).Error; err != nil {
+		return nil, err
+	}
+	for _, a := range accounts {
+		logins[a.ID] = a.Login
+	}
+	return logins, nil
+}
+
+// inScope restricts the query to the personal secrets of the user or, for a
+// vault ID other than zero, to the secrets of the vault.
+func inScope(query *gorm.DB, userID, vaultID uint) *gorm.DB {
+	if vaultID != 0 {
+		return query.Where("vault_id = ?", vaultID)
+	}
+	return query.Where("user_id = ? AND vault_id = 0", userID)
+}
+
+// GetVaultRole returns the role of the user in the organization owning the
+// vault, or an empty role if the user is no member. The owner of a personal
+// vault has the owner role, nobody else has a role.
+func (g *GormRepository) GetVaultRole(vaultID, userID uint) (string, error) {
+	vault, err := g.GetVault(vaultID)
+	if err == gorm.ErrRecordNotFound {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if vault.Personal() {
+		if vault.UserID == userID {
+			return org.RoleOwner, nil
+		}
+		return "", nil
+	}
+	member, err := g.GetMember(vault.OrgID, userID)
+	if err == gorm.ErrRecordNotFound {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return member.Role, nil
+}
+
+func (g *GormRepository) GetVault(vaultID uint) (*org.Vault, error) {
+	v := org.Vault{}
+	if err := g.db.Where("id = ?", vaultID).First(&v).Error; err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// MoveSecretToVault moves the secret into the vault, or among the personal
+// secrets of userID for a nil vault. Secrets moved into a personal vault or
+// out of a vault belong to userID afterwards, secrets moved into the vault of
+// an organization are no longer shared with single accounts.
+func (g *GormRepository) MoveSecretToVault(s *sec.Secret, vault *org.Vault, userID uint) error {
+	columns := map[string]interface{}{"vault_id": 0, "user_id": userID}
+	if vault != nil {
+		columns["vault_id"] = vault.ID
+		if vault.Personal() {
+			columns["user_id"] = vault.UserID
+		} else {
+			delete(columns, "user_id")
+		}
+	}
+	return g.db.Transaction(func(tx *gorm.DB) error {
+		if vault != nil && !vault.Personal() {
+			if err := tx.Where("secret_id = ?", s.ID).Delete(&sec.Share{}).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Model(s).UpdateColumns(columns).Error; err != nil {
+			return err
+		}
+		// The certificate details keep the owner for the expiry listing.
+		if owner, ok := columns["user_id"]; ok {
+			return tx.Model(&sec.CertificateInfo{}).Where("secret_id = ?", s.ID).UpdateColumn("user_id", owner).Error
+		}
+		return nil
+	})
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// ErrVaultNotEmpty is returned when deleting a vault which still holds
+// secrets, including the ones in the trash.
+var ErrVaultNotEmpty = errors.New("vault is not empty")
+
+// CreateOrganization creates the organization with ownerID as its first
+// owner.
+func (g *GormRepository) CreateOrganization(o *org.Organization, ownerID uint) error {
+	return g.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(o).Error; err != nil {
+			return err
+		}
+		return tx.Create(&org.Member{OrgID: o.ID, UserID: ownerID, Role: org.RoleOwner}).Error
+	})
+}
+
+func (g *GormRepository) GetOrganizationByName(name string) (*org.Organization, error) {
+	o := org.Organization{}
+	if err := g.db.Where("name = ?", name).First(&o).Error; err != nil {
+		return nil, err
+	}
+	return &o, nil
+}
+
+// GetOrganizations returns the organizations the user is a member of with
+// the role of the user, ordered by name.
+func (g *GormRepository) GetOrganizations(userID uint) ([]org.Organization, error) {
+	var members []org.Member
+	if err := g.db.Where("user_id = ?", userID).Find(&members).Error; err != nil {
+		return nil, err
+	}
+	roles := make(map[uint]string, len(members))
+	ids := make([]uint, len(members))
+	for i, m := range members {
+		roles[m.OrgID] = m.Role
+		ids[i] = m.OrgID
+	}
+	orgs := []org.Organization{}
+	if len(ids) == 0 {
+		return orgs, nil
+	}
+	if err := g.db.Where("id IN (?)", ids).Order("name").Find(&orgs).Error; err != nil {
+		return nil, err
+	}
+	for i := range orgs {
+		orgs[i].Role = roles[orgs[i].ID]
+	}
+	return orgs, nil
+}
+
+func (g *GormRepository) GetMember(orgID, userID uint) (*org.Member, error) {
+	m := org.Member{}
+	if err := g.db.Where("org_id = ? AND user_id = ?", orgID, userID).First(&m).Error; err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// GetMembers returns the members of the organization with their logins,
+// ordered by login.
+func (g *GormRepository) GetMembers(orgID uint) ([]org.Member, error) {
+	var members []org.Member
+	if err := g.db.Where("org_id = ?", orgID).Find(&members).Error; err != nil {
+		return nil, err
+	}
+	ids := make([]uint, len(members))
+	for i, m := range members {
+		ids[i] = m.UserID
+	}
+	logins, err := g.loginsByID(ids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range members {
+		members[i].Login = logins[members[i].UserID]
+	}
+	sort.Slice(members, func(i, j int) bool { return members[i].Login < members[j].Login })
+	return members, nil
+}
+
+// SaveMember adds the member or changes the role of an existing one.
+func (g *GormRepository) SaveMember(m *org.Member) error {
+	existing := org.Member{}
+	err := g.db.Where("org_id = ? AND user_id = ?", m.OrgID, m.UserID).First(&existing).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return err
+	}
+	m.ID = existing.ID
+	if err == nil {
+		m.CreatedAt = existing.CreatedAt
+	}
+	return g.db.Save(m).Error
+}
+
+func (g *GormRepository) DeleteMember(orgID, userID uint) error {
+	result := g.db.Where("org_id = ? AND user_id = ?", orgID, userID).Delete(&org.Member{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (g *GormRepository) CountOwners(orgID uint) (int, error) {
+	var count int
+	err := g.db.Model(&org.Member{}).Where("org_id = ? AND role = ?", orgID, org.RoleOwner).Count(&count).Error
+	return count, err
+}
+
+func (g *GormRepository) CreateVault(v *org.Vault) error {
+	return g.db.Create(v).Error
+}
+
+func (g *GormRepository) GetVaults(orgID uint) ([]org.Vault, error) {
+	vaults := []org.Vault{}
+	if err := g.db.Where("org_id = ?", orgID).Order("name").Find(&vaults).Error; err != nil {
+		return nil, err
+	}
+	return vaults, nil
+}
+
+func (g *GormRepository) GetVaultByName(orgID uint, name string) (*org.Vault, error) {
+	v := org.Vault{}
+	if err := g.db.Where("org_id = ? AND name = ?", orgID, name).First(&v).Error; err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// DeleteVault deletes the vault if it holds no secrets, ErrVaultNotEmpty is
+// returned otherwise.
+func (g *GormRepository) DeleteVault(v *org.Vault) error {
+	var count int
+	if err := g.db.Unscoped().Model(&sec.Secret{}).Where("vault_id = ?", v.ID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrVaultNotEmpty
+	}
+	return g.db.Delete(v).Error
+}
+
+// GetPersonalVaults returns the personal vaults of the user, ordered by name.
+func (g *GormRepository) GetPersonalVaults(userID uint) ([]org.Vault, error) {
+	vaults := []org.Vault{}
+	if err := g.db.Where("org_id = 0 AND user_id = ?", userID).Order("name").Find(&vaults).Error; err != nil {
+		return nil, err
+	}
+	return vaults, nil
+}
+
+func (g *GormRepository) GetPersonalVaultByName(userID uint, name string) (*org.Vault, error) {
+	v := org.Vault{}
+	if err := g.db.Where("org_id = 0 AND user_id = ? AND name = ?", userID, name).First(&v).Error; err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// PurgeVault permanently deletes the vault with all of its secrets, including
+// the ones in the trash, and returns the number of secrets deleted.
+func (g *GormRepository) PurgeVault(v *org.Vault) (int, error) {
+	var secrets []sec.Secret
+	err := g.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Table("secrets").Select("id").Where("vault_id = ?", v.ID).Find(&secrets).Error; err != nil {
+			return err
+		}
+		if len(secrets) > 0 {
+			ids := make([]uint, len(secrets))
+			for i, s := range secrets {
+				ids[i] = s.ID
+			}
+			if err := tx.Where("secret_id IN (?)", ids).Delete(&sec.Attachment{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Exec("DELETE FROM secret_tags WHERE secret_id IN (?)", ids).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("secret_id IN (?)", ids).Delete(&sec.CertificateInfo{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("secret_id IN (?)", ids).Delete(&sec.Share{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Unscoped().Where("id IN (?)", ids).Delete(&sec.Secret{}).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Delete(v).Error
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(secrets), nil
+}
+
+func (g *GormRepository) SaveOneTimeShare(share *sec.OneTimeShare) error {
+	return g.db.Create(share).Error
+}
+
+// OpenOneTimeShare counts one view of the unexpired share and returns it with
+// the views left. The share is deleted with its last view. Concurrent opens
+// never return a share more often than its views allow.
+func (g *GormRepository) OpenOneTimeShare(token string, now time.Time) (*sec.OneTimeShare, error) {
+	var shares []sec.OneTimeShare
+	err := g.db.Raw("UPDATE one_time_shares SET views_left = views_left - 1 "+
+		"WHERE token = ? AND views_left > 0 AND expires_at > ? RETURNING *", token, now).Scan(&shares).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+	if len(shares) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	share := shares[0]
+	if share.ViewsLeft <= 0 {
+		if err := g.db.Where("id = ?", share.ID).Delete(&sec.OneTimeShare{}).Error; err != nil {
+			log.Printf("cannot delete opened one-time share %d - %s", share.ID, err)
+		}
+	}
+	return &share, nil
+}
+
+// PurgeExpiredOneTimeShares deletes the one-time shares which expired before
+// now without having been opened often enough.
+func (g *GormRepository) PurgeExpiredOneTimeShares(now time.Time) (int, error) {
+	result := g.db.Where("expires_at <= ? OR views_left <= 0", now).Delete(&sec.OneTimeShare{})
+	return int(result.RowsAffected), result.Error
+}
+
+// ErrEmergencyStateChanged is returned when the state of an emergency contact
+// changed since it was loaded.
+var ErrEmergencyStateChanged = errors.New("emergency access changed concurrently")
+
+func (g *GormRepository) CreateEmergencyContact(c *em.EmergencyContact) error {
+	return g.db.Create(c).Error
+}
+
+// SetEmergencyWait changes only the waiting period of the contact, so that it
+// does not overwrite a state transition happening at the same time.
+func (g *GormRepository) SetEmergencyWait(c *em.EmergencyContact, waitHours int) error {
+	return g.db.Model(c).UpdateColumns(map[string]interface{}{"wait_hours": waitHours, "updated_at": time.Now()}).Error
+}
+
+func (g *GormRepository) GetEmergencyContact(ownerID, contactID uint) (*em.EmergencyContact, error) {
+	c := em.EmergencyContact{}
+	if err := g.db.Where("owner_id = ? AND contact_id = ?", ownerID, contactID).First(&c).Error; err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// GetEmergencyContacts returns the emergency contacts of the owner with the
+// login of each contact.
+func (g *GormRepository) GetEmergencyContacts(ownerID uint) ([]em.EmergencyContact, error) {
+	var contacts []em.EmergencyContact
+	if err := g.db.Where("owner_id = ?", ownerID).Order("id").Find(&contacts).Error; err != nil {
+		return nil, err
+	}
+	ids := make([]uint, len(contacts))
+	for i, c := range contacts {
+		ids[i] = c.ContactID
+	}
+	logins, err := g.loginsByID(ids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range contacts {
+		contacts[i].Login = logins[contacts[i].ContactID]
+	}
+	return contacts, nil
+}
+
+// GetEmergencyOwners returns the accounts which designated the contact as an
+// emergency contact with the login of each owner.
+func (g *GormRepository) GetEmergencyOwners(contactID uint) ([]em.EmergencyContact, error) {
+	var contacts []em.EmergencyContact
+	if err := g.db.Where("contact_id = ?", contactID).Order("id").Find(&contacts).Error; err != nil {
+		return nil, err
+	}
+	ids := make([]uint, len(contacts))
+	for i, c := range contacts {
+		ids[i] = c.OwnerID
+	}
+	logins, err := g.loginsByID(ids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range contacts {
+		contacts[i].Login = logins[contacts[i].OwnerID]
+	}
+	return contacts, nil
+}
+
+// DeleteEmergencyContact deletes the emergency contact with its events, which
+// ends any access granted to it.
+func (g *GormRepository) DeleteEmergencyContact(c *em.EmergencyContact) error {
+	return g.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("contact_id = ?", c.ID).Delete(&em.EmergencyEvent{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(c).Error
+	})
+}
+
+// TransitionEmergencyAccess applies the action to the emergency contact and
+// records it as an event of actorID, which is zero for the server. The
+// contact is updated only if its state is still the one it was loaded with,
+// ErrEmergencyStateChanged is returned otherwise.
+func (g *GormRepository) TransitionEmergencyAccess(c *em.EmergencyContact, action string, actorID uint, now time.Time) error {
+	next, err := em.Transition(c.State, action)
+	if err != nil {
+		return err
+	}
+	columns := map[string]interface{}{"state": next, "updated_at": now}
+	if next == em.StateRequested {
+		columns["requested_at"] = now
+	}
+	err = g.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&em.EmergencyContact{}).Where("id = ? AND state = ?", c.ID, c.State).UpdateColumns(columns)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrEmergencyStateChanged
+		}
+		event := em.EmergencyEvent{ContactID: c.ID, Action: action, From: c.State, To: next, ActorID: actorID, CreatedAt: now}
+		return tx.Create(&event).Error
+	})
+	if err != nil {
+		return err
+	}
+	c.State = next
+	c.UpdatedAt = now
+	if next == em.StateRequested {
+		c.RequestedAt = &now
+	}
+	return nil
+}
+
+// GrantDueEmergencyRequests grants the requests whose waiting period passed
+// before now without the owner deciding on them and returns their number.
+func (g *GormRepository) GrantDueEmergencyRequests(now time.Time) (int, error) {
+	var due []em.EmergencyContact
+	err := g.db.Where("state = ? AND requested_at + wait_hours * interval '1 hour' <= ?", em.StateRequested, now).
+		Find(&due).Error
+	if err != nil {
+		return 0, err
+	}
+	granted := 0
+	for i := range due {
+		err := g.TransitionEmergencyAccess(&due[i], em.ActionTimeout, 0, now)
+		if err == ErrEmergencyStateChanged {
+			continue
+		}
+		if err != nil {
+			return granted, err
+		}
+		granted++
+	}
+	return granted, nil
+}
+
+// GetEmergencyEvents returns the events of the emergency contact in the order
+// they happened with the login of each actor.
+func (g *GormRepository) GetEmergencyEvents(contactID uint) ([]em.EmergencyEvent, error) {
+	var events []em.EmergencyEvent
+	if err := g.db.Where("contact_id = ?", contactID).Order("id").Find(&events).Error; err != nil {
+		return nil, err
+	}
+	ids := make([]uint, 0, len(events))
+	for _, e := range events {
+		if e.ActorID != 0 {
+			ids = append(ids, e.ActorID)
+		}
+	}
+	logins, err := g.loginsByID(ids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range events {
+		events[i].Actor = logins[events[i].ActorID]
+	}
+	return events, nil
+}
+
+// GetEmergencySecrets returns the served secrets of the owner which granted
+// emergency contacts may read, which are the ones in the default and the
+// personal vaults, without their values.
+func (g *GormRepository) GetEmergencySecrets(ownerID uint) ([]sec.Secret, error) {
+	var secrets []sec.Secret
+	personal := g.db.Table("vaults").Select("id").Where("org_id = 0 AND user_id = ?", ownerID).SubQuery()
+	err := g.db.Table("secrets").Select(secretColumnsWithoutValue).
+		Where("user_id = ?", ownerID).Where("vault_id = 0 OR vault_id IN (?)", personal).
+		Where(servedSecrets).Order("vault_id, path, id").Find(&secrets).Error
+	if err != nil {
+		return nil, err
+	}
+	return secrets, nil
+}
+
+// HasEmergencyAccess reports whether the contact was granted emergency access
+// to the secrets of the owner.
+func (g *GormRepository) HasEmergencyAccess(ownerID, contactID uint) (bool, error) {
+	var count int
+	err := g.db.Model(&em.EmergencyContact{}).
+		Where("owner_id = ? AND contact_id = ? AND state = ?", ownerID, contactID, em.StateGranted).Count(&count).Error
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// lockAuditLog serializes appending to the audit log until the end of the
+// transaction, so that every event chains to the one stored before it.
+// Reading the log is not blocked.
+const lockAuditLog = "LOCK TABLE audit_events IN SHARE ROW EXCLUSIVE MODE"
+
+// RecordAuditEvents chains the events to the end of the audit log and
+// appends them in one transaction.
+func (g *GormRepository) RecordAuditEvents(events []audit.AuditEvent) error {
+	return g.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(lockAuditLog).Error; err != nil {
+			return err
+		}
+		prev, err := lastAuditHash(tx)
+		if err != nil {
+			return err
+		}
+		for i := range events {
+			events[i].Chain(prev)
+			if err := tx.Create(&events[i]).Error; err != nil {
+				return err
+			}
+			prev = events[i].Hash
+		}
+		return nil
+	})
+}
+
+// lastAuditHash returns the hash of the last event of the audit log, which is
+// empty for an empty log.
+func lastAuditHash(tx *gorm.DB) (string, error) {
+	last := audit.AuditEvent{}
+	err := tx.Select("hash").Order("id DESC").First(&last).Error
+	if err == gorm.ErrRecordNotFound {
+		return "", nil
+	}
+	return last.Hash, err
+}
+
+// GetAuditEvents returns the audit events of the actions of the user and of
+// the actions of others on the secrets of the user, newest first, with the
+// login of each actor.
+func (g *GormRepository) GetAuditEvents(userID uint, q audit.Query) ([]audit.AuditEvent, error) {
+	var events []audit.AuditEvent
+	query := g.db.Where("actor_id = ? OR owner_id = ?", userID, userID)
+	if q.Since != nil {
+		query = query.Where("created_at >= ?", *q.Since)
+	}
+	if q.SecretID != 0 {
+		query = query.Where("secret_id = ?", q.SecretID)
+	}
+	if err := query.Order("id DESC").Limit(q.Limit).Find(&events).Error; err != nil {
+		return nil, err
+	}
+	ids := make([]uint, 0, len(events))
+	for _, e := range events {
+		if e.ActorID != 0 {
+			ids = append(ids, e.ActorID)
+		}
+	}
+	logins, err := g.loginsByID(ids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range events {
+		events[i].Actor = logins[events[i].ActorID]
+	}
+	return events, nil
+}
+
+func (g *GormRepository) GetLastAuditEvent() (*audit.AuditEvent, error) {
+	e := audit.AuditEvent{}
+	if err := g.db.Order("id DESC").First(&e).Error; err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+func (g *GormRepository) GetLastAuditCheckpoint() (*audit.AuditCheckpoint, error) {
+	c := audit.AuditCheckpoint{}
+	if err := g.db.Order("id DESC").First(&c).Error; err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (g *GormRepository) SaveAuditCheckpoint(c *audit.AuditCheckpoint) error {
+	return g.db.Create(c).Error
+}
+
+// auditExportBatch is the number of audit events loaded at a time by
+// ExportAudit.
+const auditExportBatch = 1000
+
+// ExportAudit passes the whole audit log to fn in the order of the chain,
+// each checkpoint right after the event it covers.
+func (g *GormRepository) ExportAudit(fn func(rec audit.ExportRecord) error) error {
+	var checkpoints []audit.AuditCheckpoint
+	if err := g.db.Order("event_id, id").Find(&checkpoints).Error; err != nil {
+		return err
+	}
+	var after uint
+	for {
+		var events []audit.AuditEvent
+		if err := g.db.Where("id > ?", after).Order("id").Limit(auditExportBatch).Find(&events).Error; err != nil {
+			return err
+		}
+		for i := range events {
+			if err := fn(audit.ExportRecord{Event: &events[i]}); err != nil {
+				return err
+			}
+			for len(checkpoints) > 0 && checkpoints[0].EventID <= events[i].ID {
+				if err := fn(audit.ExportRecord{Checkpoint: &checkpoints[0]}); err != nil {
+					return err
+				}
+				checkpoints = checkpoints[1:]
+			}
+		}
+		if len(events) < auditExportBatch {
+			return nil
+		}
+		after = events[len(events)-1].ID
+	}
+}
+
+// ChainAuditEvents chains the audit events recorded before the audit log had
+// a hash chain and returns their number. It is the only change ever made to
+// stored audit events.
+func (g *GormRepository) ChainAuditEvents() (int, error) {
+	var count int
+	if err := g.db.Model(&audit.AuditEvent{}).Where("hash = '' OR hash IS NULL").Count(&count).Error; err != nil || count == 0 {
+		return 0, err
+	}
+	chained := 0
+	err := g.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(lockAuditLog).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("ALTER TABLE audit_events DISABLE TRIGGER USER").Error; err != nil {
+			return err
+		}
+		var events []audit.AuditEvent
+		if err := tx.Order("id").Find(&events).Error; err != nil {
+			return err
+		}
+		prev := ""
+		for i := range events {
+			if events[i].Hash == "" {
+				events[i].Chain(prev)
+				err := tx.Model(&events[i]).UpdateColumns(map[string]interface{}{
+					"prev_hash": events[i].PrevHash, "hash": events[i].Hash, "created_at": events[i].CreatedAt,
+				}).Error
+				if err != nil {
+					return err
+				}
+				chained++
+			}
+			prev = events[i].Hash
+		}
+		return tx.Exec("ALTER TABLE audit_events ENABLE TRIGGER USER").Error
+	})
+	if err != nil {
+		return 0, err
+	}
+	return chained, nil
+}
+
+func (g *GormRepository) CreateWebhook(h *wh.Webhook) error {
+	return g.db.Create(h).Error
+}
+
+// GetWebhooks returns the personal webhooks of the user.
+func (g *GormRepository) GetWebhooks(userID uint) ([]wh.Webhook, error) {
+	var hooks []wh.Webhook
+	result := g.db.Where("user_id = ? AND org_id = 0", userID).Order("id").Find(&hooks)
+	return hooks, result.Error
+}
+
+func (g *GormRepository) GetOrgWebhooks(orgID uint) ([]wh.Webhook, error) {
+	var hooks []wh.Webhook
+	result := g.db.Where("org_id = ?", orgID).Order("id").Find(&hooks)
+	return hooks, result.Error
+}
+
+func (g *GormRepository) GetWebhook(id uint) (*wh.Webhook, error) {
+	h := wh.Webhook{}
+	if err := g.db.Where("id = ?", id).First(&h).Error; err != nil {
+		return nil, err
+	}
+	return &h, nil
+}
+
+// DeleteWebhook removes the webhook with its delivery log.
+func (g *GormRepository) DeleteWebhook(h *wh.Webhook) error {
+	return g.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("webhook_id = ?", h.ID).Delete(&wh.Delivery{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(h).Error
+	})
+}
+
+// GetDeliveries returns the last limit deliveries of the webhook, the most
+// recent first.
+func (g *GormRepository) GetDeliveries(webhookID uint, limit int) ([]wh.Delivery, error) {
+	var deliveries []wh.Delivery
+	result := g.db.Where("webhook_id = ?", webhookID).Order("id DESC").Limit(limit).Find(&deliveries)
+	return deliveries, result.Error
+}
+
+// GetEventScope returns whose webhooks receive the events of the secret: the
+// account owning it, or the organization owning its vault. Deleted secrets
+// are included.
+func (g *GormRepository) GetEventScope(secretID uint) (uint, uint, error) {
+	s := sec.Secret{}
+	if err := g.db.Unscoped().Select("id, user_id, vault_id").Where("id = ?", secretID).First(&s).Error; err != nil {
+		return 0, 0, err
+	}
+	if s.VaultID == 0 {
+		return s.UserID, 0, nil
+	}
+	vault, err := g.GetVault(s.VaultID)
+	if err != nil {
+		return 0, 0, err
+	}
+	if vault.Personal() {
+		return vault.UserID, 0, nil
+	}
+	return 0, vault.OrgID, nil
+}
+
+// GetMatchingWebhooks returns the personal webhooks of the user and the
+// webhooks of the organization.
+func (g *GormRepository) GetMatchingWebhooks(userID, orgID uint) ([]wh.Webhook, error) {
+	var hooks []wh.Webhook
+	result := g.db.Where("(org_id = 0 AND user_id = ?) OR (org_id <> 0 AND org_id = ?)", userID, orgID).
+		Order("id").Find(&hooks)
+	return hooks, result.Error
+}
+
+func (g *GormRepository) CreateDelivery(d *wh.Delivery) error {
+	return g.db.Create(d).Error
+}
+
+// ClaimDelivery postpones the next attempt of the pending delivery to until if
+// it is due at now, so that only one attempt is made at a time. It reports
+// whether the delivery was claimed.
+func (g *GormRepository) ClaimDelivery(id uint, now, until time.Time) (bool, error) {
+	result := g.db.Model(&wh.Delivery{}).
+		Where("id = ? AND state = ? AND next_attempt_at <= ?", id, wh.DeliveryPending, now).
+		UpdateColumn("next_attempt_at", until)
+	return result.RowsAffected == 1, result.Error
+}
+
+func (g *GormRepository) SaveDelivery(d *wh.Delivery) error {
+	return g.db.Save(d).Error
+}
+
+// GetDueDeliveries returns up to limit pending deliveries whose next attempt
+// is due at now, the oldest first.
+func (g *GormRepository) GetDueDeliveries(now time.Time, limit int) ([]wh.Delivery, error) {
+	var deliveries []wh.Delivery
+	result := g.db.Where("state = ? AND next_attempt_at <= ?", wh.DeliveryPending, now).
+		Order("next_attempt_at").Limit(limit).Find(&deliveries)
+	return deliveries, result.Error
+}
@@ -0,0 +1,290 @@
+package models
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	em "passKeeper/internal/models/emergency"
+	org "passKeeper/internal/models/org"
+	sec "passKeeper/internal/models/secret"
+
+	"github.com/jinzhu/gorm"
+)
+
+// stubDriver answers every query with its rows and records the statements
+// executed and the queries run through it. Tests emulating a table set onExec
+// and onQuery instead.
+type stubDriver struct {
+	mu      sync.Mutex
+	columns []string
+	rows    [][]driver.Value
+	execs   []string
+	args    [][]driver.Value
+	queries []string
+	onExec  func(query string, args []driver.Value)
+	onQuery func(query string, args []driver.Value) ([]string, [][]driver.Value)
+}
+
+func (d *stubDriver) Open(string) (driver.Conn, error) { return &stubConn{d}, nil }
+
+type stubConn struct{ d *stubDriver }
+
+func (c *stubConn) Prepare(query string) (driver.Stmt, error) { return &stubStmt{c.d, query}, nil }
+func (c *stubConn) Close() error                              { return nil }
+func (c *stubConn) Begin() (driver.Tx, error)                 { return c, nil }
+func (c *stubConn) Commit() error                             { return nil }
+func (c *stubConn) Rollback() error                           { return nil }
+
+type stubStmt struct {
+	d     *stubDriver
+	query string
+}
+
+func (s *stubStmt) Close() error  { return nil }
+func (s *stubStmt) NumInput() int { return -1 }
+
+func (s *stubStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	s.d.execs = append(s.d.execs, s.query)
+	s.d.args = append(s.d.args, args)
+	if s.d.onExec != nil {
+		s.d.onExec(s.query, args)
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (s *stubStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	s.d.queries = append(s.d.queries, s.query)
+	if s.d.onQuery != nil {
+		columns, rows := s.d.onQuery(s.query, args)
+		return &stubRows{columns: columns, rows: rows}, nil
+	}
+	return &stubRows{columns: s.d.columns, rows: s.d.rows}, nil
+}
+
+type stubRows struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (r *stubRows) Columns() []string { return r.columns }
+func (r *stubRows) Close() error      { return nil }
+
+func (r *stubRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+func openStub(t *testing.T, name string, d *stubDriver) *gorm.DB {
+	sql.Register(name, d)
+	conn, err := sql.Open(name, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := gorm.Open("postgres", conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestMigrateLegacyMetadataFromNullColumns(t *testing.T) {
+	d := &stubDriver{
+		columns: []string{"id", "secret_type", "metadata", "name", "description", "file_filename"},
+		rows: [][]driver.Value{
+			// Stored before the typed columns existed, they are NULL.
+			{int64(1), "ByteSlice", "backup|tar|nightly backup", nil, nil, nil},
+			{int64(2), "Text", "notes", nil, nil, nil},
+			// Already migrated.
+			{int64(3), "Text", "notes", nil, "notes", nil},
+		},
+	}
+	repo := GetMigrationRepo(openStub(t, "stub-migrate-metadata", d))
+
+	n, err := repo.MigrateLegacyMetadata()
+	if err != nil {
+		t.Fatalf("didn't expect error, got %v", err)
+	}
+	if n != 2 {
+		t.Fatalf("expected 2 migrated secrets, got %d", n)
+	}
+	if len(d.execs) != 2 {
+		t.Fatalf("expected 2 updates, got %v", d.execs)
+	}
+	for _, query := range d.execs {
+		if !strings.HasPrefix(query, `UPDATE "secrets"`) {
+			t.Errorf("unexpected statement %s", query)
+		}
+	}
+	if !containsValue(d.args[0], "backup") || !containsValue(d.args[0], "backup.tar") || !containsValue(d.args[0], "nightly backup") {
+		t.Errorf("file secret got arguments %v", d.args[0])
+	}
+	if !containsValue(d.args[1], "notes") {
+		t.Errorf("text secret got arguments %v", d.args[1])
+	}
+}
+
+func containsValue(values []driver.Value, want string) bool {
+	for _, v := range values {
+		if s, ok := v.(string); ok && s == want {
+			return true
+		}
+	}
+	return false
+}
+
+func TestSetEmergencyWaitKeepsState(t *testing.T) {
+	d := &stubDriver{}
+	repo := GetEmergencyRepo(openStub(t, "stub-emergency-wait", d))
+
+	contact := &em.EmergencyContact{ID: 4, OwnerID: 1, ContactID: 2, State: em.StateIdle, WaitHours: 24}
+	if err := repo.SetEmergencyWait(contact, 48); err != nil {
+		t.Fatalf("didn't expect error, got %v", err)
+	}
+	if len(d.execs) != 1 {
+		t.Fatalf("expected 1 update, got %v", d.execs)
+	}
+	// The state of the stale contact must not overwrite a transition.
+	if query := d.execs[0]; !strings.Contains(query, `"wait_hours"`) || strings.Contains(query, `"state"`) {
+		t.Errorf("unexpected statement %s", query)
+	}
+}
+
+func TestGetChangesCoversVaultsAndShares(t *testing.T) {
+	d := &stubDriver{}
+	repo := GetSecretRepo(openStub(t, "stub-changes-scope", d))
+
+	if _, err := repo.GetChanges(2, 41, 10); err != nil {
+		t.Fatalf("didn't expect error, got %v", err)
+	}
+	var secrets, tombstones string
+	for _, query := range d.queries {
+		switch {
+		case strings.Contains(query, `FROM "secrets"`):
+			secrets = query
+		case strings.Contains(query, `FROM "tombstones"`):
+			tombstones = query
+		}
+	}
+	// Secrets of vaults and shares belong to other accounts, the feed must
+	// not be limited to the secrets the caller owns.
+	for _, table := range []string{"vaults", "members", "shares"} {
+		if !strings.Contains(secrets, table) {
+			t.Errorf("expected the secrets query to cover %s, got %s", table, secrets)
+		}
+	}
+	for _, table := range []string{"vaults", "members"} {
+		if !strings.Contains(tombstones, table) {
+			t.Errorf("expected the tombstones query to cover %s, got %s", table, tombstones)
+		}
+	}
+}
+
+func TestMoveSecretToVaultMovesCertificate(t *testing.T) {
+	notAfter := time.Now().Add(24 * time.Hour)
+	// certificate_infos as secret ID to owner.
+	owners := map[int64]int64{7: 1}
+	d := &stubDriver{
+		onExec: func(query string, args []driver.Value) {
+			if strings.HasPrefix(query, `UPDATE "certificate_infos"`) {
+				owners[args[1].(int64)] = args[0].(int64)
+			}
+		},
+		onQuery: func(query string, args []driver.Value) ([]string, [][]driver.Value) {
+			var rows [][]driver.Value
+			if strings.Contains(query, `FROM "certificate_infos"`) {
+				for secretID, userID := range owners {
+					if userID == args[0].(int64) {
+						rows = append(rows, []driver.Value{secretID, userID, notAfter})
+					}
+				}
+			}
+			return []string{"secret_id", "user_id", "not_after"}, rows
+		},
+	}
+	repo := GetSecretRepo(openStub(t, "stub-move-certificate", d))
+
+	s := &sec.Secret{ID: 7, UserID: 1, SecretType: "Certificate"}
+	if err := repo.MoveSecretToVault(s, &org.Vault{ID: 3, UserID: 2, Name: "work"}, 1); err != nil {
+		t.Fatalf("didn't expect error, got %v", err)
+	}
+	before := notAfter.Add(time.Hour)
+	if certs, err := repo.GetExpiringCertificates(1, before); err != nil || len(certs) != 0 {
+		t.Errorf("expected no certificates of the previous owner, got %+v, %v", certs, err)
+	}
+	certs, err := repo.GetExpiringCertificates(2, before)
+	if err != nil || len(certs) != 1 || certs[0].SecretID != 7 {
+		t.Errorf("expected the certificate of the new owner, got %+v, %v", certs, err)
+	}
+}
+
+func TestEnsureIndexesCoverSortOrders(t *testing.T) {
+	d := &stubDriver{}
+	repo := GetMigrationRepo(openStub(t, "stub-sort-indexes", d))
+
+	if err := repo.EnsureIndexes(); err != nil {
+		t.Fatalf("didn't expect error, got %v", err)
+	}
+	for sort, expr := range sortExpressions {
+		for _, scope := range []string{"user_id", "vault_id"} {
+			columns := "(" + scope + ", " + expr + ", id)"
+			found := false
+			for _, stmt := range d.execs {
+				found = found || (strings.HasPrefix(stmt, "CREATE INDEX") && strings.HasSuffix(stmt, columns))
+			}
+			if !found {
+				t.Errorf("expected an index on %s for sort %s", columns, sort)
+			}
+		}
+	}
+}
+
+func TestTagFiltersCoverVaultSecretsOfOtherMembers(t *testing.T) {
+	d := &stubDriver{}
+	repo := GetSecretRepo(openStub(t, "stub-vault-tags", d))
+
+	if _, err := repo.GetSecretsForUser(2, SecretFilter{VaultID: 5, Tags: []string{"prod"}}); err != nil {
+		t.Fatalf("didn't expect error, got %v", err)
+	}
+	q := sec.SearchQuery{Terms: []sec.SearchTerm{{Text: "prod"}}, VaultID: 5}
+	if _, err := repo.SearchSecrets(2, q, 10); err != nil {
+		t.Fatalf("didn't expect error, got %v", err)
+	}
+	if len(d.queries) == 0 {
+		t.Fatal("expected queries")
+	}
+	for _, query := range d.queries {
+		if strings.Contains(query, "tags.user_id") {
+			t.Errorf("expected tags of all members to match, got %s", query)
+		}
+	}
+}
+
+func TestEmptyTrashReturnsOwners(t *testing.T) {
+	d := &stubDriver{
+		columns: []string{"id", "user_id"},
+		rows:    [][]driver.Value{{int64(3), int64(1)}, {int64(4), int64(2)}},
+	}
+	repo := GetSecretRepo(openStub(t, "stub-empty-trash", d))
+
+	purged, err := repo.EmptyTrash(1, 5)
+	if err != nil {
+		t.Fatalf("didn't expect error, got %v", err)
+	}
+	if len(purged) != 2 || purged[0].UserID != 1 || purged[1].ID != 4 || purged[1].UserID != 2 {
+		t.Errorf("expected the purged secrets with their owners, got %+v", purged)
+	}
+}
@@ -0,0 +1,116 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// States of an emergency contact. A designated contact is idle until it
+// requests access. The request is granted when the owner approves it or when
+// the waiting period passes without the owner rejecting it.
+const (
+	StateIdle      = "idle"
+	StateRequested = "requested"
+	StateGranted   = "granted"
+	StateRejected  = "rejected"
+)
+
+// Actions changing the state of an emergency contact. Contacts request and
+// cancel, owners approve, reject and revoke, and the server times out
+// requests whose waiting period has passed.
+const (
+	ActionRequest = "request"
+	ActionCancel  = "cancel"
+	ActionApprove = "approve"
+	ActionReject  = "reject"
+	ActionRevoke  = "revoke"
+	ActionTimeout = "timeout"
+)
+
+// Limits of the waiting period of emergency contacts.
+const (
+	DefaultWaitHours = 7 * 24
+	MaxWaitHours     = 365 * 24
+)
+
+var transitions = map[string]map[string]string{
+	StateIdle:      {ActionRequest: StateRequested},
+	StateRejected:  {ActionRequest: StateRequested},
+	StateRequested: {ActionApprove: StateGranted, ActionReject: StateRejected, ActionTimeout: StateGranted, ActionCancel: StateIdle},
+	StateGranted:   {ActionRevoke: StateIdle},
+}
+
+// Transition returns the state which the action leads to from state, or an
+// error if the action is not possible in that state.
+func Transition(state, action string) (string, error) {
+	next, ok := transitions[state][action]
+	if !ok {
+		return "", fmt.Errorf("cannot %s emergency access which is %s", action, state)
+	}
+	return next, nil
+}
+
+// OwnerAction reports whether the action is taken by the owner, as opposed
+// to the contact or the server.
+func OwnerAction(action string) bool {
+	return action == ActionApprove || action == ActionReject || action == ActionRevoke
+}
+
+// ContactAction reports whether the action is taken by the contact.
+func ContactAction(action string) bool {
+	return action == ActionRequest || action == ActionCancel
+}
+
+// EmergencyContact allows the account ContactID to request read access to
+// the personal secrets of the account OwnerID.
+type EmergencyContact struct {
+	ID        uint `gorm:"primary_key"`
+	OwnerID   uint `gorm:"unique_index:idx_emergency_owner_contact"`
+	ContactID uint `gorm:"unique_index:idx_emergency_owner_contact;index"`
+	WaitHours int
+	State     string `sql:"index"`
+	// RequestedAt is when the current or last request was made.
+	RequestedAt *time.Time `json:",omitempty"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	// Login is the login of the other account, filled in for listings.
+	Login string `gorm:"-" json:",omitempty"`
+}
+
+// GrantAt returns when a pending request is granted automatically, or nil if
+// no request is pending.
+func (c *EmergencyContact) GrantAt() *time.Time {
+	if c.State != StateRequested || c.RequestedAt == nil {
+		return nil
+	}
+	at := c.RequestedAt.Add(time.Duration(c.WaitHours) * time.Hour)
+	return &at
+}
+
+// EmergencyEvent records a change of state of an emergency contact. ActorID
+// is zero for changes made by the server.
+type EmergencyEvent struct {
+	ID        uint `gorm:"primary_key"`
+	ContactID uint `gorm:"index"`
+	Action    string
+	From      string
+	To        string
+	ActorID   uint
+	CreatedAt time.Time
+	// Actor is the login of the actor, filled in for listings.
+	Actor string `gorm:"-" json:",omitempty"`
+}
+
+// ContactRequest designates an emergency contact or changes its waiting
+// period.
+type ContactRequest struct {
+	WaitHours int `json:"wait_hours"`
+}
+
+// Validate checks the waiting period.
+func (r *ContactRequest) Validate() error {
+	if r.WaitHours < 1 || r.WaitHours > MaxWaitHours {
+		return fmt.Errorf("waiting period must be between 1 hour and %d days", MaxWaitHours/24)
+	}
+	return nil
+}
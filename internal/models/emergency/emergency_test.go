@@ -0,0 +1,48 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTransition(t *testing.T) {
+	tests := []struct {
+		state, action, want string
+		expectErr           bool
+	}{
+		{StateIdle, ActionRequest, StateRequested, false},
+		{StateRejected, ActionRequest, StateRequested, false},
+		{StateRequested, ActionApprove, StateGranted, false},
+		{StateRequested, ActionTimeout, StateGranted, false},
+		{StateRequested, ActionReject, StateRejected, false},
+		{StateRequested, ActionCancel, StateIdle, false},
+		{StateGranted, ActionRevoke, StateIdle, false},
+		{StateIdle, ActionApprove, "", true},
+		{StateGranted, ActionRequest, "", true},
+		{StateRejected, ActionTimeout, "", true},
+		{StateRequested, ActionRequest, "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := Transition(tt.state, tt.action)
+		if (err != nil) != tt.expectErr {
+			t.Errorf("Transition(%s, %s) error = %v, expectErr %v", tt.state, tt.action, err, tt.expectErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Transition(%s, %s) = %s, want %s", tt.state, tt.action, got, tt.want)
+		}
+	}
+}
+
+func TestGrantAt(t *testing.T) {
+	requested := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	c := EmergencyContact{State: StateRequested, RequestedAt: &requested, WaitHours: 48}
+	if got := c.GrantAt(); got == nil || !got.Equal(requested.Add(48*time.Hour)) {
+		t.Errorf("GrantAt() = %v, want %v", got, requested.Add(48*time.Hour))
+	}
+	c.State = StateRejected
+	if got := c.GrantAt(); got != nil {
+		t.Errorf("GrantAt() = %v for a rejected request, want nil", got)
+	}
+}
@@ -0,0 +1,100 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Roles of organization members, from most to least privileged. Owners
+// manage the organization and all members, admins manage vaults and the
+// editors and viewers, editors change secrets and viewers read them.
+const (
+	RoleOwner  = "owner"
+	RoleAdmin  = "admin"
+	RoleEditor = "editor"
+	RoleViewer = "viewer"
+)
+
+var roleRanks = map[string]int{
+	RoleOwner:  4,
+	RoleAdmin:  3,
+	RoleEditor: 2,
+	RoleViewer: 1,
+}
+
+// ValidRole reports whether role is one of the member roles.
+func ValidRole(role string) bool {
+	_, ok := roleRanks[role]
+	return ok
+}
+
+// RoleAllows reports whether role grants at least the rights of required. An
+// empty role, as for non-members, allows nothing.
+func RoleAllows(role, required string) bool {
+	return roleRanks[role] > 0 && roleRanks[role] >= roleRanks[required]
+}
+
+var nameRegexp = regexp.MustCompile(`^[a-z0-9][a-z0-9_.-]*$`)
+
+// NormalizeName lower-cases an organization or vault name and checks that it
+// only contains letters, digits and the separators _ . -.
+func NormalizeName(name string) (string, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if len(name) > 64 || !nameRegexp.MatchString(name) {
+		return "", fmt.Errorf("invalid name: %q", name)
+	}
+	return name, nil
+}
+
+// Organization owns vaults whose secrets belong to the team rather than to
+// one account.
+type Organization struct {
+	ID        uint   `gorm:"primary_key"`
+	Name      string `gorm:"unique_index"`
+	CreatedAt time.Time
+	// Role is the role of the caller, filled in for listings.
+	Role string `gorm:"-" json:",omitempty"`
+}
+
+// Member gives an account a role in an organization.
+type Member struct {
+	ID        uint `gorm:"primary_key"`
+	OrgID     uint `gorm:"unique_index:idx_member_org_user"`
+	UserID    uint `gorm:"unique_index:idx_member_org_user;index"`
+	Role      string
+	CreatedAt time.Time
+	// Login is the login of the member, filled in for listings.
+	Login string `gorm:"-" json:",omitempty"`
+}
+
+// DefaultVault is the name of the vault holding the personal secrets which
+// are in no other vault. It is not stored and has the vault ID zero.
+const DefaultVault = "default"
+
+// Vault is a named collection of secrets owned by an organization or, for
+// personal vaults, by the account UserID.
+type Vault struct {
+	ID        uint   `gorm:"primary_key"`
+	OrgID     uint   `gorm:"unique_index:idx_vault_owner_name"`
+	UserID    uint   `gorm:"unique_index:idx_vault_owner_name" json:",omitempty"`
+	Name      string `gorm:"unique_index:idx_vault_owner_name"`
+	CreatedAt time.Time
+}
+
+// Personal reports whether the vault belongs to an account rather than an
+// organization.
+func (v *Vault) Personal() bool {
+	return v.OrgID == 0
+}
+
+// MemberRequest sets the role of a member.
+type MemberRequest struct {
+	Role string `json:"role"`
+}
+
+// NameRequest creates an organization or vault.
+type NameRequest struct {
+	Name string `json:"name"`
+}
@@ -0,0 +1,48 @@
+package models
+
+import "testing"
+
+func TestRoleAllows(t *testing.T) {
+	tests := []struct {
+		role, required string
+		want           bool
+	}{
+		{RoleOwner, RoleAdmin, true},
+		{RoleAdmin, RoleAdmin, true},
+		{RoleEditor, RoleViewer, true},
+		{RoleEditor, RoleAdmin, false},
+		{RoleViewer, RoleEditor, false},
+		{"", RoleViewer, false},
+		{"guest", RoleViewer, false},
+	}
+
+	for _, tt := range tests {
+		if got := RoleAllows(tt.role, tt.required); got != tt.want {
+			t.Errorf("RoleAllows(%q, %q) = %v, want %v", tt.role, tt.required, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeName(t *testing.T) {
+	tests := []struct {
+		in, want  string
+		expectErr bool
+	}{
+		{in: " Payments ", want: "payments"},
+		{in: "team-1.prod", want: "team-1.prod"},
+		{in: "-team", expectErr: true},
+		{in: "team/prod", expectErr: true},
+		{in: "", expectErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := NormalizeName(tt.in)
+		if (err != nil) != tt.expectErr {
+			t.Errorf("NormalizeName(%q) error = %v, expectErr %v", tt.in, err, tt.expectErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("NormalizeName(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+)
+
+// Attachment is a file stored alongside a secret of any type. Filenames are
+// unique per secret.
+type Attachment struct {
+	ID       uint   `gorm:"primary_key"`
+	SecretID uint   `gorm:"unique_index:idx_attachment_secret_filename"`
+	Filename string `gorm:"unique_index:idx_attachment_secret_filename"`
+	MimeType string
+	Size     int64
+	Checksum string
+	Data     ByteSlice `json:",omitempty"`
+}
+
+// NewAttachment builds an attachment for the secret and computes its size and
+// SHA-256 checksum.
+func NewAttachment(secretID uint, filename, mimeType string, data []byte) (Attachment, error) {
+	filename = filepath.Base(filename)
+	if filename == "." || filename == "/" || filename == "" {
+		return Attachment{}, fmt.Errorf("attachment filename is required")
+	}
+	if mimeType == "" {
+		mimeType = "application/octet-stream"
+	}
+	return Attachment{
+		SecretID: secretID,
+		Filename: filename,
+		MimeType: mimeType,
+		Size:     int64(len(data)),
+		Checksum: Checksum(data),
+		Data:     data,
+	}, nil
+}
+
+// Checksum returns the hex encoded SHA-256 of data.
+func Checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Verify checks that the attachment data matches its checksum.
+func (a *Attachment) Verify() error {
+	if Checksum(a.Data) != a.Checksum {
+		return fmt.Errorf("checksum mismatch for attachment %s", a.Filename)
+	}
+	return nil
+}
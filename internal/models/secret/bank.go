@@ -0,0 +1,115 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var bicRegexp = regexp.MustCompile(`^[A-Z]{4}[A-Z]{2}[A-Z0-9]{2}([A-Z0-9]{3})?$`)
+
+type BankAccount struct {
+	Holder        string
+	BankName      string
+	IBAN          string
+	BIC           string
+	AccountNumber string
+	RoutingNumber string
+}
+
+func (ba *BankAccount) ToBytes() (ByteSlice, error) {
+	data, err := json.Marshal(ba)
+	if err != nil {
+		return nil, err
+	}
+	return ByteSlice(data), nil
+}
+
+func (ba *BankAccount) FromBytes(data ByteSlice) error {
+	return json.Unmarshal([]byte(data), ba)
+}
+
+func (ba *BankAccount) String() string {
+	return fmt.Sprintf("{ \"Holder\": \"%s\", \"BankName\": \"%s\", \"IBAN\": \"%s\", \"BIC\": \"%s\" }", ba.Holder, ba.BankName, MaskDigits(ba.IBAN), ba.BIC)
+}
+
+// Validate checks the IBAN and BIC of the account and normalizes both to the
+// compact upper case form.
+func (ba *BankAccount) Validate() error {
+	if ba.Holder == "" {
+		return fmt.Errorf("account holder is required")
+	}
+	if ba.IBAN == "" && ba.AccountNumber == "" {
+		return fmt.Errorf("either IBAN or account number is required")
+	}
+	if ba.IBAN != "" {
+		if err := ValidateIBAN(ba.IBAN); err != nil {
+			return err
+		}
+		ba.IBAN = compactUpper(ba.IBAN)
+	}
+	if ba.BIC != "" {
+		if err := ValidateBIC(ba.BIC); err != nil {
+			return err
+		}
+		ba.BIC = compactUpper(ba.BIC)
+	}
+	return nil
+}
+
+// ValidateIBAN checks the structure and the mod-97 check digits of an IBAN.
+// Spaces between the groups are allowed.
+func ValidateIBAN(iban string) error {
+	s := compactUpper(iban)
+	if len(s) < 15 || len(s) > 34 {
+		return fmt.Errorf("IBAN has invalid length")
+	}
+	for i, r := range s {
+		switch {
+		case i < 2 && (r < 'A' || r > 'Z'):
+			return fmt.Errorf("IBAN must start with a country code")
+		case i >= 2 && i < 4 && (r < '0' || r > '9'):
+			return fmt.Errorf("IBAN check digits are invalid")
+		case (r < 'A' || r > 'Z') && (r < '0' || r > '9'):
+			return fmt.Errorf("IBAN contains invalid characters")
+		}
+	}
+
+	// Move the country code and check digits to the end and compute the
+	// remainder digit by digit, letters count as two digits (A=10 ... Z=35).
+	rearranged := s[4:] + s[:4]
+	remainder := 0
+	for _, r := range rearranged {
+		if r >= 'A' && r <= 'Z' {
+			remainder = (remainder*100 + int(r-'A') + 10) % 97
+		} else {
+			remainder = (remainder*10 + int(r-'0')) % 97
+		}
+	}
+	if remainder != 1 {
+		return fmt.Errorf("IBAN checksum is invalid")
+	}
+	return nil
+}
+
+// ValidateBIC checks the format of a BIC/SWIFT code (8 or 11 characters).
+func ValidateBIC(bic string) error {
+	if !bicRegexp.MatchString(compactUpper(bic)) {
+		return fmt.Errorf("BIC is invalid")
+	}
+	return nil
+}
+
+// MaskDigits hides everything but the last four characters of s.
+func MaskDigits(s string) string {
+	s = compactUpper(s)
+	if len(s) <= 4 {
+		return s
+	}
+	return strings.Repeat("*", len(s)-4) + s[len(s)-4:]
+}
+
+func compactUpper(s string) string {
+	return strings.ToUpper(strings.ReplaceAll(s, " ", ""))
+}
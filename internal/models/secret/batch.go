@@ -0,0 +1,56 @@
+package models
+
+// Operations of a batch request.
+const (
+	OpCreate = "create"
+	OpUpdate = "update"
+	OpDelete = "delete"
+)
+
+// BatchOperation is one operation of a batch. Creates and updates carry the
+// secret, updates and deletes address an existing secret by ID or path. Paths
+// refer to the vault VaultID or, if it is zero, to the personal secrets.
+type BatchOperation struct {
+	Op      string         `json:"op"`
+	ID      uint           `json:"id,omitempty"`
+	Path    string         `json:"path,omitempty"`
+	VaultID uint           `json:"vault_id,omitempty"`
+	Secret  *SecretRequest `json:"secret,omitempty"`
+}
+
+// BatchRequest is applied in one transaction: either all of its operations
+// take effect or none of them.
+type BatchRequest struct {
+	Operations []BatchOperation `json:"operations"`
+}
+
+// BatchResult reports the outcome of one operation using HTTP status codes.
+// ID is the secret created, updated or deleted and OwnerID the account it
+// belongs to, which the audit log records.
+type BatchResult struct {
+	Op      string `json:"op"`
+	ID      uint   `json:"id,omitempty"`
+	OwnerID uint   `json:"owner_id,omitempty"`
+	Status  int    `json:"status"`
+	Error   string `json:"error,omitempty"`
+}
+
+// BatchResponse holds a result for every operation of the request, in order.
+// Once an operation fails the batch is rolled back, Committed is false and
+// the remaining operations are not attempted.
+type BatchResponse struct {
+	Committed bool          `json:"committed"`
+	Results   []BatchResult `json:"results"`
+}
+
+// Failed returns the results of the operations which were attempted and
+// failed.
+func (br *BatchResponse) Failed() []BatchResult {
+	var failed []BatchResult
+	for _, r := range br.Results {
+		if r.Status != 0 && r.Status != 200 {
+			failed = append(failed, r)
+		}
+	}
+	return failed
+}
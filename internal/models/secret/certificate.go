@@ -0,0 +1,123 @@
+package models
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Certificate is a PEM encoded certificate chain with an optional private key
+// for the leaf. The descriptive fields are filled in by Validate.
+type Certificate struct {
+	Chain      string
+	PrivateKey string
+	Subject    string
+	SANs       []string
+	Issuer     string
+	Serial     string
+	NotAfter   time.Time
+}
+
+// CertificateInfo keeps the searchable part of a Certificate secret next to
+// the secret itself, so expiry reports do not have to decode every value.
+type CertificateInfo struct {
+	SecretID uint `gorm:"primary_key;auto_increment:false"`
+	UserID   uint `gorm:"index"`
+	Subject  string
+	SANs     string
+	Issuer   string
+	Serial   string
+	NotAfter time.Time `gorm:"index"`
+}
+
+// Validator is implemented by secret values which have to be checked before
+// they are stored.
+type Validator interface {
+	Validate() error
+}
+
+func (c *Certificate) ToBytes() (ByteSlice, error) {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+	return ByteSlice(data), nil
+}
+
+func (c *Certificate) FromBytes(data ByteSlice) error {
+	return json.Unmarshal([]byte(data), c)
+}
+
+func (c *Certificate) String() string {
+	return fmt.Sprintf("{ \"Subject\": \"%s\", \"Issuer\": \"%s\", \"Serial\": \"%s\", \"NotAfter\": \"%s\" }", c.Subject, c.Issuer, c.Serial, c.NotAfter.Format(time.RFC3339))
+}
+
+// Validate parses the chain, checks that the private key (if any) belongs to
+// the leaf certificate and fills the descriptive fields from the leaf.
+func (c *Certificate) Validate() error {
+	chain, err := parseCertificateChain([]byte(c.Chain))
+	if err != nil {
+		return err
+	}
+	if c.PrivateKey != "" {
+		if _, err := tls.X509KeyPair([]byte(c.Chain), []byte(c.PrivateKey)); err != nil {
+			return fmt.Errorf("private key does not match the leaf certificate: %w", err)
+		}
+	}
+
+	leaf := chain[0]
+	c.Subject = leaf.Subject.String()
+	c.Issuer = leaf.Issuer.String()
+	c.Serial = leaf.SerialNumber.Text(16)
+	c.NotAfter = leaf.NotAfter
+	c.SANs = c.SANs[:0]
+	c.SANs = append(c.SANs, leaf.DNSNames...)
+	for _, ip := range leaf.IPAddresses {
+		c.SANs = append(c.SANs, ip.String())
+	}
+	c.SANs = append(c.SANs, leaf.EmailAddresses...)
+	for _, uri := range leaf.URIs {
+		c.SANs = append(c.SANs, uri.String())
+	}
+	return nil
+}
+
+// Info returns the searchable fields of a validated certificate.
+func (c *Certificate) Info(secretID, userID uint) CertificateInfo {
+	return CertificateInfo{
+		SecretID: secretID,
+		UserID:   userID,
+		Subject:  c.Subject,
+		SANs:     strings.Join(c.SANs, ","),
+		Issuer:   c.Issuer,
+		Serial:   c.Serial,
+		NotAfter: c.NotAfter,
+	}
+}
+
+func parseCertificateChain(data []byte) ([]*x509.Certificate, error) {
+	var chain []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse certificate: %w", err)
+		}
+		chain = append(chain, cert)
+	}
+	if len(chain) == 0 {
+		return nil, fmt.Errorf("no PEM certificate found")
+	}
+	return chain, nil
+}
@@ -0,0 +1,184 @@
+package models
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// HiddenMarker is the dotenv comment which marks the next variable as hidden.
+const HiddenMarker = "# passkeeper:hidden"
+
+var envKeyRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.]*$`)
+
+type EnvVar struct {
+	Key    string
+	Value  string
+	Hidden bool
+}
+
+// EnvBundle is an ordered set of environment variables, e.g. the whole
+// environment of one service.
+type EnvBundle struct {
+	Vars []EnvVar
+}
+
+func (eb *EnvBundle) ToBytes() (ByteSlice, error) {
+	data, err := json.Marshal(eb)
+	if err != nil {
+		return nil, err
+	}
+	return ByteSlice(data), nil
+}
+
+func (eb *EnvBundle) FromBytes(data ByteSlice) error {
+	return json.Unmarshal([]byte(data), eb)
+}
+
+func (eb *EnvBundle) String() string {
+	return fmt.Sprintf("{ \"Keys\": \"%s\" }", strings.Join(eb.Keys(), ", "))
+}
+
+// Keys returns the variable names in order.
+func (eb *EnvBundle) Keys() []string {
+	keys := make([]string, len(eb.Vars))
+	for i, v := range eb.Vars {
+		keys[i] = v.Key
+	}
+	return keys
+}
+
+// Set updates the variable in place or appends it to the end of the bundle.
+func (eb *EnvBundle) Set(key, value string, hidden bool) {
+	for i := range eb.Vars {
+		if eb.Vars[i].Key == key {
+			eb.Vars[i].Value = value
+			eb.Vars[i].Hidden = hidden
+			return
+		}
+	}
+	eb.Vars = append(eb.Vars, EnvVar{Key: key, Value: value, Hidden: hidden})
+}
+
+// Validate checks that every key is a valid variable name and is used once.
+func (eb *EnvBundle) Validate() error {
+	seen := make(map[string]bool, len(eb.Vars))
+	for _, v := range eb.Vars {
+		if !envKeyRegexp.MatchString(v.Key) {
+			return fmt.Errorf("invalid variable name %q", v.Key)
+		}
+		if seen[v.Key] {
+			return fmt.Errorf("duplicate variable %s", v.Key)
+		}
+		seen[v.Key] = true
+	}
+	return nil
+}
+
+// ParseDotenv reads variables in dotenv syntax. Values may be unquoted, single
+// quoted (taken literally) or double quoted (with \n, \" and \\ escapes).
+// A "# passkeeper:hidden" comment marks the following variable as hidden.
+func ParseDotenv(data string) (*EnvBundle, error) {
+	bundle := &EnvBundle{}
+	hidden := false
+	scanner := bufio.NewScanner(strings.NewReader(data))
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "#") {
+			if line == HiddenMarker {
+				hidden = true
+			}
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		eq := strings.Index(line, "=")
+		if eq < 0 {
+			return nil, fmt.Errorf("line %d: expected KEY=VALUE", lineNo)
+		}
+		key := strings.TrimSpace(line[:eq])
+		if !envKeyRegexp.MatchString(key) {
+			return nil, fmt.Errorf("line %d: invalid variable name %q", lineNo, key)
+		}
+		value, err := parseDotenvValue(strings.TrimSpace(line[eq+1:]))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+		bundle.Set(key, value, hidden)
+		hidden = false
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return bundle, nil
+}
+
+func parseDotenvValue(raw string) (string, error) {
+	switch {
+	case strings.HasPrefix(raw, "'"):
+		end := strings.Index(raw[1:], "'")
+		if end < 0 {
+			return "", fmt.Errorf("unterminated single quote")
+		}
+		return raw[1 : end+1], nil
+	case strings.HasPrefix(raw, `"`):
+		var b strings.Builder
+		for i := 1; i < len(raw); i++ {
+			switch c := raw[i]; c {
+			case '"':
+				return b.String(), nil
+			case '\\':
+				if i+1 == len(raw) {
+					return "", fmt.Errorf("unterminated double quote")
+				}
+				i++
+				switch raw[i] {
+				case 'n':
+					b.WriteByte('\n')
+				case 't':
+					b.WriteByte('\t')
+				default:
+					b.WriteByte(raw[i])
+				}
+			default:
+				b.WriteByte(c)
+			}
+		}
+		return "", fmt.Errorf("unterminated double quote")
+	default:
+		if i := strings.Index(raw, " #"); i >= 0 {
+			raw = raw[:i]
+		}
+		return strings.TrimSpace(raw), nil
+	}
+}
+
+// Dotenv renders the bundle in dotenv syntax which ParseDotenv reads back.
+func (eb *EnvBundle) Dotenv() string {
+	var b strings.Builder
+	for _, v := range eb.Vars {
+		if v.Hidden {
+			b.WriteString(HiddenMarker)
+			b.WriteByte('\n')
+		}
+		b.WriteString(v.Key)
+		b.WriteByte('=')
+		b.WriteString(quoteDotenvValue(v.Value))
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+func quoteDotenvValue(value string) string {
+	if strings.ContainsAny(value, " \t\n\"'#\\=$") {
+		r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\t", `\t`)
+		return `"` + r.Replace(value) + `"`
+	}
+	return value
+}
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// SecretWarningPeriod is how long before its expiry date a secret is
+// highlighted as expiring soon.
+const SecretWarningPeriod = 7 * 24 * time.Hour
+
+// Expired reports whether the secret has an expiry date which has passed.
+func (s *Secret) Expired(now time.Time) bool {
+	return s.ExpiresAt != nil && !s.ExpiresAt.After(now)
+}
+
+// ExpiryWarning returns a short warning if the secret has expired or expires
+// within SecretWarningPeriod, and an empty string otherwise.
+func (ds *DecodedSecret) ExpiryWarning(now time.Time) string {
+	if ds.ExpiresAt == nil {
+		return ""
+	}
+	left := ds.ExpiresAt.Sub(now)
+	switch {
+	case left <= 0:
+		return "EXPIRED"
+	case left < 24*time.Hour:
+		return fmt.Sprintf("expires in %d hours", int(left.Hours()))
+	case left < SecretWarningPeriod:
+		return fmt.Sprintf("expires in %d days", int(left.Hours()/24))
+	default:
+		return ""
+	}
+}
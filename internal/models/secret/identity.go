@@ -0,0 +1,83 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// DateLayout is the layout of the dates stored in Identity documents.
+const DateLayout = "2006-01-02"
+
+// IdentityWarningPeriod is how long before the expiry date an identity
+// document is reported as expiring.
+const IdentityWarningPeriod = 90 * 24 * time.Hour
+
+// Identity describes a passport, ID card or similar document. Scans of the
+// document are stored as attachments of its secret.
+type Identity struct {
+	DocumentType  string
+	Number        string
+	Country       string
+	NameAsPrinted string
+	IssueDate     string
+	ExpiryDate    string
+}
+
+func (id *Identity) ToBytes() (ByteSlice, error) {
+	data, err := json.Marshal(id)
+	if err != nil {
+		return nil, err
+	}
+	return ByteSlice(data), nil
+}
+
+func (id *Identity) FromBytes(data ByteSlice) error {
+	return json.Unmarshal([]byte(data), id)
+}
+
+func (id *Identity) String() string {
+	return fmt.Sprintf("{ \"DocumentType\": \"%s\", \"Number\": \"%s\", \"Country\": \"%s\", \"ExpiryDate\": \"%s\" }", id.DocumentType, id.Number, id.Country, id.ExpiryDate)
+}
+
+// Validate checks that the document number is present and that the dates are
+// well formed and in order.
+func (id *Identity) Validate() error {
+	if id.Number == "" {
+		return fmt.Errorf("document number is required")
+	}
+	var issued, expires time.Time
+	var err error
+	if id.IssueDate != "" {
+		if issued, err = time.Parse(DateLayout, id.IssueDate); err != nil {
+			return fmt.Errorf("issue date must be in YYYY-MM-DD format")
+		}
+	}
+	if id.ExpiryDate != "" {
+		if expires, err = time.Parse(DateLayout, id.ExpiryDate); err != nil {
+			return fmt.Errorf("expiry date must be in YYYY-MM-DD format")
+		}
+	}
+	if !issued.IsZero() && !expires.IsZero() && expires.Before(issued) {
+		return fmt.Errorf("expiry date is before issue date")
+	}
+	return nil
+}
+
+// ExpiryWarning returns a short warning if the document has expired or expires
+// within IdentityWarningPeriod, and an empty string otherwise.
+func (id *Identity) ExpiryWarning(now time.Time) string {
+	expires, err := time.Parse(DateLayout, id.ExpiryDate)
+	if err != nil {
+		return ""
+	}
+	left := expires.Sub(now)
+	switch {
+	case left < 0:
+		return "EXPIRED"
+	case left < IdentityWarningPeriod:
+		return fmt.Sprintf("expires in %d days", int(left.Hours()/24))
+	default:
+		return ""
+	}
+}
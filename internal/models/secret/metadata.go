@@ -0,0 +1,130 @@
+package models
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// FileInfo describes the original file of a ByteSlice secret.
+type FileInfo struct {
+	Filename string `json:",omitempty"`
+	MimeType string `json:",omitempty"`
+	Size     int64  `json:",omitempty"`
+	SHA256   string `json:",omitempty"`
+	Mode     uint32 `json:",omitempty"`
+}
+
+// SecretMeta is the typed metadata of a secret. It replaces the free form
+// Metadata string, which is kept for clients using the name|ext|description
+// format.
+type SecretMeta struct {
+	Name        string   `json:",omitempty"`
+	Description string   `json:",omitempty"`
+	File        FileInfo `gorm:"embedded;embedded_prefix:file_"`
+}
+
+// IsZero reports whether no metadata field is set.
+func (m SecretMeta) IsZero() bool {
+	return m == SecretMeta{}
+}
+
+// FileMode returns the permission bits to restore the file with, 0600 if the
+// original mode is unknown.
+func (f FileInfo) FileMode() os.FileMode {
+	if f.Mode == 0 {
+		return 0600
+	}
+	return os.FileMode(f.Mode).Perm()
+}
+
+// ParseLegacyMetadata reads the name|ext|description format of older
+// clients. The extension may be empty and the description may contain "|".
+// Strings in any other format are taken as the description.
+func ParseLegacyMetadata(meta string) (SecretMeta, bool) {
+	parts := strings.SplitN(meta, "|", 3)
+	if len(parts) != 3 || parts[0] == "" {
+		return SecretMeta{Description: meta}, false
+	}
+	filename := parts[0]
+	if parts[1] != "" {
+		filename += "." + parts[1]
+	}
+	return SecretMeta{
+		Name:        parts[0],
+		Description: parts[2],
+		File:        FileInfo{Filename: filename},
+	}, true
+}
+
+// LegacyMetadata renders the metadata in the format older clients parse:
+// name|ext|description for files and the description otherwise.
+func (m SecretMeta) LegacyMetadata() string {
+	if m.File.Filename == "" {
+		if m.Description == "" {
+			return m.Name
+		}
+		return m.Description
+	}
+	ext := filepath.Ext(m.File.Filename)
+	name := strings.TrimSuffix(m.File.Filename, ext)
+	return name + "|" + strings.TrimPrefix(ext, ".") + "|" + m.Description
+}
+
+// Label is the short name shown for the secret in listings.
+func (m SecretMeta) Label() string {
+	switch {
+	case m.Name != "":
+		return m.Name
+	case m.File.Filename != "":
+		return m.File.Filename
+	default:
+		return m.Description
+	}
+}
+
+// TypedMeta returns the typed metadata of the secret, falling back to parsing
+// the legacy Metadata string for secrets which have not been migrated.
+func (s *Secret) TypedMeta() SecretMeta {
+	return typedMeta(s.Meta, s.Metadata, s.SecretType == "ByteSlice")
+}
+
+// TypedMeta works like Secret.TypedMeta.
+func (ds *DecodedSecret) TypedMeta() SecretMeta {
+	_, isFile := ds.Value.(*ByteSlice)
+	return typedMeta(ds.Meta, ds.Metadata, isFile)
+}
+
+func typedMeta(meta SecretMeta, legacy string, isFile bool) SecretMeta {
+	if !meta.IsZero() || legacy == "" {
+		return meta
+	}
+	return legacyMeta(legacy, isFile)
+}
+
+// legacyMeta parses the legacy string. Only file secrets used the
+// name|ext|description format, for the other types it is a description.
+func legacyMeta(legacy string, isFile bool) SecretMeta {
+	if !isFile {
+		return SecretMeta{Description: legacy}
+	}
+	meta, _ := ParseLegacyMetadata(legacy)
+	return meta
+}
+
+// MetadataFromRequest returns the typed metadata of the request and the
+// legacy string stored next to it. Requests of older clients only carry the
+// legacy string, which is parsed.
+func MetadataFromRequest(req SecretRequest) (SecretMeta, string) {
+	if req.Info != nil {
+		legacy := req.Meta
+		if legacy == "" {
+			legacy = req.Info.LegacyMetadata()
+		}
+		return *req.Info, legacy
+	}
+	if req.Meta == "" {
+		return SecretMeta{}, ""
+	}
+	return legacyMeta(req.Meta, req.Type == "ByteSlice"), req.Meta
+}
@@ -0,0 +1,61 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// Limits of one-time shares.
+const (
+	MaxOneTimeViews    = 10
+	MaxOneTimeLifetime = 30 * 24 * time.Hour
+	MaxOneTimeSize     = 1 << 20
+)
+
+// OneTimeShare holds a value encrypted on the client for someone without an
+// account. The key is only part of the link handed out, never of a request,
+// so the server cannot decrypt the value. The share is deleted once it has
+// been opened ViewsLeft times or when it expires.
+type OneTimeShare struct {
+	ID         uint   `gorm:"primary_key"`
+	Token      string `gorm:"unique_index"`
+	UserID     uint   `gorm:"index"`
+	Ciphertext []byte
+	ViewsLeft  int
+	ExpiresAt  time.Time `sql:"index"`
+	CreatedAt  time.Time
+}
+
+// OneTimeShareRequest creates a one-time share of the ciphertext.
+type OneTimeShareRequest struct {
+	Ciphertext []byte    `json:"ciphertext"`
+	MaxViews   int       `json:"max_views"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// Validate checks the request against the limits of one-time shares.
+func (r *OneTimeShareRequest) Validate(now time.Time) error {
+	if len(r.Ciphertext) == 0 || len(r.Ciphertext) > MaxOneTimeSize {
+		return fmt.Errorf("ciphertext must have between 1 and %d bytes", MaxOneTimeSize)
+	}
+	if r.MaxViews < 1 || r.MaxViews > MaxOneTimeViews {
+		return fmt.Errorf("max views must be between 1 and %d", MaxOneTimeViews)
+	}
+	if !r.ExpiresAt.After(now) || r.ExpiresAt.After(now.Add(MaxOneTimeLifetime)) {
+		return fmt.Errorf("expiry must be in the future and at most %d days away", int(MaxOneTimeLifetime.Hours()/24))
+	}
+	return nil
+}
+
+// OneTimeShareCreated returns the token addressing a new one-time share.
+type OneTimeShareCreated struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// OneTimeShareOpened returns the ciphertext of an opened one-time share and
+// how often it can still be opened.
+type OneTimeShareOpened struct {
+	Ciphertext []byte `json:"ciphertext"`
+	ViewsLeft  int    `json:"views_left"`
+}
@@ -0,0 +1,100 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Sort orders accepted by the paginated secret listing.
+const (
+	SortName    = "name"
+	SortCreated = "created"
+	SortUpdated = "updated"
+	SortType    = "type"
+	// SortAccessed orders by the last access, secrets which were never read
+	// come first. SortAccesses orders by the number of accesses.
+	SortAccessed = "accessed"
+	SortAccesses = "accesses"
+)
+
+// SecretPage is one page of a paginated secret listing. NextCursor is empty
+// on the last page.
+type SecretPage struct {
+	Items      []Secret `json:"items"`
+	NextCursor string   `json:"next_cursor,omitempty"`
+}
+
+// PageCursor is the position after the last secret of a page: the value of
+// the sort key and the ID, which breaks ties between equal keys.
+type PageCursor struct {
+	Sort  string     `json:"s"`
+	Key   string     `json:"k,omitempty"`
+	Time  *time.Time `json:"t,omitempty"`
+	Count int        `json:"c,omitempty"`
+	ID    uint       `json:"i"`
+}
+
+// ValidSort reports whether sort is one of the supported sort orders.
+func ValidSort(sort string) bool {
+	switch sort {
+	case SortName, SortCreated, SortUpdated, SortType, SortAccessed, SortAccesses:
+		return true
+	}
+	return false
+}
+
+// CursorAfter returns the cursor pointing behind the secret in the sort order.
+func CursorAfter(s Secret, sort string) PageCursor {
+	c := PageCursor{Sort: sort, ID: s.ID}
+	switch sort {
+	case SortName:
+		c.Key = strings.ToLower(s.Meta.Label())
+	case SortType:
+		c.Key = s.SecretType
+	case SortCreated:
+		t := s.CreatedAt
+		c.Time = &t
+	case SortUpdated:
+		t := s.UpdatedAt
+		c.Time = &t
+	case SortAccessed:
+		// Never accessed secrets sort as the zero time.
+		var t time.Time
+		if s.LastAccessedAt != nil {
+			t = *s.LastAccessedAt
+		}
+		c.Time = &t
+	case SortAccesses:
+		c.Count = s.AccessCount
+	}
+	return c
+}
+
+// Encode returns the opaque string form of the cursor.
+func (c PageCursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor parses a cursor for the given sort order. Cursors of another
+// sort order are rejected, since they do not describe a position in it.
+func DecodeCursor(cursor, sort string) (PageCursor, error) {
+	var c PageCursor
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return c, fmt.Errorf("invalid cursor")
+	}
+	if err := json.Unmarshal(data, &c); err != nil {
+		return c, fmt.Errorf("invalid cursor")
+	}
+	if c.Sort != sort {
+		return c, fmt.Errorf("cursor does not match sort order %s", sort)
+	}
+	if (sort == SortCreated || sort == SortUpdated || sort == SortAccessed) && c.Time == nil {
+		return c, fmt.Errorf("invalid cursor")
+	}
+	return c, nil
+}
@@ -0,0 +1,47 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var pathSegmentRegexp = regexp.MustCompile(`^[A-Za-z0-9_@][A-Za-z0-9_.@-]*$`)
+
+// NormalizePath validates a slash separated secret path such as
+// prod/payments/db-password and strips leading and trailing slashes. Plain
+// numbers are rejected, so that a path can never be mistaken for an ID.
+func NormalizePath(path string) (string, error) {
+	path = strings.Trim(strings.TrimSpace(path), "/")
+	if path == "" {
+		return "", fmt.Errorf("path is empty")
+	}
+	if len(path) > 255 {
+		return "", fmt.Errorf("path is too long")
+	}
+	if _, err := strconv.ParseUint(path, 10, 64); err == nil {
+		return "", fmt.Errorf("path must not be a number")
+	}
+	for _, segment := range strings.Split(path, "/") {
+		if !pathSegmentRegexp.MatchString(segment) {
+			return "", fmt.Errorf("invalid path segment %q", segment)
+		}
+	}
+	return path, nil
+}
+
+// NormalizePrefix turns a directory such as "prod/" or "/prod" into the
+// "prod/" form used to match paths below it. An empty prefix matches all.
+func NormalizePrefix(prefix string) (string, error) {
+	prefix = strings.Trim(strings.TrimSpace(prefix), "/")
+	if prefix == "" {
+		return "", nil
+	}
+	for _, segment := range strings.Split(prefix, "/") {
+		if !pathSegmentRegexp.MatchString(segment) {
+			return "", fmt.Errorf("invalid path segment %q", segment)
+		}
+	}
+	return prefix + "/", nil
+}
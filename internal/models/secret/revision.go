@@ -0,0 +1,75 @@
+package models
+
+import "time"
+
+// Limits of the number of changes returned at a time by the change feed.
+const (
+	DefaultChangeLimit = 500
+	MaxChangeLimit     = 1000
+)
+
+// ChangeRevision is the last revision handed out for the secrets of all
+// accounts, kept in a single row. Every write to a secret takes the next
+// revision, so revisions only grow and one revision covers the personal
+// secrets, the vaults and the shares of an account alike.
+type ChangeRevision struct {
+	ID       uint `gorm:"primary_key;auto_increment:false"`
+	Revision uint64
+}
+
+// Tombstone records that the secret left the vault VaultID, or the personal
+// secrets of the account UserID for a VaultID of zero, at Revision, because
+// it was purged or moved. Tombstones of UserID alone also record that the
+// account lost access, e.g. because a share was revoked.
+type Tombstone struct {
+	ID        uint `gorm:"primary_key"`
+	SecretID  uint
+	UserID    uint
+	VaultID   uint `gorm:"not null;default:0"`
+	Revision  uint64
+	RemovedAt time.Time
+}
+
+// Change is an entry of the change feed. Secret holds the secret as of
+// Revision, with its value and tags. Deleted is set instead when the secret
+// was purged, moved to the trash, expired, moved elsewhere or is no longer
+// readable by the caller.
+type Change struct {
+	Revision uint64
+	SecretID uint
+	Deleted  bool
+	Secret   *Secret `json:",omitempty"`
+}
+
+// ChangeFeed is a page of the changes after a revision, in revision order.
+// Revision is the revision to ask for changes after next time; while More is
+// set, further changes follow right away.
+type ChangeFeed struct {
+	Revision uint64
+	Changes  []Change
+	More     bool
+}
+
+// MergeChanges merges the changed secrets and the tombstones, both in
+// revision order, into the first limit changes. Secrets which are not served
+// at now are deleted. It reports whether changes were left out.
+func MergeChanges(secrets []Secret, tombstones []Tombstone, limit int, now time.Time) ([]Change, bool) {
+	changes := make([]Change, 0, limit)
+	i, j := 0, 0
+	for len(changes) < limit && (i < len(secrets) || j < len(tombstones)) {
+		if j == len(tombstones) || (i < len(secrets) && secrets[i].Revision < tombstones[j].Revision) {
+			s := secrets[i]
+			i++
+			if s.DeletedAt != nil || s.ArchivedAt != nil || (s.ExpiresAt != nil && !s.ExpiresAt.After(now)) {
+				changes = append(changes, Change{Revision: s.Revision, SecretID: s.ID, Deleted: true})
+				continue
+			}
+			changes = append(changes, Change{Revision: s.Revision, SecretID: s.ID, Secret: &s})
+			continue
+		}
+		t := tombstones[j]
+		j++
+		changes = append(changes, Change{Revision: t.Revision, SecretID: t.SecretID, Deleted: true})
+	}
+	return changes, i < len(secrets) || j < len(tombstones)
+}
@@ -0,0 +1,51 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SearchTerm is a single word of a search query. Terms match case-insensitively
+// anywhere in a field, or only at its start when Prefix is set.
+type SearchTerm struct {
+	Text   string
+	Prefix bool
+}
+
+// SearchQuery is a parsed search. A secret matches if every term is found in
+// its metadata, path, tags or type, and its type is one of Types (if any).
+type SearchQuery struct {
+	Terms []SearchTerm
+	Types []string
+	// VaultID searches the vault instead of the personal secrets. It is set
+	// by the caller rather than parsed from the query.
+	VaultID uint
+}
+
+// ParseSearchQuery splits the query on whitespace. A trailing * turns a term
+// into a prefix match and type:<type> restricts the secret type, e.g.
+// "db* type:KeyValue prod".
+func ParseSearchQuery(query string) (SearchQuery, error) {
+	var q SearchQuery
+	for _, word := range strings.Fields(query) {
+		word = strings.ToLower(word)
+		if strings.HasPrefix(word, "type:") {
+			t := strings.TrimPrefix(word, "type:")
+			if t == "" {
+				return q, fmt.Errorf("type filter is empty")
+			}
+			q.Types = append(q.Types, t)
+			continue
+		}
+		term := SearchTerm{Text: strings.TrimSuffix(word, "*")}
+		term.Prefix = term.Text != word
+		if term.Text == "" {
+			return q, fmt.Errorf("search term %q is empty", word)
+		}
+		q.Terms = append(q.Terms, term)
+	}
+	if len(q.Terms) == 0 && len(q.Types) == 0 {
+		return q, fmt.Errorf("search query is empty")
+	}
+	return q, nil
+}
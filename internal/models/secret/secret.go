@@ -4,7 +4,8 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
-	"regexp"
+	"strings"
+	"time"
 )
 
 type SecretRequest struct {
@@ -13,6 +14,15 @@ type SecretRequest struct {
 	Data     json.RawMessage `json:"data"`
 	ByteData string          `json:"byteData,omitempty"` // New field for base64 encoded []byte
 	Meta     string          `json:"meta,omitempty"`
+	Path     string          `json:"path,omitempty"`
+	Info     *SecretMeta     `json:"info,omitempty"`
+	// ExpiresAt sets the expiry date, NoExpiry removes it. Without either an
+	// update keeps the expiry date of the secret.
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	NoExpiry  bool       `json:"no_expiry,omitempty"`
+	// VaultID creates the secret in a vault of an organization instead of
+	// the personal secrets of the caller. It is ignored on updates.
+	VaultID uint `json:"vault_id,omitempty"`
 }
 
 type Secret struct {
@@ -21,12 +31,42 @@ type Secret struct {
 	Value      ByteSlice
 	SecretType string
 	Metadata   string
+	Meta       SecretMeta `gorm:"embedded"`
+	Path       string
+	Tags       []Tag `gorm:"many2many:secret_tags" json:",omitempty"`
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	// DeletedAt is set while the secret is in the trash.
+	DeletedAt *time.Time `sql:"index" json:",omitempty"`
+	// ExpiresAt is the optional expiry date. Expired secrets are no longer
+	// served and are moved to the archive, which sets ArchivedAt.
+	ExpiresAt  *time.Time `sql:"index" json:",omitempty"`
+	ArchivedAt *time.Time `json:",omitempty"`
+	// LastAccessedAt and AccessCount are maintained by the server whenever
+	// the value of the secret is read.
+	LastAccessedAt *time.Time `json:",omitempty"`
+	AccessCount    int        `gorm:"not null;default:0"`
+	// VaultID is the vault of an organization the secret belongs to, zero for
+	// personal secrets. UserID is the creator of vault secrets.
+	VaultID uint `gorm:"not null;default:0" sql:"index" json:",omitempty"`
+	// Revision is the revision at the last change of the secret, set by
+	// the database, see ChangeRevision.
+	Revision uint64 `gorm:"not null;default:0" json:",omitempty"`
 }
 type DecodedSecret struct {
-	ID       uint
-	UserID   uint
-	Value    interface{}
-	Metadata string
+	ID        uint
+	UserID    uint
+	Value     interface{}
+	Metadata  string
+	Meta      SecretMeta
+	Path      string
+	Tags      []string
+	ExpiresAt *time.Time
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	// LastAccessedAt is nil for secrets which have never been read.
+	LastAccessedAt *time.Time
+	AccessCount    int
 }
 
 func NewSecret(userID uint, secretType string, value ByteConvertible, meta string) (Secret, error) {
@@ -49,6 +89,14 @@ func GetSecretFromRequest(req SecretRequest, user uint) (ByteConvertible, error)
 		value = &CreditCard{}
 	case "ByteSlice":
 		value = &ByteSlice{}
+	case "Certificate":
+		value = &Certificate{}
+	case "BankAccount":
+		value = &BankAccount{}
+	case "Identity":
+		value = &Identity{}
+	case "EnvBundle":
+		value = &EnvBundle{}
 
 	default:
 		return nil, fmt.Errorf("invalid type: %s", req.Type)
@@ -83,6 +131,14 @@ func GetDecodedSecrets(secrets []Secret) ([]DecodedSecret, error) {
 			value = new(CreditCard)
 		case "ByteSlice":
 			value = new(ByteSlice)
+		case "Certificate":
+			value = new(Certificate)
+		case "BankAccount":
+			value = new(BankAccount)
+		case "Identity":
+			value = new(Identity)
+		case "EnvBundle":
+			value = new(EnvBundle)
 		default:
 			return nil, fmt.Errorf("unknown secret type: %s", secret.SecretType)
 		}
@@ -92,10 +148,18 @@ func GetDecodedSecrets(secrets []Secret) ([]DecodedSecret, error) {
 			return nil, fmt.Errorf("cannot decode secret %d of type %s: %w", secret.ID, secret.SecretType, err)
 		}
 		decodedSecrets[i] = DecodedSecret{
-			ID:       secret.ID,
-			UserID:   secret.UserID,
-			Value:    value,
-			Metadata: secret.Metadata,
+			ID:             secret.ID,
+			UserID:         secret.UserID,
+			Value:          value,
+			Metadata:       secret.Metadata,
+			Meta:           secret.Meta,
+			Path:           secret.Path,
+			Tags:           TagNames(secret.Tags),
+			ExpiresAt:      secret.ExpiresAt,
+			CreatedAt:      secret.CreatedAt,
+			UpdatedAt:      secret.UpdatedAt,
+			LastAccessedAt: secret.LastAccessedAt,
+			AccessCount:    secret.AccessCount,
 		}
 	}
 	return decodedSecrets, nil
@@ -109,18 +173,48 @@ func (ds *DecodedSecret) ValueToString() string {
 		return v.Value
 	case *CreditCard:
 		return fmt.Sprintf("Number: %s,\n Expiration: %s,\n CVV: %s,\n Cardholder: %s", v.Number, v.Expiration, v.CVV, v.Cardholder)
+	case *Certificate:
+		return fmt.Sprintf("Subject: %s,\n SANs: %s,\n Issuer: %s,\n Serial: %s,\n Not after: %s", v.Subject, strings.Join(v.SANs, ", "), v.Issuer, v.Serial, v.NotAfter.Format(time.RFC3339))
+	case *BankAccount:
+		return fmt.Sprintf("Holder: %s,\n Bank: %s,\n IBAN: %s,\n BIC: %s,\n Account number: %s,\n Routing number: %s", v.Holder, v.BankName, MaskDigits(v.IBAN), v.BIC, MaskDigits(v.AccountNumber), v.RoutingNumber)
+	case *Identity:
+		return fmt.Sprintf("Document: %s,\n Number: %s,\n Country: %s,\n Name: %s,\n Issued: %s,\n Expires: %s", v.DocumentType, v.Number, v.Country, v.NameAsPrinted, v.IssueDate, v.ExpiryDate)
+	case *EnvBundle:
+		lines := make([]string, len(v.Vars))
+		for i, env := range v.Vars {
+			lines[i] = env.Key + "=********"
+		}
+		return strings.Join(lines, "\n")
 	case *ByteSlice:
-		re := regexp.MustCompile(`^([^|]+)\|([^|]+)\|(.+)$`)
-		matches := re.FindStringSubmatch(ds.Metadata)
-		if len(matches) != 4 {
+		meta := ds.Meta
+		if meta.IsZero() {
+			var ok bool
+			if meta, ok = ParseLegacyMetadata(ds.Metadata); !ok {
+				return "*Binary data*"
+			}
+		}
+		if meta.Description == "" {
 			return "*Binary data*"
 		}
-		return matches[3]
+		return meta.Description
 	default:
 		return "Unknown Value Type"
 	}
 }
 
+// RevealedValueToString works like ValueToString but does not mask sensitive
+// fields of the value.
+func (ds *DecodedSecret) RevealedValueToString() string {
+	switch v := ds.Value.(type) {
+	case *BankAccount:
+		return fmt.Sprintf("Holder: %s,\n Bank: %s,\n IBAN: %s,\n BIC: %s,\n Account number: %s,\n Routing number: %s", v.Holder, v.BankName, v.IBAN, v.BIC, v.AccountNumber, v.RoutingNumber)
+	case *EnvBundle:
+		return strings.TrimSuffix(v.Dotenv(), "\n")
+	default:
+		return ds.ValueToString()
+	}
+}
+
 type ByteConvertible interface {
 	ToBytes() (ByteSlice, error)
 	FromBytes(ByteSlice) error
@@ -1,10 +1,20 @@
 package models
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
 	"encoding/json"
+	"encoding/pem"
 	"fmt"
+	"math/big"
 	"reflect"
 	"testing"
+	"time"
+
+	"github.com/jinzhu/gorm"
 )
 
 type testByteConvertible struct {
@@ -117,6 +127,29 @@ func TestGetDecodedSecrets(t *testing.T) {
 			},
 			expectedErr: nil,
 		},
+		{
+			name: "secret with tags",
+			secrets: []Secret{
+				{
+					ID:         uint(2),
+					UserID:     uint(1),
+					Value:      ByteSlice(`{"key":"mykey","value":"myvalue"}`),
+					SecretType: "KeyValue",
+					Metadata:   "test",
+					Tags:       []Tag{{ID: 1, Name: "prod"}, {ID: 2, Name: "team-payments"}},
+				},
+			},
+			expectedDecoded: []DecodedSecret{
+				{
+					ID:       uint(2),
+					UserID:   uint(1),
+					Value:    &KeyValue{Key: "mykey", Value: "myvalue"},
+					Metadata: "test",
+					Tags:     []string{"prod", "team-payments"},
+				},
+			},
+			expectedErr: nil,
+		},
 		{
 			name: "valid Text secret",
 			secrets: []Secret{
@@ -237,6 +270,18 @@ func TestValueToString(t *testing.T) {
 			}(),
 			expectedOutput: "*Binary data*",
 		},
+		{
+			name: "ByteSlice with typed metadata",
+			secret: func() DecodedSecret {
+				b := ByteSlice([]byte("Hello"))
+				return DecodedSecret{
+					Value:    &b,
+					Metadata: "myFile|txt|old description",
+					Meta:     SecretMeta{Description: "notes | drafts", File: FileInfo{Filename: "notes"}},
+				}
+			}(),
+			expectedOutput: "notes | drafts",
+		},
 		{
 			name: "Unknown Value Type",
 			secret: DecodedSecret{
@@ -255,3 +300,628 @@ func TestValueToString(t *testing.T) {
 		})
 	}
 }
+
+func generateTestCertificate(t *testing.T, notAfter time.Time) (string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("cannot generate key: %v", err)
+	}
+	template := x509.Certificate{
+		SerialNumber: big.NewInt(42),
+		Subject:      pkix.Name{CommonName: "example.com"},
+		DNSNames:     []string{"example.com", "www.example.com"},
+		NotBefore:    notAfter.Add(-24 * time.Hour),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("cannot create certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("cannot marshal key: %v", err)
+	}
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER})
+	return string(certPEM), string(keyPEM)
+}
+
+func TestCertificateValidate(t *testing.T) {
+	notAfter := time.Now().Add(48 * time.Hour).UTC().Truncate(time.Second)
+	chain, key := generateTestCertificate(t, notAfter)
+	_, otherKey := generateTestCertificate(t, notAfter)
+
+	testCases := []struct {
+		name      string
+		cert      Certificate
+		expectErr bool
+	}{
+		{
+			name: "chain with matching key",
+			cert: Certificate{Chain: chain, PrivateKey: key},
+		},
+		{
+			name: "chain without key",
+			cert: Certificate{Chain: chain},
+		},
+		{
+			name:      "key of another certificate",
+			cert:      Certificate{Chain: chain, PrivateKey: otherKey},
+			expectErr: true,
+		},
+		{
+			name:      "not a PEM chain",
+			cert:      Certificate{Chain: "garbage"},
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.cert.Validate()
+			if (err != nil) != tc.expectErr {
+				t.Fatalf("Validate() error = %v, expectErr %v", err, tc.expectErr)
+			}
+			if tc.expectErr {
+				return
+			}
+			if tc.cert.Subject != "CN=example.com" {
+				t.Errorf("Expected subject CN=example.com, got %s", tc.cert.Subject)
+			}
+			if !reflect.DeepEqual(tc.cert.SANs, []string{"example.com", "www.example.com"}) {
+				t.Errorf("Unexpected SANs %v", tc.cert.SANs)
+			}
+			if tc.cert.Serial != "2a" {
+				t.Errorf("Expected serial 2a, got %s", tc.cert.Serial)
+			}
+			if !tc.cert.NotAfter.Equal(notAfter) {
+				t.Errorf("Expected NotAfter %v, got %v", notAfter, tc.cert.NotAfter)
+			}
+		})
+	}
+}
+
+func TestValidateIBAN(t *testing.T) {
+	testCases := []struct {
+		iban      string
+		expectErr bool
+	}{
+		{iban: "GB82 WEST 1234 5698 7654 32"},
+		{iban: "DE89370400440532013000"},
+		{iban: "de89370400440532013000"},
+		{iban: "DE89370400440532013001", expectErr: true},
+		{iban: "D189370400440532013000", expectErr: true},
+		{iban: "DE8937040044", expectErr: true},
+		{iban: "DE89-3704-0044-0532-0130-00", expectErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.iban, func(t *testing.T) {
+			err := ValidateIBAN(tc.iban)
+			if (err != nil) != tc.expectErr {
+				t.Errorf("ValidateIBAN(%q) error = %v, expectErr %v", tc.iban, err, tc.expectErr)
+			}
+		})
+	}
+}
+
+func TestValidateBIC(t *testing.T) {
+	testCases := []struct {
+		bic       string
+		expectErr bool
+	}{
+		{bic: "DEUTDEFF"},
+		{bic: "DEUTDEFF500"},
+		{bic: "deutdeff"},
+		{bic: "DEUTDEF", expectErr: true},
+		{bic: "DEU1DEFF", expectErr: true},
+		{bic: "DEUTDEFF50", expectErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.bic, func(t *testing.T) {
+			err := ValidateBIC(tc.bic)
+			if (err != nil) != tc.expectErr {
+				t.Errorf("ValidateBIC(%q) error = %v, expectErr %v", tc.bic, err, tc.expectErr)
+			}
+		})
+	}
+}
+
+func TestBankAccountValueToString(t *testing.T) {
+	secret := DecodedSecret{
+		Value: &BankAccount{Holder: "John Doe", BankName: "Bank", IBAN: "DE89370400440532013000", BIC: "DEUTDEFF", AccountNumber: "0532013000", RoutingNumber: "37040044"},
+	}
+
+	masked := "Holder: John Doe,\n Bank: Bank,\n IBAN: ******************3000,\n BIC: DEUTDEFF,\n Account number: ******3000,\n Routing number: 37040044"
+	if out := secret.ValueToString(); out != masked {
+		t.Errorf("Expected output %s, but got %s", masked, out)
+	}
+
+	revealed := "Holder: John Doe,\n Bank: Bank,\n IBAN: DE89370400440532013000,\n BIC: DEUTDEFF,\n Account number: 0532013000,\n Routing number: 37040044"
+	if out := secret.RevealedValueToString(); out != revealed {
+		t.Errorf("Expected output %s, but got %s", revealed, out)
+	}
+}
+
+func TestIdentity(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	testCases := []struct {
+		name            string
+		identity        Identity
+		expectErr       bool
+		expectedWarning string
+	}{
+		{
+			name:     "valid document",
+			identity: Identity{Number: "X123", IssueDate: "2020-01-01", ExpiryDate: "2030-01-01"},
+		},
+		{
+			name:            "expires soon",
+			identity:        Identity{Number: "X123", ExpiryDate: "2026-01-31"},
+			expectedWarning: "expires in 30 days",
+		},
+		{
+			name:            "expired",
+			identity:        Identity{Number: "X123", ExpiryDate: "2025-12-31"},
+			expectedWarning: "EXPIRED",
+		},
+		{
+			name:      "missing number",
+			identity:  Identity{ExpiryDate: "2030-01-01"},
+			expectErr: true,
+		},
+		{
+			name:      "malformed date",
+			identity:  Identity{Number: "X123", ExpiryDate: "01/01/2030"},
+			expectErr: true,
+		},
+		{
+			name:      "expires before issued",
+			identity:  Identity{Number: "X123", IssueDate: "2030-01-01", ExpiryDate: "2020-01-01"},
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.identity.Validate()
+			if (err != nil) != tc.expectErr {
+				t.Fatalf("Validate() error = %v, expectErr %v", err, tc.expectErr)
+			}
+			if tc.expectErr {
+				return
+			}
+			if warning := tc.identity.ExpiryWarning(now); warning != tc.expectedWarning {
+				t.Errorf("Expected warning %q, got %q", tc.expectedWarning, warning)
+			}
+		})
+	}
+}
+
+func TestNewAttachment(t *testing.T) {
+	attachment, err := NewAttachment(1, "/tmp/docs/contract.pdf", "", []byte("Hello"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if attachment.Filename != "contract.pdf" {
+		t.Errorf("Expected filename contract.pdf, got %s", attachment.Filename)
+	}
+	if attachment.MimeType != "application/octet-stream" {
+		t.Errorf("Expected default MIME type, got %s", attachment.MimeType)
+	}
+	if attachment.Size != 5 {
+		t.Errorf("Expected size 5, got %d", attachment.Size)
+	}
+	if attachment.Checksum != "185f8db32271fe25f561a6fc938b2e264306ec304eda518007d1764826381969" {
+		t.Errorf("Unexpected checksum %s", attachment.Checksum)
+	}
+	if err := attachment.Verify(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	attachment.Data = ByteSlice("tampered")
+	if err := attachment.Verify(); err == nil {
+		t.Errorf("Expected checksum mismatch, got nil")
+	}
+
+	if _, err := NewAttachment(1, "", "", nil); err == nil {
+		t.Errorf("Expected error for empty filename, got nil")
+	}
+}
+
+func TestParseDotenv(t *testing.T) {
+	testCases := []struct {
+		name           string
+		data           string
+		expectedBundle *EnvBundle
+		expectErr      bool
+	}{
+		{
+			name: "plain, quoted and hidden values",
+			data: "# comment\nexport DB_HOST=localhost\nDB_PASSWORD='p@ss word'\n" + HiddenMarker + "\nAPI_TOKEN=\"line1\\nline2\" # inline\nEMPTY=\nPORT=5432 # inline comment\n",
+			expectedBundle: &EnvBundle{Vars: []EnvVar{
+				{Key: "DB_HOST", Value: "localhost"},
+				{Key: "DB_PASSWORD", Value: "p@ss word"},
+				{Key: "API_TOKEN", Value: "line1\nline2", Hidden: true},
+				{Key: "EMPTY", Value: ""},
+				{Key: "PORT", Value: "5432"},
+			}},
+		},
+		{
+			name: "duplicate keys keep the first position",
+			data: "A=1\nB=2\nA=3\n",
+			expectedBundle: &EnvBundle{Vars: []EnvVar{
+				{Key: "A", Value: "3"},
+				{Key: "B", Value: "2"},
+			}},
+		},
+		{
+			name:      "missing equals sign",
+			data:      "JUSTAKEY\n",
+			expectErr: true,
+		},
+		{
+			name:      "invalid name",
+			data:      "1KEY=value\n",
+			expectErr: true,
+		},
+		{
+			name:      "unterminated quote",
+			data:      "KEY=\"value\n",
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			bundle, err := ParseDotenv(tc.data)
+			if (err != nil) != tc.expectErr {
+				t.Fatalf("ParseDotenv() error = %v, expectErr %v", err, tc.expectErr)
+			}
+			if !reflect.DeepEqual(tc.expectedBundle, bundle) {
+				t.Errorf("Expected bundle %+v, but got %+v", tc.expectedBundle, bundle)
+			}
+		})
+	}
+}
+
+func TestDotenvRoundTrip(t *testing.T) {
+	bundle := &EnvBundle{Vars: []EnvVar{
+		{Key: "SIMPLE", Value: "value"},
+		{Key: "SPACES", Value: "a b c"},
+		{Key: "QUOTES", Value: `say "hi" \ bye`, Hidden: true},
+		{Key: "MULTILINE", Value: "one\ntwo"},
+		{Key: "EMPTY", Value: ""},
+	}}
+
+	parsed, err := ParseDotenv(bundle.Dotenv())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(bundle, parsed) {
+		t.Errorf("Expected bundle %+v, but got %+v", bundle, parsed)
+	}
+
+	decoded := DecodedSecret{Value: bundle}
+	expected := "SIMPLE=********\nSPACES=********\nQUOTES=********\nMULTILINE=********\nEMPTY=********"
+	if out := decoded.ValueToString(); out != expected {
+		t.Errorf("Expected output %s, but got %s", expected, out)
+	}
+}
+
+func TestNormalizeTag(t *testing.T) {
+	testCases := []struct {
+		tag       string
+		expected  string
+		expectErr bool
+	}{
+		{tag: "prod", expected: "prod"},
+		{tag: " Team-Payments ", expected: "team-payments"},
+		{tag: "env:staging", expected: "env:staging"},
+		{tag: "", expectErr: true},
+		{tag: "-staging", expectErr: true},
+		{tag: "prod/eu", expectErr: true},
+		{tag: "two words", expectErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.tag, func(t *testing.T) {
+			actual, err := NormalizeTag(tc.tag)
+			if (err != nil) != tc.expectErr {
+				t.Fatalf("NormalizeTag(%q) error = %v, expectErr %v", tc.tag, err, tc.expectErr)
+			}
+			if actual != tc.expected {
+				t.Errorf("Expected tag %q, but got %q", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestNormalizePath(t *testing.T) {
+	testCases := []struct {
+		path      string
+		expected  string
+		expectErr bool
+	}{
+		{path: "prod/payments/db-password", expected: "prod/payments/db-password"},
+		{path: "/prod/db/", expected: "prod/db"},
+		{path: "team@corp/api_key.v2", expected: "team@corp/api_key.v2"},
+		{path: "", expectErr: true},
+		{path: "42", expectErr: true},
+		{path: "prod//db", expectErr: true},
+		{path: "prod/../db", expectErr: true},
+		{path: "prod/db password", expectErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.path, func(t *testing.T) {
+			actual, err := NormalizePath(tc.path)
+			if (err != nil) != tc.expectErr {
+				t.Fatalf("NormalizePath(%q) error = %v, expectErr %v", tc.path, err, tc.expectErr)
+			}
+			if actual != tc.expected {
+				t.Errorf("Expected path %q, but got %q", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestParseSearchQuery(t *testing.T) {
+	testCases := []struct {
+		query     string
+		expected  SearchQuery
+		expectErr bool
+	}{
+		{query: "Payments", expected: SearchQuery{Terms: []SearchTerm{{Text: "payments"}}}},
+		{query: "db* prod", expected: SearchQuery{Terms: []SearchTerm{{Text: "db", Prefix: true}, {Text: "prod"}}}},
+		{query: "type:KeyValue", expected: SearchQuery{Types: []string{"keyvalue"}}},
+		{query: "  ", expectErr: true},
+		{query: "*", expectErr: true},
+		{query: "type:", expectErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.query, func(t *testing.T) {
+			actual, err := ParseSearchQuery(tc.query)
+			if (err != nil) != tc.expectErr {
+				t.Fatalf("ParseSearchQuery(%q) error = %v, expectErr %v", tc.query, err, tc.expectErr)
+			}
+			if tc.expectErr {
+				return
+			}
+			if !reflect.DeepEqual(actual, tc.expected) {
+				t.Errorf("Expected %+v, but got %+v", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestParseLegacyMetadata(t *testing.T) {
+	testCases := []struct {
+		meta     string
+		expected SecretMeta
+		ok       bool
+	}{
+		{
+			meta:     "myFile|txt|This is a text file",
+			expected: SecretMeta{Name: "myFile", Description: "This is a text file", File: FileInfo{Filename: "myFile.txt"}},
+			ok:       true,
+		},
+		{
+			meta:     "Makefile||build | test targets",
+			expected: SecretMeta{Name: "Makefile", Description: "build | test targets", File: FileInfo{Filename: "Makefile"}},
+			ok:       true,
+		},
+		{meta: "gmail password", expected: SecretMeta{Description: "gmail password"}},
+		{meta: "|txt|no name", expected: SecretMeta{Description: "|txt|no name"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.meta, func(t *testing.T) {
+			actual, ok := ParseLegacyMetadata(tc.meta)
+			if ok != tc.ok || actual != tc.expected {
+				t.Errorf("ParseLegacyMetadata(%q) = %+v, %v, expected %+v, %v", tc.meta, actual, ok, tc.expected, tc.ok)
+			}
+			if ok {
+				if back, _ := ParseLegacyMetadata(actual.LegacyMetadata()); back != actual {
+					t.Errorf("Expected %+v after round trip, but got %+v", actual, back)
+				}
+			}
+		})
+	}
+}
+
+func TestMetadataFromRequest(t *testing.T) {
+	info := &SecretMeta{Name: "report", Description: "Q3 | final", File: FileInfo{Filename: "report.pdf", Size: 10}}
+	testCases := []struct {
+		name           string
+		req            SecretRequest
+		expectedMeta   SecretMeta
+		expectedLegacy string
+	}{
+		{
+			name:           "structured metadata",
+			req:            SecretRequest{Type: "ByteSlice", Info: info},
+			expectedMeta:   *info,
+			expectedLegacy: "report|pdf|Q3 | final",
+		},
+		{
+			name:           "legacy file metadata",
+			req:            SecretRequest{Type: "ByteSlice", Meta: "report|pdf|Q3"},
+			expectedMeta:   SecretMeta{Name: "report", Description: "Q3", File: FileInfo{Filename: "report.pdf"}},
+			expectedLegacy: "report|pdf|Q3",
+		},
+		{
+			name:           "pipes in the metadata of other types",
+			req:            SecretRequest{Type: "Text", Meta: "a|b|c"},
+			expectedMeta:   SecretMeta{Description: "a|b|c"},
+			expectedLegacy: "a|b|c",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			meta, legacy := MetadataFromRequest(tc.req)
+			if meta != tc.expectedMeta || legacy != tc.expectedLegacy {
+				t.Errorf("Expected %+v, %q, but got %+v, %q", tc.expectedMeta, tc.expectedLegacy, meta, legacy)
+			}
+		})
+	}
+}
+
+func TestPageCursor(t *testing.T) {
+	created := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	s := Secret{ID: 42, SecretType: "Text", Meta: SecretMeta{Description: "Gmail"}, CreatedAt: created}
+
+	byName := CursorAfter(s, SortName)
+	decoded, err := DecodeCursor(byName.Encode(), SortName)
+	if err != nil {
+		t.Fatalf("DecodeCursor() error = %v", err)
+	}
+	if decoded.Key != "gmail" || decoded.ID != 42 {
+		t.Errorf("Expected cursor after gmail/42, but got %+v", decoded)
+	}
+
+	// Secrets without a name, filename or description sort as the empty
+	// name, their columns may be NULL.
+	unnamed := CursorAfter(Secret{ID: 7, SecretType: "Text"}, SortName)
+	if unnamed.Key != "" || unnamed.ID != 7 {
+		t.Errorf("Expected cursor after the empty name/7, but got %+v", unnamed)
+	}
+
+	byCreated := CursorAfter(s, SortCreated)
+	decoded, err = DecodeCursor(byCreated.Encode(), SortCreated)
+	if err != nil {
+		t.Fatalf("DecodeCursor() error = %v", err)
+	}
+	if decoded.Time == nil || !decoded.Time.Equal(created) {
+		t.Errorf("Expected cursor at %v, but got %+v", created, decoded)
+	}
+
+	if _, err := DecodeCursor(byName.Encode(), SortCreated); err == nil {
+		t.Errorf("Expected error for a cursor of another sort order")
+	}
+	if _, err := DecodeCursor("not a cursor", SortName); err == nil {
+		t.Errorf("Expected error for an invalid cursor")
+	}
+}
+
+func TestPageCursorAccess(t *testing.T) {
+	accessed := time.Date(2024, 6, 1, 8, 0, 0, 0, time.UTC)
+	used := Secret{ID: 7, LastAccessedAt: &accessed, AccessCount: 12}
+	unused := Secret{ID: 8}
+
+	decoded, err := DecodeCursor(CursorAfter(used, SortAccessed).Encode(), SortAccessed)
+	if err != nil {
+		t.Fatalf("DecodeCursor() error = %v", err)
+	}
+	if decoded.Time == nil || !decoded.Time.Equal(accessed) || decoded.ID != 7 {
+		t.Errorf("Expected cursor at %v, but got %+v", accessed, decoded)
+	}
+
+	decoded, err = DecodeCursor(CursorAfter(unused, SortAccessed).Encode(), SortAccessed)
+	if err != nil {
+		t.Fatalf("DecodeCursor() error = %v", err)
+	}
+	if decoded.Time == nil || !decoded.Time.IsZero() {
+		t.Errorf("Expected never accessed secrets at the zero time, but got %+v", decoded)
+	}
+
+	decoded, err = DecodeCursor(CursorAfter(used, SortAccesses).Encode(), SortAccesses)
+	if err != nil {
+		t.Fatalf("DecodeCursor() error = %v", err)
+	}
+	if decoded.Count != 12 || decoded.ID != 7 {
+		t.Errorf("Expected cursor after 12 accesses, but got %+v", decoded)
+	}
+}
+
+func TestSecretExpiryWarning(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) *time.Time {
+		t := now.Add(d)
+		return &t
+	}
+	tests := []struct {
+		expiresAt *time.Time
+		want      string
+	}{
+		{expiresAt: nil, want: ""},
+		{expiresAt: at(-time.Hour), want: "EXPIRED"},
+		{expiresAt: at(5 * time.Hour), want: "expires in 5 hours"},
+		{expiresAt: at(3 * 24 * time.Hour), want: "expires in 3 days"},
+		{expiresAt: at(30 * 24 * time.Hour), want: ""},
+	}
+
+	for _, tt := range tests {
+		ds := DecodedSecret{ExpiresAt: tt.expiresAt}
+		if got := ds.ExpiryWarning(now); got != tt.want {
+			t.Errorf("ExpiryWarning() = %q, want %q", got, tt.want)
+		}
+		s := Secret{ExpiresAt: tt.expiresAt}
+		if expired := s.Expired(now); expired != (tt.want == "EXPIRED") {
+			t.Errorf("Expired() = %v for %v", expired, tt.expiresAt)
+		}
+	}
+}
+
+func TestOneTimeShareRequestValidate(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	valid := OneTimeShareRequest{Ciphertext: []byte("sealed"), MaxViews: 1, ExpiresAt: now.Add(time.Hour)}
+	if err := valid.Validate(now); err != nil {
+		t.Errorf("didn't expect error, got %v", err)
+	}
+
+	tests := []OneTimeShareRequest{
+		{MaxViews: 1, ExpiresAt: now.Add(time.Hour)},
+		{Ciphertext: []byte("sealed"), MaxViews: 0, ExpiresAt: now.Add(time.Hour)},
+		{Ciphertext: []byte("sealed"), MaxViews: MaxOneTimeViews + 1, ExpiresAt: now.Add(time.Hour)},
+		{Ciphertext: []byte("sealed"), MaxViews: 1, ExpiresAt: now},
+		{Ciphertext: []byte("sealed"), MaxViews: 1, ExpiresAt: now.Add(MaxOneTimeLifetime + time.Hour)},
+	}
+	for i, tt := range tests {
+		if err := tt.Validate(now); err == nil {
+			t.Errorf("case %d: expected error", i)
+		}
+	}
+}
+
+func TestMergeChanges(t *testing.T) {
+	past := time.Now()
+	secrets := []Secret{
+		{ID: 1, Revision: 2},
+		{ID: 2, Revision: 4, DeletedAt: &past},
+		{ID: 3, Revision: 6, ExpiresAt: &past},
+		{ID: 1, Revision: 7},
+	}
+	tombstones := []Tombstone{{SecretID: 9, Revision: 3}, {SecretID: 8, Revision: 5}}
+
+	changes, more := MergeChanges(secrets[:1], tombstones, 10, past)
+	if more || len(changes) != 3 {
+		t.Fatalf("expected all 3 changes, got %+v, more %v", changes, more)
+	}
+	changes, more = MergeChanges(secrets, tombstones, 5, past)
+	if !more || len(changes) != 5 {
+		t.Fatalf("expected 5 changes and more, got %+v, more %v", changes, more)
+	}
+	want := []struct {
+		revision uint64
+		id       uint
+		deleted  bool
+	}{{2, 1, false}, {3, 9, true}, {4, 2, true}, {5, 8, true}, {6, 3, true}}
+	for i, w := range want {
+		c := changes[i]
+		if c.Revision != w.revision || c.SecretID != w.id || c.Deleted != w.deleted || (c.Secret == nil) != w.deleted {
+			t.Errorf("change %d: expected %+v, got %+v", i, w, c)
+		}
+	}
+}
+
+func TestCertificateInfoPrimaryKey(t *testing.T) {
+	fields := (&gorm.Scope{Value: &CertificateInfo{}}).GetModelStruct().PrimaryFields
+	if len(fields) != 1 || fields[0].DBName != "secret_id" {
+		t.Fatalf("expected secret_id as the primary key, got %+v", fields)
+	}
+}
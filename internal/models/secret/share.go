@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// Share grants the account UserID access to a secret of another account.
+// Read-only shares allow reading the secret and its attachments, read-write
+// shares also allow updating its value and metadata. Everything else stays
+// with the owner.
+type Share struct {
+	ID        uint `gorm:"primary_key"`
+	SecretID  uint `gorm:"unique_index:idx_share_secret_user"`
+	UserID    uint `gorm:"unique_index:idx_share_secret_user;index"`
+	ReadOnly  bool
+	CreatedAt time.Time
+	// Login is the login of the recipient, filled in for listings.
+	Login string `gorm:"-" json:",omitempty"`
+}
+
+// ShareRequest asks to share a secret with the account Login.
+type ShareRequest struct {
+	Login    string `json:"login"`
+	ReadOnly bool   `json:"read_only"`
+}
+
+// SharedSecret is a secret shared with the caller, without its value.
+type SharedSecret struct {
+	Secret   Secret
+	Owner    string
+	ReadOnly bool
+}
@@ -0,0 +1,38 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var tagRegexp = regexp.MustCompile(`^[a-z0-9][a-z0-9_.:-]*$`)
+
+// Tag groups secrets of one user, a secret can carry any number of tags.
+type Tag struct {
+	ID     uint   `gorm:"primary_key"`
+	UserID uint   `gorm:"unique_index:idx_tag_user_name" json:"-"`
+	Name   string `gorm:"unique_index:idx_tag_user_name"`
+}
+
+// NormalizeTag lower-cases the tag name and checks that it only contains
+// letters, digits and the separators _ . : -.
+func NormalizeTag(name string) (string, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if len(name) > 64 || !tagRegexp.MatchString(name) {
+		return "", fmt.Errorf("invalid tag: %q", name)
+	}
+	return name, nil
+}
+
+// TagNames returns the names of the tags or nil if there are none.
+func TagNames(tags []Tag) []string {
+	if len(tags) == 0 {
+		return nil
+	}
+	names := make([]string, len(tags))
+	for i, t := range tags {
+		names[i] = t.Name
+	}
+	return names
+}
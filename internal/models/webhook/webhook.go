@@ -0,0 +1,168 @@
+package models
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Events webhooks subscribe to.
+const (
+	EventCreated = "created"
+	EventUpdated = "updated"
+	EventDeleted = "deleted"
+	EventShared  = "shared"
+	EventExpired = "expired"
+)
+
+// AllEvents lists the events in the order they are shown.
+var AllEvents = []string{EventCreated, EventUpdated, EventDeleted, EventShared, EventExpired}
+
+// MinSecretLength is the shortest signing secret accepted from clients.
+const MinSecretLength = 16
+
+// MaxDeliveryLimit is the largest number of deliveries listed at a time.
+const MaxDeliveryLimit = 100
+
+// States of a delivery.
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed"
+)
+
+// Headers of deliveries. The signature is the hex HMAC-SHA256 of the
+// timestamp header, a dot and the body, keyed with the signing secret of the
+// webhook, see Sign.
+const (
+	HeaderEvent     = "X-PassKeeper-Event"
+	HeaderDelivery  = "X-PassKeeper-Delivery"
+	HeaderTimestamp = "X-PassKeeper-Timestamp"
+	HeaderSignature = "X-PassKeeper-Signature"
+)
+
+// Webhook posts the events of the secrets of UserID, or of the vaults of
+// OrgID if it is not zero, to URL. Secret signs the deliveries and is only
+// shown once, when the webhook is created.
+type Webhook struct {
+	ID     uint `gorm:"primary_key"`
+	UserID uint `gorm:"index"`
+	OrgID  uint `gorm:"index"`
+	URL    string
+	// Events is the comma separated list of events posted.
+	Events    string
+	Secret    string `json:"-"`
+	CreatedAt time.Time
+}
+
+// Subscribes reports whether the webhook posts the event.
+func (w *Webhook) Subscribes(event string) bool {
+	for _, e := range strings.Split(w.Events, ",") {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookRequest registers a webhook, for the vaults of the organization Org
+// if it is set. Without Events the webhook posts all events, without a
+// Secret the server generates one.
+type WebhookRequest struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+	Secret string   `json:"secret"`
+	Org    string   `json:"org"`
+}
+
+// NormalizeEvents checks the events of the request and returns them in the
+// stored form.
+func (r *WebhookRequest) NormalizeEvents() (string, error) {
+	if len(r.Events) == 0 {
+		return strings.Join(AllEvents, ","), nil
+	}
+	var events []string
+	for _, e := range AllEvents {
+		for _, requested := range r.Events {
+			if strings.TrimSpace(strings.ToLower(requested)) == e {
+				events = append(events, e)
+				break
+			}
+		}
+	}
+	if len(events) == 0 || len(events) < len(uniqueEvents(r.Events)) {
+		return "", fmt.Errorf("events must be among %s", strings.Join(AllEvents, ", "))
+	}
+	return strings.Join(events, ","), nil
+}
+
+func uniqueEvents(events []string) map[string]bool {
+	unique := make(map[string]bool, len(events))
+	for _, e := range events {
+		unique[strings.TrimSpace(strings.ToLower(e))] = true
+	}
+	return unique
+}
+
+// ValidateURL checks that the URL is an absolute http or https URL.
+func ValidateURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return fmt.Errorf("webhook URL must be an absolute http or https URL")
+	}
+	return nil
+}
+
+// WebhookCreated is the response to a registered webhook, the only one
+// holding its signing secret.
+type WebhookCreated struct {
+	Webhook
+	Secret string
+}
+
+// Payload is the body of a delivery. It identifies the secret but never
+// holds its value. The delivery ID is sent in HeaderDelivery.
+type Payload struct {
+	Webhook    uint      `json:"webhook"`
+	Event      string    `json:"event"`
+	SecretID   uint      `json:"secret_id"`
+	ActorID    uint      `json:"actor_id,omitempty"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+// Delivery is the log entry of posting one event to one webhook, retried
+// until it is delivered or Attempts reaches the limit of the dispatcher.
+type Delivery struct {
+	ID            uint `gorm:"primary_key"`
+	WebhookID     uint `gorm:"index"`
+	Event         string
+	SecretID      uint
+	Payload       string
+	State         string `sql:"index"`
+	Attempts      int
+	LastStatus    int
+	LastError     string
+	NextAttemptAt time.Time `sql:"index"`
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+// Sign returns the signature of a delivery body sent at timestamp.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifySignature checks the signature of a delivery in constant time, for
+// receivers written in Go.
+func VerifySignature(secret string, timestamp int64, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature))
+}
@@ -0,0 +1,39 @@
+package models
+
+import "testing"
+
+func TestNormalizeEvents(t *testing.T) {
+	tests := []struct {
+		events    []string
+		want      string
+		expectErr bool
+	}{
+		{nil, "created,updated,deleted,shared,expired", false},
+		{[]string{"Deleted", "created"}, "created,deleted", false},
+		{[]string{"updated", "updated"}, "updated", false},
+		{[]string{"updated", "read"}, "", true},
+	}
+
+	for _, tt := range tests {
+		req := WebhookRequest{Events: tt.events}
+		got, err := req.NormalizeEvents()
+		if (err != nil) != tt.expectErr {
+			t.Errorf("NormalizeEvents(%v) error = %v, expectErr %v", tt.events, err, tt.expectErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("NormalizeEvents(%v) = %s, want %s", tt.events, got, tt.want)
+		}
+	}
+}
+
+func TestSign(t *testing.T) {
+	body := []byte(`{"event":"updated","secret_id":7}`)
+	sig := Sign("s3cret", 1700000000, body)
+	if !VerifySignature("s3cret", 1700000000, body, sig) {
+		t.Errorf("expected the signature to verify")
+	}
+	if VerifySignature("s3cret", 1700000001, body, sig) || VerifySignature("other", 1700000000, body, sig) {
+		t.Errorf("expected the signature to depend on the timestamp and the secret")
+	}
+}
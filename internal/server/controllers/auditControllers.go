@@ -0,0 +1,83 @@
+package controllers
+
+import (
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"passKeeper/internal/events"
+	audit "passKeeper/internal/models/audit"
+	auth "passKeeper/internal/models/auth"
+	db "passKeeper/internal/models/database"
+
+	"github.com/go-chi/chi"
+	"github.com/go-chi/chi/middleware"
+)
+
+// AuditMiddleware records the action of the request in the audit log once it
+// is handled. The secret is taken from the {id} URL parameter unless the
+// handler sets it on the audit.Trail of the request context. An empty action
+// records only the events the handler adds to the trail. Recorded changes of
+// secrets are published to the bus, which may be nil.
+func AuditMiddleware(repo db.AuditRepository, bus *events.Bus, action string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			trail := &audit.Trail{Action: action}
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			next.ServeHTTP(ww, r.WithContext(audit.NewContext(r.Context(), trail)))
+
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+			if user, ok := auth.GetUserFromContext(r.Context()); ok {
+				trail.ActorID = user
+			}
+			recorded := trail.Events
+			if len(recorded) == 0 && trail.Action != "" {
+				if trail.SecretID == 0 {
+					trail.SecretID = uintParam(r, "id")
+				}
+				recorded = []audit.AuditEvent{{Action: trail.Action, SecretID: trail.SecretID, OwnerID: trail.OwnerID, Result: audit.ResultOf(status)}}
+			}
+			if len(recorded) == 0 {
+				return
+			}
+			now := time.Now()
+			for i := range recorded {
+				recorded[i].ActorID = trail.ActorID
+				recorded[i].ClientIP = clientIP(r)
+				recorded[i].UserAgent = r.UserAgent()
+				recorded[i].CreatedAt = now
+			}
+			if err := repo.RecordAuditEvents(recorded); err != nil {
+				log.Printf("cannot record %d audit events of user %d - %s", len(recorded), trail.ActorID, err)
+			}
+			for _, e := range recorded {
+				if event, ok := events.FromAudit(e); ok {
+					bus.Publish(event)
+				}
+			}
+		})
+	}
+}
+
+// clientIP returns the address of the client connected to the server.
+// Forwarding headers are ignored, since clients may set them to anything.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func uintParam(r *http.Request, key string) uint {
+	id, err := strconv.ParseUint(chi.URLParam(r, key), 10, 64)
+	if err != nil {
+		return 0
+	}
+	return uint(id)
+}
@@ -3,8 +3,12 @@ package client
 import (
 	"encoding/base64"
 	"fmt"
+	"mime"
+	"net/http"
 	"os"
 	secret "passKeeper/internal/models/secret"
+	"path/filepath"
+	"strings"
 )
 
 func FileExists(filename string) bool {
@@ -39,3 +43,47 @@ func SecretRequestFromBytes(data []byte, meta string) secret.SecretRequest {
 
 	return request
 }
+
+// FileSecretRequest reads the file and builds the request for a ByteSlice
+// secret together with the file metadata. meta is the description of the
+// file, the legacy name|ext|description format is accepted as well.
+func FileSecretRequest(path, meta string) (secret.SecretRequest, error) {
+	stat, err := os.Stat(path)
+	if err != nil {
+		return secret.SecretRequest{}, fmt.Errorf("failed to read file: %v", err)
+	}
+	data, err := FiletoBytes(path)
+	if err != nil {
+		return secret.SecretRequest{}, err
+	}
+
+	filename := filepath.Base(path)
+	info, ok := secret.ParseLegacyMetadata(meta)
+	if !ok {
+		info.Name = strings.TrimSuffix(filename, filepath.Ext(filename))
+	}
+	info.File = secret.FileInfo{
+		Filename: filename,
+		MimeType: DetectMimeType(path, data),
+		Size:     int64(len(data)),
+		SHA256:   secret.Checksum(data),
+		Mode:     uint32(stat.Mode().Perm()),
+	}
+
+	request := SecretRequestFromBytes(data, meta)
+	request.Info = &info
+	if request.Meta == "" {
+		// Older servers only store the legacy string.
+		request.Meta = info.LegacyMetadata()
+	}
+	return request, nil
+}
+
+// DetectMimeType guesses the MIME type from the file extension and falls back
+// to sniffing the content.
+func DetectMimeType(path string, data []byte) string {
+	if t := mime.TypeByExtension(filepath.Ext(path)); t != "" {
+		return t
+	}
+	return http.DetectContentType(data)
+}
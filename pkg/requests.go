@@ -6,10 +6,22 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"passKeeper/internal/events"
 	account "passKeeper/internal/models/account"
+	audit "passKeeper/internal/models/audit"
+	emergency "passKeeper/internal/models/emergency"
+	org "passKeeper/internal/models/org"
 	secret "passKeeper/internal/models/secret"
+	webhook "passKeeper/internal/models/webhook"
+	"path/filepath"
+	"strconv"
+	"time"
 )
 
+// userAgent identifies the client in the audit log of the server.
+const userAgent = "passKeeper-cli"
+
 func sendJSONRequest(client *http.Client, method, host, endpoint, token string, payload interface{}) ([]byte, error) {
 	payloadBuf := new(bytes.Buffer)
 	if err := json.NewEncoder(payloadBuf).Encode(payload); err != nil {
@@ -23,6 +35,7 @@ func sendJSONRequest(client *http.Client, method, host, endpoint, token string,
 	}
 
 	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", userAgent)
 	if token != "" {
 		req.Header.Set("Authorization", token)
 	}
@@ -64,7 +77,84 @@ func SendRegisterRequest(client *http.Client, host, login, password string) (*ac
 	return &response, nil
 }
 func SendGetSecretList(client *http.Client, host, token string) ([]secret.Secret, error) {
-	body, err := sendJSONRequest(client, "GET", host, "/api/secrets", token, nil)
+	return getSecretList(client, host, token, nil)
+}
+
+// SendGetSecretListByTags lists the secrets carrying any of the tags, or all
+// of them when matchAll is set.
+func SendGetSecretListByTags(client *http.Client, host, token string, tags []string, matchAll bool) ([]secret.Secret, error) {
+	query := url.Values{"tag": tags}
+	if matchAll {
+		query.Set("match", "all")
+	}
+	return getSecretList(client, host, token, query)
+}
+
+// ListOptions selects and orders the secrets of a paginated listing. Sort is
+// one of name, created, updated or type. A Vault other than zero lists the
+// secrets of that vault instead of the personal ones.
+type ListOptions struct {
+	Vault        uint
+	Tags         []string
+	MatchAllTags bool
+	Types        []string
+	Sort         string
+	Desc         bool
+	Limit        int
+}
+
+func (o ListOptions) query() url.Values {
+	query := url.Values{}
+	if o.Vault != 0 {
+		query.Set("vault", strconv.FormatUint(uint64(o.Vault), 10))
+	}
+	if len(o.Tags) > 0 {
+		query["tag"] = o.Tags
+	}
+	if o.MatchAllTags {
+		query.Set("match", "all")
+	}
+	if len(o.Types) > 0 {
+		query["type"] = o.Types
+	}
+	if o.Sort != "" {
+		query.Set("sort", o.Sort)
+	}
+	if o.Desc {
+		query.Set("order", "desc")
+	}
+	if o.Limit > 0 {
+		query.Set("limit", strconv.Itoa(o.Limit))
+	}
+	return query
+}
+
+// GetSecretPage returns the page of secrets starting at cursor, or the first
+// page for an empty cursor.
+func GetSecretPage(client *http.Client, host, token string, opts ListOptions, cursor string) (*secret.SecretPage, error) {
+	query := opts.query()
+	if cursor != "" {
+		query.Set("cursor", cursor)
+	}
+	body, err := sendJSONRequest(client, "GET", host, "/api/secret/secrets?"+query.Encode(), token, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var page secret.SecretPage
+	if err := json.Unmarshal(body, &page); err != nil {
+		return nil, err
+	}
+
+	return &page, nil
+}
+
+func getSecretList(client *http.Client, host, token string, query url.Values) ([]secret.Secret, error) {
+	endpoint := "/api/secret/secrets"
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+	body, err := sendJSONRequest(client, "GET", host, endpoint, token, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -96,53 +186,387 @@ func SendLoginRequest(client *http.Client, host, login, password string) (string
 	return response, nil
 }
 
-func PostSecret(client *http.Client, host, token, meta, secretType string, data interface{}, id uint) error {
+// SecretOption sets optional fields of the request used to create or update
+// a secret.
+type SecretOption func(*secret.SecretRequest)
+
+// WithName sets the display name of the secret.
+func WithName(name string) SecretOption {
+	return func(r *secret.SecretRequest) {
+		if r.Info == nil {
+			info, _ := secret.ParseLegacyMetadata(r.Meta)
+			if r.Type != "ByteSlice" {
+				info = secret.SecretMeta{Description: r.Meta}
+			}
+			r.Info = &info
+		}
+		r.Info.Name = name
+	}
+}
+
+// WithPath stores the secret under the given path.
+func WithPath(path string) SecretOption {
+	return func(r *secret.SecretRequest) {
+		r.Path = path
+	}
+}
+
+// WithExpiry sets the expiry date of the secret, after which the server stops
+// serving it.
+func WithExpiry(expiresAt time.Time) SecretOption {
+	return func(r *secret.SecretRequest) {
+		r.ExpiresAt = &expiresAt
+	}
+}
+
+// WithVault creates the secret in the vault instead of among the personal
+// secrets. It has no effect on updates, secrets stay in their vault.
+func WithVault(vaultID uint) SecretOption {
+	return func(r *secret.SecretRequest) {
+		r.VaultID = vaultID
+	}
+}
+
+func PostSecret(client *http.Client, host, token, meta, secretType string, data interface{}, id uint, opts ...SecretOption) error {
 	dataJson, err := json.Marshal(data)
 	if err != nil {
 		return err
 	}
 	if secretType == "ByteSlice" {
+		if request, ok := data.(secret.SecretRequest); ok {
+			for _, opt := range opts {
+				opt(&request)
+			}
+			data = request
+		}
 		_, err = sendJSONRequest(client, "POST", host, "/api/secret", token, data)
 		return err
 	}
 
 	secretRequest := secret.SecretRequest{ID: id, Type: secretType, Meta: meta, Data: json.RawMessage(dataJson)}
+	for _, opt := range opts {
+		opt(&secretRequest)
+	}
 	_, err = sendJSONRequest(client, "POST", host, "/api/secret", token, secretRequest)
 	return err
 }
 
-func PostTextSecret(client *http.Client, host, token, meta, value string, id uint) error {
+// ApplyBatch sends the operations to be applied in one transaction and
+// returns the result of every operation.
+func ApplyBatch(client *http.Client, host, token string, ops []secret.BatchOperation) (*secret.BatchResponse, error) {
+	body, err := sendJSONRequest(client, "POST", host, "/api/secret/batch", token, secret.BatchRequest{Operations: ops})
+	if err != nil {
+		return nil, err
+	}
+
+	var response secret.BatchResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, err
+	}
+
+	return &response, nil
+}
+
+func PostTextSecret(client *http.Client, host, token, meta, value string, id uint, opts ...SecretOption) error {
 	if id == 0 {
-		return PostSecret(client, host, token, meta, "Text", secret.Text{Value: value}, 0)
+		return PostSecret(client, host, token, meta, "Text", secret.Text{Value: value}, 0, opts...)
 	}
-	return PostSecret(client, host, token, meta, "Text", secret.Text{Value: value}, id)
+	return PostSecret(client, host, token, meta, "Text", secret.Text{Value: value}, id, opts...)
 }
 
-func PostKVSecret(client *http.Client, host, token, meta, key, value string, id uint) error {
+func PostKVSecret(client *http.Client, host, token, meta, key, value string, id uint, opts ...SecretOption) error {
 	if id == 0 {
-		return PostSecret(client, host, token, meta, "KeyValue", secret.KeyValue{Key: key, Value: value}, 0)
+		return PostSecret(client, host, token, meta, "KeyValue", secret.KeyValue{Key: key, Value: value}, 0, opts...)
 	}
-	return PostSecret(client, host, token, meta, "KeyValue", secret.KeyValue{Key: key, Value: value}, id)
+	return PostSecret(client, host, token, meta, "KeyValue", secret.KeyValue{Key: key, Value: value}, id, opts...)
 }
 
-func PostCCSecret(client *http.Client, host, token, meta, cnn, exp, cvv, cholder string, id uint) error {
+func PostCCSecret(client *http.Client, host, token, meta, cnn, exp, cvv, cholder string, id uint, opts ...SecretOption) error {
 	data := secret.CreditCard{Number: cnn, Expiration: exp, CVV: cvv, Cardholder: cholder}
 	if id == 0 {
-		return PostSecret(client, host, token, meta, "CreditCard", data, 0)
+		return PostSecret(client, host, token, meta, "CreditCard", data, 0, opts...)
 	}
 
-	return PostSecret(client, host, token, meta, "CreditCard", data, id)
+	return PostSecret(client, host, token, meta, "CreditCard", data, id, opts...)
 }
-func PostFileSecret(client *http.Client, host, token, meta, path string, id uint) error {
+func PostFileSecret(client *http.Client, host, token, meta, path string, id uint, opts ...SecretOption) error {
 
-	data, err := FiletoBytes(path)
+	secret, err := FileSecretRequest(path, meta)
 	if err != nil {
 		return err
 	}
 
-	secret := SecretRequestFromBytes(data, meta)
+	return PostSecret(client, host, token, meta, "ByteSlice", secret, id, opts...)
+}
+
+func PostBankSecret(client *http.Client, host, token, meta string, account secret.BankAccount, id uint, opts ...SecretOption) error {
+	return PostSecret(client, host, token, meta, "BankAccount", account, id, opts...)
+}
+
+func PostCertificateSecret(client *http.Client, host, token, meta, chain, key string, id uint, opts ...SecretOption) error {
+	data := secret.Certificate{Chain: chain, PrivateKey: key}
+	return PostSecret(client, host, token, meta, "Certificate", data, id, opts...)
+}
+
+func PostEnvSecret(client *http.Client, host, token, meta string, bundle secret.EnvBundle, id uint, opts ...SecretOption) error {
+	return PostSecret(client, host, token, meta, "EnvBundle", bundle, id, opts...)
+}
+
+// PostIdentitySecret stores the identity document and returns the saved
+// secret, whose ID the scans are attached to.
+func PostIdentitySecret(client *http.Client, host, token, meta string, identity secret.Identity, id uint, opts ...SecretOption) (*secret.Secret, error) {
+	data, err := json.Marshal(identity)
+	if err != nil {
+		return nil, err
+	}
+	request := secret.SecretRequest{ID: id, Type: "Identity", Meta: meta, Data: json.RawMessage(data)}
+	for _, opt := range opts {
+		opt(&request)
+	}
+
+	body, err := sendJSONRequest(client, "POST", host, "/api/secret", token, request)
+	if err != nil {
+		return nil, err
+	}
+
+	var saved secret.Secret
+	if err := json.Unmarshal(body, &saved); err != nil {
+		return nil, err
+	}
+
+	return &saved, nil
+}
+
+func GetSecretByPath(client *http.Client, host, token, path string) (*secret.Secret, error) {
+	endpoint := "/api/secret/path?path=" + url.QueryEscape(path)
+	body, err := sendJSONRequest(client, "GET", host, endpoint, token, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var secretResult secret.Secret
+	if err := json.Unmarshal(body, &secretResult); err != nil {
+		return nil, err
+	}
+
+	return &secretResult, nil
+}
+
+// ListPath returns the secrets stored below prefix, ordered by path and
+// without their values.
+func ListPath(client *http.Client, host, token, prefix string) ([]secret.Secret, error) {
+	return ListVaultPath(client, host, token, 0, prefix)
+}
+
+// ListVaultPath lists the secrets of the vault below prefix, or the ones of
+// the default vault for a vault of zero.
+func ListVaultPath(client *http.Client, host, token string, vault uint, prefix string) ([]secret.Secret, error) {
+	params := url.Values{"prefix": {prefix}}
+	if vault != 0 {
+		params.Set("vault", strconv.FormatUint(uint64(vault), 10))
+	}
+	endpoint := "/api/secret/ls?" + params.Encode()
+	body, err := sendJSONRequest(client, "GET", host, endpoint, token, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var secrets []secret.Secret
+	if err := json.Unmarshal(body, &secrets); err != nil {
+		return nil, err
+	}
+
+	return secrets, nil
+}
+
+// ResolvePath returns the secret stored at path without its value. Unlike
+// GetSecretByPath, it does not count as an access to the secret.
+func ResolvePath(client *http.Client, host, token, path string) (*secret.Secret, error) {
+	return ResolveVaultPath(client, host, token, 0, path)
+}
+
+// ResolveVaultPath resolves the path in the vault, or among the personal
+// secrets for a vault of zero.
+func ResolveVaultPath(client *http.Client, host, token string, vault uint, path string) (*secret.Secret, error) {
+	params := url.Values{"path": {path}}
+	if vault != 0 {
+		params.Set("vault", strconv.FormatUint(uint64(vault), 10))
+	}
+	endpoint := "/api/secret/resolve?" + params.Encode()
+	body, err := sendJSONRequest(client, "GET", host, endpoint, token, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var secretResult secret.Secret
+	if err := json.Unmarshal(body, &secretResult); err != nil {
+		return nil, err
+	}
+
+	return &secretResult, nil
+}
+
+// SearchSecrets returns the secrets matching the query without their values.
+func SearchSecrets(client *http.Client, host, token, query string, limit int) ([]secret.Secret, error) {
+	return SearchVaultSecrets(client, host, token, query, 0, limit)
+}
+
+// SearchVaultSecrets searches the secrets of the vault, or the personal
+// secrets for a vault of zero.
+func SearchVaultSecrets(client *http.Client, host, token, query string, vault uint, limit int) ([]secret.Secret, error) {
+	params := url.Values{"q": {query}}
+	if vault != 0 {
+		params.Set("vault", strconv.FormatUint(uint64(vault), 10))
+	}
+	if limit > 0 {
+		params.Set("limit", strconv.Itoa(limit))
+	}
+	body, err := sendJSONRequest(client, "GET", host, "/api/secret/search?"+params.Encode(), token, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var secrets []secret.Secret
+	if err := json.Unmarshal(body, &secrets); err != nil {
+		return nil, err
+	}
+
+	return secrets, nil
+}
+
+func MoveSecret(client *http.Client, host, token, id, path string) error {
+	endpoint := fmt.Sprintf("/api/secret/%s/path", id)
+	_, err := sendJSONRequest(client, "PUT", host, endpoint, token, map[string]string{"path": path})
+	return err
+}
+
+// MoveSecretToVault moves the secret to the vault, or to the default vault
+// for a vault of zero.
+func MoveSecretToVault(client *http.Client, host, token, id string, vault uint) error {
+	endpoint := fmt.Sprintf("/api/secret/%s/vault", id)
+	_, err := sendJSONRequest(client, "PUT", host, endpoint, token, map[string]uint{"vault_id": vault})
+	return err
+}
+
+// SetSecretExpiry sets the expiry date of the secret, nil removes it.
+func SetSecretExpiry(client *http.Client, host, token, id string, expiresAt *time.Time) error {
+	endpoint := fmt.Sprintf("/api/secret/%s/expiry", id)
+	_, err := sendJSONRequest(client, "PUT", host, endpoint, token, map[string]*time.Time{"expires_at": expiresAt})
+	return err
+}
+
+// GetExpiringSecrets returns the secrets expiring within the duration without
+// their values, including the ones which already expired.
+func GetExpiringSecrets(client *http.Client, host, token string, within time.Duration) ([]secret.Secret, error) {
+	return GetVaultExpiringSecrets(client, host, token, 0, within)
+}
+
+// GetVaultExpiringSecrets works like GetExpiringSecrets on the secrets of the
+// vault, or the ones of the default vault for a vault of zero.
+func GetVaultExpiringSecrets(client *http.Client, host, token string, vault uint, within time.Duration) ([]secret.Secret, error) {
+	params := url.Values{"within": {within.String()}}
+	if vault != 0 {
+		params.Set("vault", strconv.FormatUint(uint64(vault), 10))
+	}
+	endpoint := "/api/secret/expiring?" + params.Encode()
+	body, err := sendJSONRequest(client, "GET", host, endpoint, token, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var secrets []secret.Secret
+	if err := json.Unmarshal(body, &secrets); err != nil {
+		return nil, err
+	}
+
+	return secrets, nil
+}
+
+// GetStaleSecrets returns the secrets which have not been read for the given
+// duration, without their values.
+func GetStaleSecrets(client *http.Client, host, token string, unusedFor time.Duration) ([]secret.Secret, error) {
+	endpoint := "/api/secret/stale?unused_for=" + url.QueryEscape(unusedFor.String())
+	body, err := sendJSONRequest(client, "GET", host, endpoint, token, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var secrets []secret.Secret
+	if err := json.Unmarshal(body, &secrets); err != nil {
+		return nil, err
+	}
+
+	return secrets, nil
+}
+
+// CopySecret duplicates the secret with its tags and attachments under path
+// and returns the copy.
+func CopySecret(client *http.Client, host, token, id, path string) (*secret.Secret, error) {
+	endpoint := fmt.Sprintf("/api/secret/%s/copy", id)
+	body, err := sendJSONRequest(client, "POST", host, endpoint, token, map[string]string{"path": path})
+	if err != nil {
+		return nil, err
+	}
+
+	var copied secret.Secret
+	if err := json.Unmarshal(body, &copied); err != nil {
+		return nil, err
+	}
+
+	return &copied, nil
+}
+
+// ShareSecret grants the account in the request access to the secret.
+func ShareSecret(client *http.Client, host, token, id string, req secret.ShareRequest) (*secret.Share, error) {
+	endpoint := fmt.Sprintf("/api/secret/%s/shares", id)
+	body, err := sendJSONRequest(client, "POST", host, endpoint, token, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var share secret.Share
+	if err := json.Unmarshal(body, &share); err != nil {
+		return nil, err
+	}
+
+	return &share, nil
+}
+
+func GetShares(client *http.Client, host, token, id string) ([]secret.Share, error) {
+	endpoint := fmt.Sprintf("/api/secret/%s/shares", id)
+	body, err := sendJSONRequest(client, "GET", host, endpoint, token, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var shares []secret.Share
+	if err := json.Unmarshal(body, &shares); err != nil {
+		return nil, err
+	}
+
+	return shares, nil
+}
+
+func RevokeShare(client *http.Client, host, token, id, login string) error {
+	endpoint := fmt.Sprintf("/api/secret/%s/shares/%s", id, url.PathEscape(login))
+	_, err := sendJSONRequest(client, "DELETE", host, endpoint, token, nil)
+	return err
+}
+
+// GetSharedSecrets returns the secrets other accounts shared with the caller,
+// without their values.
+func GetSharedSecrets(client *http.Client, host, token string) ([]secret.SharedSecret, error) {
+	body, err := sendJSONRequest(client, "GET", host, "/api/secret/shared", token, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var shared []secret.SharedSecret
+	if err := json.Unmarshal(body, &shared); err != nil {
+		return nil, err
+	}
 
-	return PostSecret(client, host, token, meta, "ByteSlice", secret, id)
+	return shared, nil
 }
 
 func DeleteSecret(client *http.Client, host, token, id string) error {
@@ -151,6 +575,66 @@ func DeleteSecret(client *http.Client, host, token, id string) error {
 	return err
 }
 
+func GetTrash(client *http.Client, host, token string) ([]secret.Secret, error) {
+	return GetVaultTrash(client, host, token, 0)
+}
+
+// GetVaultTrash lists the trashed secrets of the vault, or the ones of the
+// default vault for a vault of zero.
+func GetVaultTrash(client *http.Client, host, token string, vault uint) ([]secret.Secret, error) {
+	body, err := sendJSONRequest(client, "GET", host, trashEndpoint(vault), token, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var secrets []secret.Secret
+	if err := json.Unmarshal(body, &secrets); err != nil {
+		return nil, err
+	}
+
+	return secrets, nil
+}
+
+func RestoreSecret(client *http.Client, host, token, id string) error {
+	endpoint := fmt.Sprintf("/api/secret/trash/%s/restore", id)
+	_, err := sendJSONRequest(client, "POST", host, endpoint, token, nil)
+	return err
+}
+
+func PurgeSecret(client *http.Client, host, token, id string) error {
+	endpoint := fmt.Sprintf("/api/secret/trash/%s", id)
+	_, err := sendJSONRequest(client, "DELETE", host, endpoint, token, nil)
+	return err
+}
+
+func trashEndpoint(vault uint) string {
+	if vault == 0 {
+		return "/api/secret/trash"
+	}
+	return "/api/secret/trash?" + url.Values{"vault": {strconv.FormatUint(uint64(vault), 10)}}.Encode()
+}
+
+// EmptyTrash purges every secret in the trash and returns their number.
+func EmptyTrash(client *http.Client, host, token string) (int, error) {
+	return EmptyVaultTrash(client, host, token, 0)
+}
+
+// EmptyVaultTrash purges the trashed secrets of the vault, or the ones of the
+// default vault for a vault of zero, and returns their number.
+func EmptyVaultTrash(client *http.Client, host, token string, vault uint) (int, error) {
+	body, err := sendJSONRequest(client, "DELETE", host, trashEndpoint(vault), token, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	var n int
+	if err := json.Unmarshal(body, &n); err != nil {
+		return 0, err
+	}
+
+	return n, nil
+}
+
 func GetSecret(client *http.Client, host, token, id string) (*secret.Secret, error) {
 	endpoint := fmt.Sprintf("/api/secret/%s", id)
 	body, err := sendJSONRequest(client, "GET", host, endpoint, token, nil)
@@ -167,3 +651,515 @@ func GetSecret(client *http.Client, host, token, id string) (*secret.Secret, err
 
 	return &secretResult, nil
 }
+
+func GetExpiringCertificates(client *http.Client, host, token string, within time.Duration) ([]secret.CertificateInfo, error) {
+	endpoint := "/api/secret/certs/expiring?within=" + url.QueryEscape(within.String())
+	body, err := sendJSONRequest(client, "GET", host, endpoint, token, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var certs []secret.CertificateInfo
+	if err := json.Unmarshal(body, &certs); err != nil {
+		return nil, err
+	}
+
+	return certs, nil
+}
+
+func PostAttachment(client *http.Client, host, token, id, path string) (*secret.Attachment, error) {
+	data, err := FiletoBytes(path)
+	if err != nil {
+		return nil, err
+	}
+	attachment := secret.Attachment{
+		Filename: filepath.Base(path),
+		MimeType: DetectMimeType(path, data),
+		Checksum: secret.Checksum(data),
+		Data:     data,
+	}
+
+	endpoint := fmt.Sprintf("/api/secret/%s/attachments", id)
+	body, err := sendJSONRequest(client, "POST", host, endpoint, token, attachment)
+	if err != nil {
+		return nil, err
+	}
+
+	var saved secret.Attachment
+	if err := json.Unmarshal(body, &saved); err != nil {
+		return nil, err
+	}
+
+	return &saved, nil
+}
+
+func GetAttachments(client *http.Client, host, token, id string) ([]secret.Attachment, error) {
+	endpoint := fmt.Sprintf("/api/secret/%s/attachments", id)
+	body, err := sendJSONRequest(client, "GET", host, endpoint, token, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var attachments []secret.Attachment
+	if err := json.Unmarshal(body, &attachments); err != nil {
+		return nil, err
+	}
+
+	return attachments, nil
+}
+
+func GetAttachment(client *http.Client, host, token, id, name string) (*secret.Attachment, error) {
+	endpoint := fmt.Sprintf("/api/secret/%s/attachments/%s", id, url.PathEscape(name))
+	body, err := sendJSONRequest(client, "GET", host, endpoint, token, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var attachment secret.Attachment
+	if err := json.Unmarshal(body, &attachment); err != nil {
+		return nil, err
+	}
+	if err := attachment.Verify(); err != nil {
+		return nil, err
+	}
+
+	return &attachment, nil
+}
+
+func DeleteAttachment(client *http.Client, host, token, id, name string) error {
+	endpoint := fmt.Sprintf("/api/secret/%s/attachments/%s", id, url.PathEscape(name))
+	_, err := sendJSONRequest(client, "DELETE", host, endpoint, token, nil)
+	return err
+}
+
+func AddTag(client *http.Client, host, token, id, tag string) error {
+	endpoint := fmt.Sprintf("/api/secret/%s/tags/%s", id, url.PathEscape(tag))
+	_, err := sendJSONRequest(client, "PUT", host, endpoint, token, nil)
+	return err
+}
+
+func RemoveTag(client *http.Client, host, token, id, tag string) error {
+	endpoint := fmt.Sprintf("/api/secret/%s/tags/%s", id, url.PathEscape(tag))
+	_, err := sendJSONRequest(client, "DELETE", host, endpoint, token, nil)
+	return err
+}
+
+// CreateOrganization creates the organization with the caller as its owner.
+func CreateOrganization(client *http.Client, host, token, name string) (*org.Organization, error) {
+	body, err := sendJSONRequest(client, "POST", host, "/api/org", token, org.NameRequest{Name: name})
+	if err != nil {
+		return nil, err
+	}
+
+	var o org.Organization
+	if err := json.Unmarshal(body, &o); err != nil {
+		return nil, err
+	}
+
+	return &o, nil
+}
+
+// GetOrganizations returns the organizations of the caller with its role.
+func GetOrganizations(client *http.Client, host, token string) ([]org.Organization, error) {
+	body, err := sendJSONRequest(client, "GET", host, "/api/org", token, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var orgs []org.Organization
+	if err := json.Unmarshal(body, &orgs); err != nil {
+		return nil, err
+	}
+
+	return orgs, nil
+}
+
+func GetMembers(client *http.Client, host, token, orgName string) ([]org.Member, error) {
+	endpoint := fmt.Sprintf("/api/org/%s/members", url.PathEscape(orgName))
+	body, err := sendJSONRequest(client, "GET", host, endpoint, token, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var members []org.Member
+	if err := json.Unmarshal(body, &members); err != nil {
+		return nil, err
+	}
+
+	return members, nil
+}
+
+// SetMember adds the account login to the organization or changes its role.
+func SetMember(client *http.Client, host, token, orgName, login, role string) (*org.Member, error) {
+	endpoint := fmt.Sprintf("/api/org/%s/members/%s", url.PathEscape(orgName), url.PathEscape(login))
+	body, err := sendJSONRequest(client, "PUT", host, endpoint, token, org.MemberRequest{Role: role})
+	if err != nil {
+		return nil, err
+	}
+
+	var member org.Member
+	if err := json.Unmarshal(body, &member); err != nil {
+		return nil, err
+	}
+
+	return &member, nil
+}
+
+func RemoveMember(client *http.Client, host, token, orgName, login string) error {
+	endpoint := fmt.Sprintf("/api/org/%s/members/%s", url.PathEscape(orgName), url.PathEscape(login))
+	_, err := sendJSONRequest(client, "DELETE", host, endpoint, token, nil)
+	return err
+}
+
+func GetVaults(client *http.Client, host, token, orgName string) ([]org.Vault, error) {
+	endpoint := fmt.Sprintf("/api/org/%s/vaults", url.PathEscape(orgName))
+	body, err := sendJSONRequest(client, "GET", host, endpoint, token, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var vaults []org.Vault
+	if err := json.Unmarshal(body, &vaults); err != nil {
+		return nil, err
+	}
+
+	return vaults, nil
+}
+
+func CreateVault(client *http.Client, host, token, orgName, name string) (*org.Vault, error) {
+	endpoint := fmt.Sprintf("/api/org/%s/vaults", url.PathEscape(orgName))
+	body, err := sendJSONRequest(client, "POST", host, endpoint, token, org.NameRequest{Name: name})
+	if err != nil {
+		return nil, err
+	}
+
+	var vault org.Vault
+	if err := json.Unmarshal(body, &vault); err != nil {
+		return nil, err
+	}
+
+	return &vault, nil
+}
+
+// DeleteVault deletes the vault, which must not hold any secrets.
+func DeleteVault(client *http.Client, host, token, orgName, name string) error {
+	endpoint := fmt.Sprintf("/api/org/%s/vaults/%s", url.PathEscape(orgName), url.PathEscape(name))
+	_, err := sendJSONRequest(client, "DELETE", host, endpoint, token, nil)
+	return err
+}
+
+// GetPersonalVaults returns the personal vaults of the caller.
+func GetPersonalVaults(client *http.Client, host, token string) ([]org.Vault, error) {
+	body, err := sendJSONRequest(client, "GET", host, "/api/vault", token, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var vaults []org.Vault
+	if err := json.Unmarshal(body, &vaults); err != nil {
+		return nil, err
+	}
+
+	return vaults, nil
+}
+
+func CreatePersonalVault(client *http.Client, host, token, name string) (*org.Vault, error) {
+	body, err := sendJSONRequest(client, "POST", host, "/api/vault", token, org.NameRequest{Name: name})
+	if err != nil {
+		return nil, err
+	}
+
+	var vault org.Vault
+	if err := json.Unmarshal(body, &vault); err != nil {
+		return nil, err
+	}
+
+	return &vault, nil
+}
+
+// DeletePersonalVault deletes the personal vault with all of its secrets and
+// returns the number of secrets deleted.
+func DeletePersonalVault(client *http.Client, host, token, name string) (int, error) {
+	body, err := sendJSONRequest(client, "DELETE", host, "/api/vault/"+url.PathEscape(name), token, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	var n int
+	if err := json.Unmarshal(body, &n); err != nil {
+		return 0, err
+	}
+
+	return n, nil
+}
+
+// CreateOneTimeShare stores a value encrypted on the client as a one-time
+// share and returns its token.
+func CreateOneTimeShare(client *http.Client, host, token string, req secret.OneTimeShareRequest) (*secret.OneTimeShareCreated, error) {
+	body, err := sendJSONRequest(client, "POST", host, "/api/once", token, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var created secret.OneTimeShareCreated
+	if err := json.Unmarshal(body, &created); err != nil {
+		return nil, err
+	}
+
+	return &created, nil
+}
+
+// OpenOneTimeShare uses up one view of the one-time share and returns its
+// ciphertext. It needs no account.
+func OpenOneTimeShare(client *http.Client, host, shareToken string) (*secret.OneTimeShareOpened, error) {
+	body, err := sendJSONRequest(client, "POST", host, "/api/once/"+url.PathEscape(shareToken), "", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var opened secret.OneTimeShareOpened
+	if err := json.Unmarshal(body, &opened); err != nil {
+		return nil, err
+	}
+
+	return &opened, nil
+}
+
+func GetEmergencyContacts(client *http.Client, host, token string) ([]emergency.EmergencyContact, error) {
+	return getEmergencyContacts(client, host, token, "/api/emergency/contacts")
+}
+
+// GetEmergencyOwners returns the accounts which designated the caller as an
+// emergency contact.
+func GetEmergencyOwners(client *http.Client, host, token string) ([]emergency.EmergencyContact, error) {
+	return getEmergencyContacts(client, host, token, "/api/emergency/owners")
+}
+
+func getEmergencyContacts(client *http.Client, host, token, endpoint string) ([]emergency.EmergencyContact, error) {
+	body, err := sendJSONRequest(client, "GET", host, endpoint, token, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var contacts []emergency.EmergencyContact
+	if err := json.Unmarshal(body, &contacts); err != nil {
+		return nil, err
+	}
+
+	return contacts, nil
+}
+
+// SetEmergencyContact designates the account login as an emergency contact
+// or changes its waiting period.
+func SetEmergencyContact(client *http.Client, host, token, login string, waitHours int) (*emergency.EmergencyContact, error) {
+	endpoint := "/api/emergency/contacts/" + url.PathEscape(login)
+	body, err := sendJSONRequest(client, "PUT", host, endpoint, token, emergency.ContactRequest{WaitHours: waitHours})
+	if err != nil {
+		return nil, err
+	}
+
+	return unmarshalEmergencyContact(body)
+}
+
+func RemoveEmergencyContact(client *http.Client, host, token, login string) error {
+	_, err := sendJSONRequest(client, "DELETE", host, "/api/emergency/contacts/"+url.PathEscape(login), token, nil)
+	return err
+}
+
+// ChangeEmergencyAccess applies one of the emergency.Action* actions to the
+// emergency access between the caller and the account login. Owners
+// approve, reject and revoke, contacts request and cancel.
+func ChangeEmergencyAccess(client *http.Client, host, token, login, action string) (*emergency.EmergencyContact, error) {
+	side := "owners"
+	if emergency.OwnerAction(action) {
+		side = "contacts"
+	}
+	endpoint := fmt.Sprintf("/api/emergency/%s/%s/%s", side, url.PathEscape(login), url.PathEscape(action))
+	body, err := sendJSONRequest(client, "POST", host, endpoint, token, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return unmarshalEmergencyContact(body)
+}
+
+func unmarshalEmergencyContact(body []byte) (*emergency.EmergencyContact, error) {
+	var contact emergency.EmergencyContact
+	if err := json.Unmarshal(body, &contact); err != nil {
+		return nil, err
+	}
+
+	return &contact, nil
+}
+
+// GetEmergencyEvents returns the history of the emergency access between the
+// caller and the account login, with the caller as the owner or the contact.
+func GetEmergencyEvents(client *http.Client, host, token, login string, asOwner bool) ([]emergency.EmergencyEvent, error) {
+	side := "owners"
+	if asOwner {
+		side = "contacts"
+	}
+	endpoint := fmt.Sprintf("/api/emergency/%s/%s/events", side, url.PathEscape(login))
+	body, err := sendJSONRequest(client, "GET", host, endpoint, token, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []emergency.EmergencyEvent
+	if err := json.Unmarshal(body, &events); err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+// GetEmergencySecrets lists the secrets of the account login without their
+// values once the caller was granted emergency access to them.
+func GetEmergencySecrets(client *http.Client, host, token, login string) ([]secret.Secret, error) {
+	endpoint := fmt.Sprintf("/api/emergency/owners/%s/secrets", url.PathEscape(login))
+	body, err := sendJSONRequest(client, "GET", host, endpoint, token, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var secrets []secret.Secret
+	if err := json.Unmarshal(body, &secrets); err != nil {
+		return nil, err
+	}
+
+	return secrets, nil
+}
+
+// GetAuditEvents returns the audit events of the caller, newest first,
+// optionally only the ones since a time or of the secret with the ID.
+func GetAuditEvents(client *http.Client, host, token string, since *time.Time, id string, limit int) ([]audit.AuditEvent, error) {
+	params := url.Values{}
+	if since != nil {
+		params.Set("since", since.UTC().Format(time.RFC3339))
+	}
+	if id != "" {
+		params.Set("secret", id)
+	}
+	if limit > 0 {
+		params.Set("limit", strconv.Itoa(limit))
+	}
+	body, err := sendJSONRequest(client, "GET", host, "/api/audit?"+params.Encode(), token, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []audit.AuditEvent
+	if err := json.Unmarshal(body, &events); err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+// CreateWebhook registers a webhook of the caller, or of the organization in
+// the request. The response holds the signing secret, which is not shown
+// again.
+func CreateWebhook(client *http.Client, host, token string, req webhook.WebhookRequest) (*webhook.WebhookCreated, error) {
+	body, err := sendJSONRequest(client, "POST", host, "/api/webhook", token, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var created webhook.WebhookCreated
+	if err := json.Unmarshal(body, &created); err != nil {
+		return nil, err
+	}
+
+	return &created, nil
+}
+
+// GetWebhooks lists the personal webhooks of the caller, or those of the
+// organization if it is not empty.
+func GetWebhooks(client *http.Client, host, token, organization string) ([]webhook.Webhook, error) {
+	endpoint := "/api/webhook"
+	if organization != "" {
+		endpoint += "?org=" + url.QueryEscape(organization)
+	}
+	body, err := sendJSONRequest(client, "GET", host, endpoint, token, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var hooks []webhook.Webhook
+	if err := json.Unmarshal(body, &hooks); err != nil {
+		return nil, err
+	}
+
+	return hooks, nil
+}
+
+func DeleteWebhook(client *http.Client, host, token, id string) error {
+	_, err := sendJSONRequest(client, "DELETE", host, "/api/webhook/"+url.PathEscape(id), token, nil)
+	return err
+}
+
+// GetWebhookDeliveries returns the last deliveries of the webhook, the most
+// recent first.
+func GetWebhookDeliveries(client *http.Client, host, token, id string) ([]webhook.Delivery, error) {
+	body, err := sendJSONRequest(client, "GET", host, "/api/webhook/"+url.PathEscape(id)+"/deliveries", token, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var deliveries []webhook.Delivery
+	if err := json.Unmarshal(body, &deliveries); err != nil {
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+// WatchSecrets streams the changes of the secrets the caller can read and
+// calls fn with each of them until the server closes the stream, the request
+// fails or fn returns an error. The client must not have a timeout.
+func WatchSecrets(client *http.Client, host, token string, fn func(events.Event) error) error {
+	req, err := http.NewRequest("GET", "https://"+host+"/api/watch", nil)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set("User-Agent", userAgent)
+	if token != "" {
+		req.Header.Set("Authorization", token)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+
+	return events.ReadSSE(resp.Body, fn)
+}
+
+// GetChanges returns a page of the changes of the secrets of the caller after
+// the revision since, zero for all of them. A limit of zero uses the default
+// of the server.
+func GetChanges(client *http.Client, host, token string, since uint64, limit int) (*secret.ChangeFeed, error) {
+	params := url.Values{}
+	params.Set("since", strconv.FormatUint(since, 10))
+	if limit > 0 {
+		params.Set("limit", strconv.Itoa(limit))
+	}
+	body, err := sendJSONRequest(client, "GET", host, "/api/secret/changes?"+params.Encode(), token, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var feed secret.ChangeFeed
+	if err := json.Unmarshal(body, &feed); err != nil {
+		return nil, err
+	}
+
+	return &feed, nil
+}
@@ -6,9 +6,17 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"passKeeper/internal/events"
+	audit "passKeeper/internal/models/audit"
+	emergency "passKeeper/internal/models/emergency"
+	org "passKeeper/internal/models/org"
 	secret "passKeeper/internal/models/secret"
+	webhook "passKeeper/internal/models/webhook"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/go-chi/chi"
 )
 
 func TestSendJSONRequest(t *testing.T) {
@@ -204,6 +212,28 @@ func TestSendGetSecretList(t *testing.T) {
 	}
 }
 
+// TestSendGetSecretListRoute mounts the list the way the server does, the
+// secret handler under /api/secret serving /secrets.
+func TestSendGetSecretListRoute(t *testing.T) {
+	secrets := chi.NewRouter()
+	secrets.Get("/secrets", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `[{"ID": 1, "SecretType": "Text"}]`)
+	})
+	router := chi.NewRouter()
+	router.Mount("/api/secret", secrets)
+	ts := httptest.NewTLSServer(router)
+	defer ts.Close()
+
+	host := strings.TrimPrefix(ts.URL, "https://")
+	list, err := SendGetSecretList(ts.Client(), host, "testToken")
+	if err != nil {
+		t.Fatalf("didn't expect error, got %v", err)
+	}
+	if len(list) != 1 || list[0].ID != 1 {
+		t.Errorf("unexpected secrets %+v", list)
+	}
+}
+
 func TestSendLoginRequest(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -812,3 +842,624 @@ func TestGetSecret(t *testing.T) {
 		})
 	}
 }
+
+func TestPostIdentitySecret(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req secret.SecretRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Type != "Identity" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(secret.Secret{ID: 7, SecretType: req.Type, Metadata: req.Meta})
+	}))
+	defer ts.Close()
+
+	host := strings.TrimPrefix(ts.URL, "https://")
+	identity := secret.Identity{DocumentType: "passport", Number: "X123"}
+	saved, err := PostIdentitySecret(ts.Client(), host, "testToken", "passport", identity, 0)
+	if err != nil {
+		t.Fatalf("didn't expect error, got %v", err)
+	}
+	if saved.ID != 7 || saved.Metadata != "passport" {
+		t.Errorf("unexpected saved secret %+v", saved)
+	}
+}
+
+func TestGetAttachment(t *testing.T) {
+	data := []byte("attachment data")
+	tests := []struct {
+		name     string
+		checksum string
+		hasErr   bool
+	}{
+		{
+			name:     "valid checksum",
+			checksum: secret.Checksum(data),
+			hasErr:   false,
+		},
+		{
+			name:     "corrupted download",
+			checksum: secret.Checksum([]byte("other data")),
+			hasErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/api/secret/1/attachments/my file.txt" {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				json.NewEncoder(w).Encode(secret.Attachment{Filename: "my file.txt", Checksum: tt.checksum, Data: data})
+			}))
+			defer ts.Close()
+
+			host := strings.TrimPrefix(ts.URL, "https://")
+			attachment, err := GetAttachment(ts.Client(), host, "testToken", "1", "my file.txt")
+
+			if tt.hasErr && err == nil {
+				t.Fatalf("expected error, got nil")
+			}
+			if !tt.hasErr && err != nil {
+				t.Fatalf("didn't expect error, got %v", err)
+			}
+			if !tt.hasErr && string(attachment.Data) != string(data) {
+				t.Errorf("expected data %s, got %s", data, attachment.Data)
+			}
+		})
+	}
+}
+
+func TestSendGetSecretListByTags(t *testing.T) {
+	tests := []struct {
+		name          string
+		tags          []string
+		matchAll      bool
+		expectedQuery string
+	}{
+		{
+			name:          "any of the tags",
+			tags:          []string{"prod", "team-payments"},
+			expectedQuery: "tag=prod&tag=team-payments",
+		},
+		{
+			name:          "all of the tags",
+			tags:          []string{"prod", "team-payments"},
+			matchAll:      true,
+			expectedQuery: "match=all&tag=prod&tag=team-payments",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/api/secret/secrets" || r.URL.RawQuery != tt.expectedQuery {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+				fmt.Fprintln(w, `[{"ID": 1, "SecretType": "Text", "Tags": [{"ID": 1, "Name": "prod"}]}]`)
+			}))
+			defer ts.Close()
+
+			host := strings.TrimPrefix(ts.URL, "https://")
+			secrets, err := SendGetSecretListByTags(ts.Client(), host, "testToken", tt.tags, tt.matchAll)
+			if err != nil {
+				t.Fatalf("didn't expect error, got %v", err)
+			}
+			if len(secrets) != 1 || len(secrets[0].Tags) != 1 || secrets[0].Tags[0].Name != "prod" {
+				t.Errorf("unexpected secrets %+v", secrets)
+			}
+		})
+	}
+}
+
+func TestSearchSecrets(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/secret/search" || r.URL.Query().Get("q") != "db* type:KeyValue" || r.URL.Query().Get("limit") != "10" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		fmt.Fprintln(w, `[{"ID": 3, "SecretType": "KeyValue", "Path": "prod/db-password", "Metadata": "payments"}]`)
+	}))
+	defer ts.Close()
+
+	host := strings.TrimPrefix(ts.URL, "https://")
+	secrets, err := SearchSecrets(ts.Client(), host, "testToken", "db* type:KeyValue", 10)
+	if err != nil {
+		t.Fatalf("didn't expect error, got %v", err)
+	}
+	if len(secrets) != 1 || secrets[0].Path != "prod/db-password" || secrets[0].Value != nil {
+		t.Errorf("unexpected secrets %+v", secrets)
+	}
+}
+
+func TestFileSecretRequest(t *testing.T) {
+	err := os.WriteFile("testReport.txt", []byte("report"), 0640)
+	if err != nil {
+		t.Fatalf("couldn't create a test file: %v", err)
+	}
+	defer os.Remove("testReport.txt")
+
+	req, err := FileSecretRequest("testReport.txt", "Q3 | final")
+	if err != nil {
+		t.Fatalf("didn't expect error, got %v", err)
+	}
+	expected := secret.SecretMeta{
+		Name:        "testReport",
+		Description: "Q3 | final",
+		File: secret.FileInfo{
+			Filename: "testReport.txt",
+			MimeType: "text/plain; charset=utf-8",
+			Size:     6,
+			SHA256:   secret.Checksum([]byte("report")),
+			Mode:     0640,
+		},
+	}
+	if req.Info == nil || *req.Info != expected {
+		t.Errorf("expected metadata %+v, got %+v", expected, req.Info)
+	}
+	if req.Meta != "Q3 | final" || req.Type != "ByteSlice" {
+		t.Errorf("unexpected request %+v", req)
+	}
+
+	legacy, err := FileSecretRequest("testReport.txt", "report|txt|quarterly")
+	if err != nil {
+		t.Fatalf("didn't expect error, got %v", err)
+	}
+	if legacy.Info.Name != "report" || legacy.Info.Description != "quarterly" || legacy.Info.File.Filename != "testReport.txt" {
+		t.Errorf("unexpected metadata for legacy format %+v", legacy.Info)
+	}
+
+	if _, err := FileSecretRequest("missing.txt", ""); err == nil {
+		t.Errorf("expected error for missing file, got nil")
+	}
+}
+
+func TestGetSecretPage(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/secret/secrets" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		switch r.URL.RawQuery {
+		case "limit=2&order=desc&sort=updated&type=Text":
+			fmt.Fprintln(w, `{"items": [{"ID": 3}, {"ID": 2}], "next_cursor": "abc"}`)
+		case "cursor=abc&limit=2&order=desc&sort=updated&type=Text":
+			fmt.Fprintln(w, `{"items": [{"ID": 1}]}`)
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer ts.Close()
+
+	host := strings.TrimPrefix(ts.URL, "https://")
+	opts := ListOptions{Types: []string{"Text"}, Sort: "updated", Desc: true, Limit: 2}
+	page, err := GetSecretPage(ts.Client(), host, "testToken", opts, "")
+	if err != nil {
+		t.Fatalf("didn't expect error, got %v", err)
+	}
+	if len(page.Items) != 2 || page.NextCursor != "abc" {
+		t.Fatalf("unexpected first page %+v", page)
+	}
+	page, err = GetSecretPage(ts.Client(), host, "testToken", opts, page.NextCursor)
+	if err != nil {
+		t.Fatalf("didn't expect error, got %v", err)
+	}
+	if len(page.Items) != 1 || page.NextCursor != "" {
+		t.Errorf("unexpected last page %+v", page)
+	}
+}
+
+func TestEmptyTrash(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete || r.URL.Path != "/api/secret/trash" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		fmt.Fprintln(w, "3")
+	}))
+	defer ts.Close()
+
+	host := strings.TrimPrefix(ts.URL, "https://")
+	n, err := EmptyTrash(ts.Client(), host, "testToken")
+	if err != nil {
+		t.Fatalf("didn't expect error, got %v", err)
+	}
+	if n != 3 {
+		t.Errorf("expected 3 purged secrets, got %d", n)
+	}
+}
+
+func TestResolveVaultPath(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/secret/resolve" || r.URL.Query().Get("path") != "prod/db" || r.URL.Query().Get("vault") != "5" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		fmt.Fprintln(w, `{"ID": 3, "VaultID": 5, "Path": "prod/db", "SecretType": "Text"}`)
+	}))
+	defer ts.Close()
+
+	host := strings.TrimPrefix(ts.URL, "https://")
+	s, err := ResolveVaultPath(ts.Client(), host, "testToken", 5, "prod/db")
+	if err != nil {
+		t.Fatalf("didn't expect error, got %v", err)
+	}
+	if s.ID != 3 || s.Path != "prod/db" {
+		t.Errorf("unexpected secret %+v", s)
+	}
+}
+
+func TestGetVaultTrash(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/secret/trash" || r.URL.Query().Get("vault") != "5" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		fmt.Fprintln(w, `[{"ID": 3, "VaultID": 5, "SecretType": "Text"}]`)
+	}))
+	defer ts.Close()
+
+	host := strings.TrimPrefix(ts.URL, "https://")
+	secrets, err := GetVaultTrash(ts.Client(), host, "testToken", 5)
+	if err != nil {
+		t.Fatalf("didn't expect error, got %v", err)
+	}
+	if len(secrets) != 1 || secrets[0].VaultID != 5 {
+		t.Errorf("unexpected trash %+v", secrets)
+	}
+}
+
+func TestGetVaultExpiringSecrets(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/secret/expiring" || r.URL.Query().Get("within") != "168h0m0s" || r.URL.Query().Get("vault") != "5" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		fmt.Fprintln(w, `[{"ID": 3, "VaultID": 5, "SecretType": "Text"}]`)
+	}))
+	defer ts.Close()
+
+	host := strings.TrimPrefix(ts.URL, "https://")
+	secrets, err := GetVaultExpiringSecrets(ts.Client(), host, "testToken", 5, 7*24*time.Hour)
+	if err != nil {
+		t.Fatalf("didn't expect error, got %v", err)
+	}
+	if len(secrets) != 1 || secrets[0].VaultID != 5 {
+		t.Errorf("unexpected secrets %+v", secrets)
+	}
+}
+
+func TestSetSecretExpiry(t *testing.T) {
+	expiresAt := time.Date(2030, 1, 2, 0, 0, 0, 0, time.UTC)
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ExpiresAt *time.Time `json:"expires_at"`
+		}
+		if r.Method != "PUT" || r.URL.Path != "/api/secret/7/expiry" || json.NewDecoder(r.Body).Decode(&req) != nil ||
+			req.ExpiresAt == nil || !req.ExpiresAt.Equal(expiresAt) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		fmt.Fprintln(w, `{"ID": 7}`)
+	}))
+	defer ts.Close()
+
+	host := strings.TrimPrefix(ts.URL, "https://")
+	if err := SetSecretExpiry(ts.Client(), host, "testToken", "7", &expiresAt); err != nil {
+		t.Errorf("didn't expect error, got %v", err)
+	}
+	if err := SetSecretExpiry(ts.Client(), host, "testToken", "7", nil); err == nil {
+		t.Errorf("expected error when the server rejects the request")
+	}
+}
+
+func TestApplyBatch(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req secret.BatchRequest
+		if r.URL.Path != "/api/secret/batch" || json.NewDecoder(r.Body).Decode(&req) != nil || len(req.Operations) != 2 {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		fmt.Fprintln(w, `{"committed": false, "results": [{"op": "delete", "id": 3, "status": 200}, {"op": "delete", "status": 404, "error": "Secret not found"}]}`)
+	}))
+	defer ts.Close()
+
+	host := strings.TrimPrefix(ts.URL, "https://")
+	ops := []secret.BatchOperation{{Op: secret.OpDelete, ID: 3}, {Op: secret.OpDelete, Path: "missing"}}
+	resp, err := ApplyBatch(ts.Client(), host, "testToken", ops)
+	if err != nil {
+		t.Fatalf("didn't expect error, got %v", err)
+	}
+	if resp.Committed || len(resp.Results) != 2 {
+		t.Fatalf("unexpected response %+v", resp)
+	}
+	if failed := resp.Failed(); len(failed) != 1 || failed[0].Status != 404 {
+		t.Errorf("unexpected failed operations %+v", failed)
+	}
+}
+
+func TestCopySecret(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]string
+		if r.Method != "POST" || r.URL.Path != "/api/secret/5/copy" || json.NewDecoder(r.Body).Decode(&req) != nil || req["path"] != "staging/db" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		fmt.Fprintln(w, `{"ID": 9, "SecretType": "KeyValue", "Path": "staging/db", "Tags": [{"Name": "db"}]}`)
+	}))
+	defer ts.Close()
+
+	host := strings.TrimPrefix(ts.URL, "https://")
+	copied, err := CopySecret(ts.Client(), host, "testToken", "5", "staging/db")
+	if err != nil {
+		t.Fatalf("didn't expect error, got %v", err)
+	}
+	if copied.ID != 9 || copied.Path != "staging/db" || len(copied.Tags) != 1 {
+		t.Errorf("unexpected copy %+v", copied)
+	}
+}
+
+func TestShareSecret(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req secret.ShareRequest
+		if r.Method != "POST" || r.URL.Path != "/api/secret/4/shares" || json.NewDecoder(r.Body).Decode(&req) != nil ||
+			req.Login != "alice" || !req.ReadOnly {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		fmt.Fprintln(w, `{"ID": 1, "SecretID": 4, "UserID": 2, "ReadOnly": true, "Login": "alice"}`)
+	}))
+	defer ts.Close()
+
+	host := strings.TrimPrefix(ts.URL, "https://")
+	share, err := ShareSecret(ts.Client(), host, "testToken", "4", secret.ShareRequest{Login: "alice", ReadOnly: true})
+	if err != nil {
+		t.Fatalf("didn't expect error, got %v", err)
+	}
+	if share.SecretID != 4 || share.Login != "alice" || !share.ReadOnly {
+		t.Errorf("unexpected share %+v", share)
+	}
+}
+
+func TestSetMember(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req org.MemberRequest
+		if r.Method != "PUT" || r.URL.Path != "/api/org/acme/members/alice" || json.NewDecoder(r.Body).Decode(&req) != nil ||
+			req.Role != org.RoleEditor {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		fmt.Fprintln(w, `{"ID": 2, "OrgID": 1, "UserID": 7, "Role": "editor", "Login": "alice"}`)
+	}))
+	defer ts.Close()
+
+	host := strings.TrimPrefix(ts.URL, "https://")
+	member, err := SetMember(ts.Client(), host, "testToken", "acme", "alice", org.RoleEditor)
+	if err != nil {
+		t.Fatalf("didn't expect error, got %v", err)
+	}
+	if member.Login != "alice" || member.Role != org.RoleEditor {
+		t.Errorf("unexpected member %+v", member)
+	}
+}
+
+func TestSearchVaultSecrets(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/secret/search" || r.URL.Query().Get("q") != "db" || r.URL.Query().Get("vault") != "5" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		fmt.Fprintln(w, `[{"ID": 9, "SecretType": "KeyValue", "Path": "db", "VaultID": 5}]`)
+	}))
+	defer ts.Close()
+
+	host := strings.TrimPrefix(ts.URL, "https://")
+	secrets, err := SearchVaultSecrets(ts.Client(), host, "testToken", "db", 5, 0)
+	if err != nil {
+		t.Fatalf("didn't expect error, got %v", err)
+	}
+	if len(secrets) != 1 || secrets[0].VaultID != 5 {
+		t.Errorf("unexpected secrets %+v", secrets)
+	}
+}
+
+func TestMoveSecretToVault(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			VaultID uint `json:"vault_id"`
+		}
+		if r.Method != "PUT" || r.URL.Path != "/api/secret/4/vault" || json.NewDecoder(r.Body).Decode(&req) != nil || req.VaultID != 3 {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		fmt.Fprintln(w, `{"ID": 4, "VaultID": 3}`)
+	}))
+	defer ts.Close()
+
+	host := strings.TrimPrefix(ts.URL, "https://")
+	if err := MoveSecretToVault(ts.Client(), host, "testToken", "4", 3); err != nil {
+		t.Fatalf("didn't expect error, got %v", err)
+	}
+}
+
+func TestDeletePersonalVault(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "DELETE" || r.URL.Path != "/api/vault/work" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		fmt.Fprintln(w, `12`)
+	}))
+	defer ts.Close()
+
+	host := strings.TrimPrefix(ts.URL, "https://")
+	n, err := DeletePersonalVault(ts.Client(), host, "testToken", "work")
+	if err != nil {
+		t.Fatalf("didn't expect error, got %v", err)
+	}
+	if n != 12 {
+		t.Errorf("expected 12 deleted secrets, got %d", n)
+	}
+}
+
+func TestOpenOneTimeShare(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.URL.Path != "/api/once/abc" || r.Header.Get("Authorization") != "" {
+			w.WriteHeader(http.StatusGone)
+			return
+		}
+		fmt.Fprintln(w, `{"ciphertext": "c2VhbGVk", "views_left": 0}`)
+	}))
+	defer ts.Close()
+
+	host := strings.TrimPrefix(ts.URL, "https://")
+	opened, err := OpenOneTimeShare(ts.Client(), host, "abc")
+	if err != nil {
+		t.Fatalf("didn't expect error, got %v", err)
+	}
+	if string(opened.Ciphertext) != "sealed" || opened.ViewsLeft != 0 {
+		t.Errorf("unexpected share %+v", opened)
+	}
+	if _, err := OpenOneTimeShare(ts.Client(), host, "used"); err == nil {
+		t.Errorf("expected error for a used up share")
+	}
+}
+
+func TestChangeEmergencyAccess(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "POST" && r.URL.Path == "/api/emergency/contacts/bob/approve":
+			fmt.Fprintln(w, `{"ID": 3, "OwnerID": 1, "ContactID": 2, "WaitHours": 48, "State": "granted", "Login": "bob"}`)
+		case r.Method == "POST" && r.URL.Path == "/api/emergency/owners/alice/request":
+			fmt.Fprintln(w, `{"ID": 3, "OwnerID": 1, "ContactID": 2, "WaitHours": 48, "State": "requested", "Login": "alice"}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	host := strings.TrimPrefix(ts.URL, "https://")
+	contact, err := ChangeEmergencyAccess(ts.Client(), host, "testToken", "bob", emergency.ActionApprove)
+	if err != nil {
+		t.Fatalf("didn't expect error, got %v", err)
+	}
+	if contact.State != emergency.StateGranted {
+		t.Errorf("expected a granted contact, got %+v", contact)
+	}
+	contact, err = ChangeEmergencyAccess(ts.Client(), host, "testToken", "alice", emergency.ActionRequest)
+	if err != nil {
+		t.Fatalf("didn't expect error, got %v", err)
+	}
+	if contact.State != emergency.StateRequested {
+		t.Errorf("expected a requested contact, got %+v", contact)
+	}
+}
+
+func TestGetAuditEvents(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if r.URL.Path != "/api/audit" || q.Get("since") != "2024-03-01T12:00:00Z" || q.Get("secret") != "42" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		fmt.Fprintln(w, `[{"ID": 5, "ActorID": 2, "SecretID": 42, "Action": "read", "Result": "success", "ClientIP": "10.0.0.1", "Actor": "alice"}]`)
+	}))
+	defer ts.Close()
+
+	host := strings.TrimPrefix(ts.URL, "https://")
+	since := time.Date(2024, 3, 1, 13, 0, 0, 0, time.FixedZone("CET", 3600))
+	events, err := GetAuditEvents(ts.Client(), host, "testToken", &since, "42", 0)
+	if err != nil {
+		t.Fatalf("didn't expect error, got %v", err)
+	}
+	if len(events) != 1 || events[0].Action != audit.ActionRead || events[0].Actor != "alice" {
+		t.Errorf("unexpected events %+v", events)
+	}
+}
+
+func TestCreateWebhook(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req webhook.WebhookRequest
+		if r.Method != "POST" || r.URL.Path != "/api/webhook" || json.NewDecoder(r.Body).Decode(&req) != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if req.Org != "acme" || len(req.Events) != 2 {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		fmt.Fprintf(w, `{"ID": 4, "OrgID": 2, "URL": %q, "Events": "created,deleted", "Secret": "c2VjcmV0"}`, req.URL)
+	}))
+	defer ts.Close()
+
+	host := strings.TrimPrefix(ts.URL, "https://")
+	req := webhook.WebhookRequest{URL: "https://hooks.example.com/pk", Events: []string{"created", "deleted"}, Org: "acme"}
+	created, err := CreateWebhook(ts.Client(), host, "testToken", req)
+	if err != nil {
+		t.Fatalf("didn't expect error, got %v", err)
+	}
+	if created.ID != 4 || created.URL != req.URL || created.Secret != "c2VjcmV0" {
+		t.Errorf("unexpected webhook %+v", created)
+	}
+}
+
+func TestWatchSecrets(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/watch" || r.Header.Get("Authorization") != "testToken" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, ": watching\n\n")
+		fmt.Fprint(w, "event: updated\ndata: {\"event\": \"updated\", \"secret_id\": 7, \"actor_id\": 2}\n\n")
+		fmt.Fprint(w, ": ping\n\n")
+		fmt.Fprint(w, "event: deleted\ndata: {\"event\": \"deleted\", \"secret_id\": 9}\n\n")
+	}))
+	defer ts.Close()
+
+	host := strings.TrimPrefix(ts.URL, "https://")
+	var received []events.Event
+	err := WatchSecrets(ts.Client(), host, "testToken", func(e events.Event) error {
+		received = append(received, e)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("didn't expect error, got %v", err)
+	}
+	if len(received) != 2 || received[0].SecretID != 7 || received[1].Type != "deleted" {
+		t.Errorf("unexpected events %+v", received)
+	}
+	if err := WatchSecrets(ts.Client(), host, "", func(events.Event) error { return nil }); err == nil {
+		t.Error("expected an error without a token")
+	}
+}
+
+func TestGetChanges(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/secret/changes" || r.URL.Query().Get("since") != "41" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		fmt.Fprintln(w, `{"Revision": 44, "More": true, "Changes": [`+
+			`{"Revision": 42, "SecretID": 7, "Secret": {"ID": 7, "Path": "prod/db", "Revision": 42}},`+
+			`{"Revision": 44, "SecretID": 9, "Deleted": true}]}`)
+	}))
+	defer ts.Close()
+
+	host := strings.TrimPrefix(ts.URL, "https://")
+	feed, err := GetChanges(ts.Client(), host, "testToken", 41, 0)
+	if err != nil {
+		t.Fatalf("didn't expect error, got %v", err)
+	}
+	if feed.Revision != 44 || !feed.More || len(feed.Changes) != 2 {
+		t.Fatalf("unexpected feed %+v", feed)
+	}
+	if c := feed.Changes[0]; c.Deleted || c.Secret == nil || c.Secret.Path != "prod/db" {
+		t.Errorf("expected an updated secret, got %+v", c)
+	}
+	if c := feed.Changes[1]; !c.Deleted || c.Secret != nil {
+		t.Errorf("expected a tombstone, got %+v", c)
+	}
+}